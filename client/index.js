@@ -1,10 +1,21 @@
 const serverURL = "http://localhost:8080"
+// URL of a peer verifier or public gossip endpoint (e.g. "http://localhost:8090")
+// used for split-view (fork) detection; empty disables it
+const gossipURL = ""
+// public key (PEM) of the server signing key, as published for the election:
+// required to gossip, as only the states signed by the server are exchanged
+const serverPublicKey = ""
 const nikkiHaley = 1
 const kamalaHarris = 2
+// stream of the checkpoints and tally deltas pushed by the server as the txs
+// are committed (see the Events section of the README)
+const eventsURL = serverURL + "/api/v1/elections/default/events"
+// the timers below only poll while the stream is down (or not supported)
+var eventsOpen = false
 
 // verifies the consistency of the election
 const verifyConsistency = async () => {
-  VerifyConsistency(serverURL);
+  VerifyConsistency(serverURL, gossipURL, serverPublicKey);
 }
 
 // updates the election stats shown in the UI
@@ -25,6 +36,18 @@ const updateStats = async () => {
   });
 }
 
+// adds the tally delta pushed by the server to the stats shown in the UI
+const applyTally = tally => {
+  const add = (id, delta) => {
+    const el = document.getElementById(id);
+    el.innerText = (parseInt(el.innerText, 10) || 0) + (delta || 0);
+  };
+  add("haley-votes", tally["results"][nikkiHaley]);
+  add("harris-votes", tally["results"][kamalaHarris]);
+  add("registered", tally["registered"]);
+  add("ballots", tally["ballots"]);
+}
+
 // updates the ballot status (if ballot ID is present)
 var updateBallotStatusRunning = false
 const updateBallotStatus = async () => {
@@ -121,6 +144,49 @@ const verifyRandomVote = async () => {
   });
 }
 
+// debounce returns fn delayed until no call has been made for the wait (ms), so
+// that a burst of checkpoints triggers it once
+const debounce = (fn, wait) => {
+  let timer;
+  return () => {
+    clearTimeout(timer);
+    timer = setTimeout(fn, wait);
+  };
+}
+
+// subscribes to the events of the election: the tally deltas update the stats
+// and each new checkpoint triggers the consistency and ballot checks, instead
+// of the timers
+const subscribeToEvents = () => {
+  if (!window.EventSource) {
+    return
+  }
+  const onCheckpoint = debounce(() => {
+    verifyConsistency();
+    updateBallotStatus();
+    verifyRandomVote();
+  }, 500);
+  const events = new EventSource(eventsURL);
+  events.onopen = () => {
+    eventsOpen = true;
+    // the stream only has the txs from now on: resync the stats
+    updateStats();
+  };
+  events.onerror = () => {
+    // the browser reconnects by itself, resuming from the last checkpoint
+    eventsOpen = false;
+  };
+  events.addEventListener("tally", e => applyTally(JSON.parse(e.data)));
+  events.addEventListener("checkpoint", onCheckpoint);
+}
+
+// unlessEventsOpen returns fn, made a no-op while the event stream is open
+const unlessEventsOpen = fn => () => {
+  if (!eventsOpen) {
+    fn();
+  }
+}
+
 // shows notification bar with the specified message and level
 const showNotification = async (msg, level) => {
   const notifBar = document.getElementById("notification-bar");
@@ -162,10 +228,39 @@ const setupUI = async () => {
       document.getElementById("btn-vote-haley").classList.remove("hidden");
     }
   } else {
+    loadPrecincts();
     document.getElementById("registration-panel").classList.remove("hidden");
   }
 }
 
+// fills the precinct select of the registration with the precincts of the
+// election, grouped by district
+const loadPrecincts = () => {
+  fetch(serverURL + '/api/v1/elections/default').then(response => {
+    if (!response.ok) {
+      return;
+    }
+    response.json().then(election => {
+      const precinctSelect = document.getElementById("precinct-select");
+      election.regions.forEach(region => {
+        region.units.forEach(district => {
+          const group = document.createElement("optgroup");
+          group.label = region.name + " / " + district.name;
+          district.units.forEach(precinct => {
+            const option = document.createElement("option");
+            option.value = precinct.id;
+            option.textContent = precinct.name;
+            group.appendChild(option);
+          });
+          precinctSelect.appendChild(group);
+        });
+      });
+    });
+  }).catch(err => {
+    console.log(err);
+  });
+}
+
 // registers voter
 var registerVoterRunning = false;
 const registerVoter = () => {
@@ -181,12 +276,13 @@ const registerVoter = () => {
       citizen_id: document.getElementById("id-number-input").value,
       name: document.getElementById("name-input").value,
       address: document.getElementById("address-input").value,
-      email: document.getElementById("email-input").value
+      email: document.getElementById("email-input").value,
+      precinct: document.getElementById("precinct-select").value
     })
   }).then(response => {
     if (!response.ok) {
-      response.text().then(responseText => {
-        showNotification(responseText, "error");
+      errorMessage(response).then(message => {
+        showNotification(message, "error");
       });
     } else {
       response.json().then(responseJSON => {
@@ -204,6 +300,18 @@ const registerVoter = () => {
   });
 }
 
+// errorMessage returns the message of the JSON error envelope of the response
+// (see the Errors section of the server README)
+const errorMessage = response => {
+  return response.text().then(responseText => {
+    try {
+      return JSON.parse(responseText).error.message;
+    } catch (err) {
+      return responseText;
+    }
+  });
+}
+
 // vote casts the ballot
 var voteRunning = false;
 const vote = (candidateID, candidate) => {
@@ -229,8 +337,8 @@ const vote = (candidateID, candidate) => {
     })
   }).then(response => {
     if (!response.ok) {
-      response.text().then(responseText => {
-        showNotification(responseText, "error");
+      errorMessage(response).then(message => {
+        showNotification(message, "error");
       });
     } else {
       const voterDetails = {
@@ -306,13 +414,19 @@ document.addEventListener("DOMContentLoaded", function() {
     setupUI();
     setupUserActions();
 
+    subscribeToEvents();
+
     updateStats();
-    setInterval(updateStats, 10000);
+    setInterval(unlessEventsOpen(updateStats), 10000);
+    // resync the stats with the deltas applied so far, in case one was missed
+    setInterval(updateStats, 60000);
 
     updateBallotStatus();
-    setInterval(updateBallotStatus, 15000);
+    setInterval(unlessEventsOpen(updateBallotStatus), 15000);
 
-    setInterval(verifyConsistency, 5000);
-    setInterval(verifyRandomVote, 8000);
+    verifyConsistency();
+    setInterval(unlessEventsOpen(verifyConsistency), 5000);
+    verifyRandomVote();
+    setInterval(unlessEventsOpen(verifyRandomVote), 8000);
   })()
 });
\ No newline at end of file
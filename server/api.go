@@ -0,0 +1,316 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+const apiV1Prefix = "/api/v1"
+
+// apiRoute is a route of the versioned API: the router and the OpenAPI
+// document (see openapi.go) are both built from the routes, so they can not
+// drift apart
+type apiRoute struct {
+	method  string
+	path    string
+	summary string
+	handler http.HandlerFunc
+	// admin routes require basic auth
+	admin bool
+	// idempotent routes accept the Idempotency-Key header
+	idempotent bool
+	// query params (path params are described in apiPathParams)
+	query []apiParam
+	// sample values of the request and response bodies, whose types are
+	// described in the OpenAPI document; a nil response means no content
+	request  interface{}
+	response interface{}
+	// sample values of the data of the Server-Sent Events streamed by the
+	// route, instead of the response body
+	events []interface{}
+}
+
+// apiParam ...
+type apiParam struct {
+	name        string
+	description string
+	integer     bool
+	required    bool
+}
+
+var apiPathParams = map[string]apiParam{
+	"election_id": {name: "election_id", description: "ID of the election (`" + electionID + "`)"},
+	"voter_id":    {name: "voter_id", description: "voter ID (or citizen ID) of the voter"},
+	"ballot_id":   {name: "ballot_id", description: "ID of the ballot"},
+	"server_tx":   {name: "server_tx", description: "ID of the tx to prove", integer: true},
+}
+
+// apiV1Routes returns the routes of the /api/v1 API; the more specific routes
+// go first (e.g. /ballots/random before /ballots/{ballot_id})
+func (s *Server) apiV1Routes() []apiRoute {
+	return []apiRoute{
+		{
+			method:   http.MethodGet,
+			path:     "/elections",
+			summary:  "Lists the elections",
+			handler:  s.getElectionsHandler,
+			response: []ElectionResponse{},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/elections/{election_id}",
+			summary:  "Returns the election definition",
+			handler:  s.getElectionHandler,
+			response: &ElectionResponse{},
+		},
+		{
+			method:     http.MethodPost,
+			path:       "/elections/{election_id}/voters",
+			summary:    "Registers a voter and issues their ballot",
+			handler:    s.registerVoterHandler,
+			idempotent: true,
+			request:    &RegisterVoterRequest{},
+			response:   &RegisterVoterResponse{},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/elections/{election_id}/voters/{voter_id}",
+			summary:  "Returns the registration status of a voter",
+			handler:  s.getVoterStatusHandler,
+			response: &GetVoterStatusResponse{},
+		},
+		{
+			method:     http.MethodPost,
+			path:       "/elections/{election_id}/votes",
+			summary:    "Casts a ballot",
+			handler:    s.voteHandler,
+			idempotent: true,
+			request:    &VoteRequest{},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/elections/{election_id}/ballots/random",
+			summary:  "Returns a random ballot with its history",
+			handler:  s.getRandomBallotHandler,
+			response: &RandomBallotResponse{},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/elections/{election_id}/ballots/{ballot_id}",
+			summary:  "Returns a ballot",
+			handler:  s.getBallotHandler,
+			response: &GetBallotResponse{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/elections/{election_id}/stats",
+			summary: "Returns the registration and voting stats, rolled up by region, district and precinct",
+			handler: s.getStatsHandler,
+			query: []apiParam{
+				{name: "unit", description: "ID of the region, district or precinct to return the stats of (default: all of the election)"},
+				{name: "as_of_tx", description: "return the stats as of this tx", integer: true},
+				{name: "as_of_time", description: "return the stats as of the latest tx committed at this RFC 3339 time"},
+			},
+			response: &GetStatsResponse{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/elections/{election_id}/turnout",
+			summary: "Returns the registrations and the ballots cast by interval, from the tx log",
+			handler: s.getTurnoutHandler,
+			query: []apiParam{
+				{name: "interval", description: "duration of the buckets, in minutes up to 24h (default: 1h)"},
+				{name: "from", description: "RFC 3339 time of the first bucket (default: the first registration or ballot)"},
+				{name: "to", description: "RFC 3339 time of the end of the last bucket (default: the latest registration or ballot)"},
+			},
+			response: &TurnoutResponse{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/elections/{election_id}/bulletin-board",
+			summary: "Returns the public bulletin board, page by page",
+			handler: s.getBulletinBoardHandler,
+			query: []apiParam{
+				{name: "since_tx", description: "return the txs after this one", integer: true},
+				{name: "limit", description: "max number of txs to return", integer: true},
+			},
+			response: &BulletinBoardResponse{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/elections/{election_id}/audit-sweep",
+			summary: "Returns the full history of the ballots, page by page, to be verified",
+			handler: s.getAuditSweepHandler,
+			query: []apiParam{
+				{name: "state_tx", description: "tx of the state to sweep (default: the current one)", integer: true},
+				{name: "limit", description: "max number of ballots to return", integer: true},
+				{name: "cursor", description: "cursor of the next page, from the previous page"},
+			},
+			response: &AuditSweepResponse{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/elections/{election_id}/events",
+			summary: "Streams the checkpoints, tally deltas and election events of the new txs as Server-Sent Events",
+			handler: s.getEventsHandler,
+			query: []apiParam{
+				{name: "since_tx", description: "stream the txs after this one (default: the current one); the Last-Event-ID header takes precedence", integer: true},
+			},
+			events: []interface{}{&Checkpoint{}, &TallyEvent{}, &ElectionEvent{}},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/elections/{election_id}/rla",
+			summary:  "Returns the status of the risk-limiting audit",
+			handler:  s.getRLAStatusHandler,
+			response: &RLAStatus{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/elections/{election_id}/rla/sample",
+			summary: "Returns the ballots sampled by the risk-limiting audit",
+			handler: s.getRLASampleHandler,
+			query: []apiParam{
+				{name: "from", description: "first draw to return (default: 1)", integer: true},
+				{name: "count", description: "number of draws to return", integer: true},
+			},
+			response: []RLASampleDraw{},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/elections/{election_id}/certification",
+			summary:  "Returns the final results document, once the election has been closed, with the signatures of the officials",
+			handler:  s.getCertificationHandler,
+			response: &CertificationResponse{},
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/elections/{election_id}/certification/signatures",
+			summary:  "Records the signature of the results document by an official",
+			handler:  s.signCertificationHandler,
+			request:  &SignCertificationRequest{},
+			response: &CertificationResponse{},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/state",
+			summary:  "Returns the current state of the database",
+			handler:  s.getStateHandler,
+			response: &GetStateResponse{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/verifiable-txs/{server_tx}",
+			summary: "Returns a tx with the proof of its consistency with a previously verified tx",
+			handler: s.getVerifiableTransactionHandler,
+			query: []apiParam{
+				{name: "local_tx", description: "ID of the previously verified tx", integer: true, required: true},
+			},
+			response: &schema.VerifiableTx{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/admin/elections/{election_id}/audit-bundle",
+			summary: "Exports the self-contained audit bundle of the election",
+			handler: s.getAuditBundleHandler,
+			admin:   true,
+			query: []apiParam{
+				{name: "tx", description: "tx of the state to export (default: the current one)", integer: true},
+			},
+			response: &AuditBundle{},
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/admin/elections/{election_id}/rla",
+			summary:  "Commits the ballot frame of the risk-limiting audit, once the election has been closed",
+			handler:  s.commitRLAHandler,
+			admin:    true,
+			request:  &CommitRLARequest{},
+			response: &RLAStatus{},
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/admin/elections/{election_id}/rla/seed",
+			summary:  "Commits the public seed of the risk-limiting audit, after its ballot frame",
+			handler:  s.commitRLASeedHandler,
+			admin:    true,
+			request:  &CommitRLASeedRequest{},
+			response: &RLAStatus{},
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/admin/elections/{election_id}/rla/interpretations",
+			summary:  "Records the interpretation of a sampled paper ballot",
+			handler:  s.recordRLAInterpretationHandler,
+			admin:    true,
+			request:  &RecordRLAInterpretationRequest{},
+			response: &RLAStatus{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/admin/elections/{election_id}/voters",
+			summary: "Lists the voters with their registration status, page by page",
+			handler: s.getVoterListHandler,
+			admin:   true,
+			query: []apiParam{
+				{name: "limit", description: "max number of voters to return", integer: true},
+				{name: "cursor", description: "cursor of the next page, from the previous page"},
+			},
+			response: &VoterListResponse{},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/admin/elections/{election_id}/results",
+			summary: "Exports the results, the turnout and the rollups of the units as JSON, CSV or NIST CDF JSON, with the tx they are as of",
+			handler: s.getResultsExportHandler,
+			admin:   true,
+			query: []apiParam{
+				{name: "format", description: "json (default), csv or nist-cdf (NIST SP 1500-100 Election Results Reporting Common Data Format, JSON)"},
+			},
+			response: &ResultsExport{},
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/admin/elections/{election_id}/certification",
+			summary:  "Closes the election and persists the final results document, to be signed by the officials",
+			handler:  s.closeElectionHandler,
+			admin:    true,
+			response: &CertificationResponse{},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/admin/elections/{election_id}/duplicate-citizens",
+			summary:  "Lists the citizens registered more than once",
+			handler:  s.getDuplicateCitizensHandler,
+			admin:    true,
+			response: &DuplicateCitizensReport{},
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/admin/elections/{election_id}/duplicate-citizens/resolve",
+			summary:  "Revokes all the voters of the duplicate citizens but one",
+			handler:  s.resolveDuplicateCitizensHandler,
+			admin:    true,
+			response: &DuplicateCitizensReport{},
+		},
+	}
+}
+
+// apiV1Handler wraps the handler of the route with its middlewares
+func (s *Server) apiV1Handler(route apiRoute) http.HandlerFunc {
+	var middlewares []middleware
+	if route.admin {
+		middlewares = append(middlewares, s.corsAndBasicAuth)
+	} else {
+		middlewares = append(middlewares, cors)
+	}
+	if strings.Contains(route.path, "{election_id}") {
+		middlewares = append(middlewares, electionScoped)
+	}
+	if route.idempotent {
+		middlewares = append(middlewares, s.idempotent)
+	}
+	return chain(route.handler, middlewares...)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+	"github.com/codenotary/immudb/pkg/signer"
+)
+
+// AuditBundle is a self-contained snapshot of the election at a given tx, which
+// can be archived and verified later, fully offline:
+//   - every entry comes with the proof of its inclusion in the tx it was set in
+//   - every such tx comes with a checkpoint: the dual proof linking it to the
+//     bundle state
+//   - the bundle state is signed with the signing key of the server or, if it
+//     has none, by immudb (if it runs with a signing key)
+type AuditBundle struct {
+	State       AuditBundleState             `json:"state"`
+	Election    AuditBundleEntry             `json:"election"`
+	VoterRoll   []AuditBundleEntry           `json:"voter_roll"`
+	Ballots     []AuditBundleEntry           `json:"ballots"`
+	Checkpoints map[uint64]*schema.DualProof `json:"checkpoints"`
+}
+
+// AuditBundleState ...
+type AuditBundleState struct {
+	DB        string            `json:"db"`
+	TXID      uint64            `json:"tx_id"`
+	TXHash    []byte            `json:"tx_hash"`
+	Signature *schema.Signature `json:"signature,omitempty"`
+}
+
+// AuditBundleEntry is an entry as of the bundle state: voters (which hold PII)
+// only have their digest exported, all other entries have their key and value
+type AuditBundleEntry struct {
+	Key            []byte                 `json:"key,omitempty"`
+	Value          []byte                 `json:"value,omitempty"`
+	Digest         []byte                 `json:"digest,omitempty"`
+	TX             uint64                 `json:"tx"`
+	InclusionProof *schema.InclusionProof `json:"inclusion_proof"`
+}
+
+// errSetAfterState is returned for a key which has only been set after the
+// bundle state
+var errSetAfterState = fmt.Errorf("%w as of the bundle state", ErrNotFound)
+
+type auditBundleBuilder struct {
+	store  Store
+	bundle *AuditBundle
+}
+
+func newAuditBundleBuilder(
+	ctx context.Context,
+	store Store,
+	stateSigner signer.Signer,
+	txID uint64,
+) (*auditBundleBuilder, error) {
+	vTX, err := store.VerifiableTXByID(ctx, txID, txID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching verifiable tx %d: %w", txID, err)
+	}
+	alh := schema.TxMetadataFrom(vTX.GetDualProof().GetTargetTxMetadata()).Alh()
+	state := schema.ImmutableState{
+		Db:        store.Database(),
+		TxId:      txID,
+		TxHash:    alh[:],
+		Signature: vTX.GetSignature(),
+	}
+	if err := signState(stateSigner, &state); err != nil {
+		return nil, err
+	}
+	return &auditBundleBuilder{store: store, bundle: &AuditBundle{
+		State: AuditBundleState{
+			DB:        state.GetDb(),
+			TXID:      state.GetTxId(),
+			TXHash:    state.GetTxHash(),
+			Signature: state.GetSignature(),
+		},
+		Checkpoints: make(map[uint64]*schema.DualProof),
+	}}, nil
+}
+
+// entry returns the value of the key as of the bundle state, along with its
+// inclusion proof, and adds the checkpoint of its tx to the bundle
+func (b *auditBundleBuilder) entry(ctx context.Context, key []byte, digestOnly bool) (*AuditBundleEntry, error) {
+	historyEntries, err := historyAll(ctx, b.store, key)
+	if err != nil {
+		return nil, fmt.Errorf("error loading history of key %s: %v", key, err)
+	}
+	var atTx uint64
+	for _, historyEntry := range historyEntries {
+		if historyEntry.GetTx() <= b.bundle.State.TXID {
+			atTx = historyEntry.GetTx()
+		}
+	}
+	if atTx == 0 {
+		return nil, fmt.Errorf("key %s %w at tx %d", key, errSetAfterState, b.bundle.State.TXID)
+	}
+
+	verifiableEntry, err := b.store.VerifiableGetAt(ctx, key, atTx, b.bundle.State.TXID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching verifiable key %s at tx %d: %w", key, atTx, err)
+	}
+	if _, ok := b.bundle.Checkpoints[atTx]; !ok {
+		b.bundle.Checkpoints[atTx] = verifiableEntry.GetVerifiableTx().GetDualProof()
+	}
+
+	entry := AuditBundleEntry{
+		TX:             atTx,
+		InclusionProof: verifiableEntry.GetInclusionProof(),
+	}
+	value := verifiableEntry.GetEntry().GetValue()
+	if digestOnly {
+		digest := database.EncodeKV(key, value).Digest()
+		entry.Digest = digest[:]
+	} else {
+		entry.Key = key
+		entry.Value = value
+	}
+	return &entry, nil
+}
+
+// entries returns all entries with the given prefix as of the bundle state
+func (b *auditBundleBuilder) entries(ctx context.Context, prefix string, digestOnly bool) ([]AuditBundleEntry, error) {
+	entries := []AuditBundleEntry{}
+	if err := scanEach(ctx, b.store, []byte(prefix), func(scannedEntry *schema.Entry) error {
+		entry, err := b.entry(ctx, scannedEntry.GetKey(), digestOnly)
+		if errors.Is(err, errSetAfterState) && scannedEntry.GetTx() > b.bundle.State.TXID {
+			// key has been created after the bundle state; any other key must
+			// be exported, else the bundle would silently lack it
+			return nil
+		} else if err != nil {
+			return err
+		}
+		entries = append(entries, *entry)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("error exporting %s entries: %w", prefix, err)
+	}
+	return entries, nil
+}
+
+// BuildAuditBundle builds the audit bundle of the election at the given tx
+func BuildAuditBundle(ctx context.Context, store Store, stateSigner signer.Signer, txID uint64) (*AuditBundle, error) {
+	b, err := newAuditBundleBuilder(ctx, store, stateSigner, txID)
+	if err != nil {
+		return nil, err
+	}
+	electionEntry, err := b.entry(ctx, []byte(electionKey), false)
+	if err != nil {
+		return nil, fmt.Errorf("error exporting election definition: %w", err)
+	}
+	b.bundle.Election = *electionEntry
+	if b.bundle.VoterRoll, err = b.entries(ctx, voterPrefix, true); err != nil {
+		return nil, err
+	}
+	if b.bundle.Ballots, err = b.entries(ctx, ballotPrefix, false); err != nil {
+		return nil, err
+	}
+	return b.bundle, nil
+}
+
+func (s *Server) getAuditBundleHandler(w http.ResponseWriter, r *http.Request) {
+	var txID uint64
+	txStr := r.URL.Query().Get("tx")
+	if len(txStr) == 0 {
+		state, err := s.store.CurrentState(r.Context())
+		if err != nil {
+			writeErrorResponse(r, w, http.StatusInternalServerError, err,
+				"error fetching current state")
+			return
+		}
+		txID = state.GetTxId()
+	} else {
+		var err error
+		if txID, err = strconv.ParseUint(txStr, 10, 64); err != nil || txID == 0 {
+			writeErrorResponse(r, w, http.StatusBadRequest, err,
+				"tx query param is not a positive unsigned int")
+			return
+		}
+	}
+
+	bundle, err := BuildAuditBundle(r.Context(), s.store, s.stateSigner, txID)
+	if err != nil {
+		httpErrCode := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			httpErrCode = http.StatusNotFound
+		}
+		writeErrorResponse(r, w, httpErrCode, err,
+			fmt.Sprintf("error building audit bundle at tx %d", txID))
+		return
+	}
+
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"immuvoting-audit-bundle-%d.json\"", txID))
+	writeJSONResponse(r, w, http.StatusOK, bundle)
+}
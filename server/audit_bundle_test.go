@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/htree"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+	"github.com/codenotary/immudb/pkg/signer"
+)
+
+func TestAuditBundle(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	electionURL := httpServer.URL + apiV1Prefix + "/elections/" + electionID
+
+	alice := registerTestVoter(t, httpServer.URL, "alice", "north-1-a")
+	registeredState, err := server.store.CurrentState(context.Background())
+	if err != nil {
+		t.Fatalf("error fetching current state: %v", err)
+	}
+	vote := &VoteRequest{RegisterVoterResponse: *alice, Vote: NikkiHaley}
+	if status := doJSON(t, http.MethodPost, electionURL+"/votes", false, vote, nil); status != http.StatusNoContent {
+		t.Fatalf("voting: got status %d, want %d", status, http.StatusNoContent)
+	}
+	registerTestVoter(t, httpServer.URL, "bob", "south-1-b")
+
+	bundleURL := httpServer.URL + apiV1Prefix + "/admin/elections/" + electionID + "/audit-bundle"
+	if status := doJSON(t, http.MethodGet, bundleURL, false, nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("exporting audit bundle without credentials: got status %d, want %d",
+			status, http.StatusUnauthorized)
+	}
+	var bundle AuditBundle
+	if status := doJSON(t, http.MethodGet, bundleURL, true, nil, &bundle); status != http.StatusOK {
+		t.Fatalf("exporting audit bundle: got status %d, want %d", status, http.StatusOK)
+	}
+	if len(bundle.VoterRoll) != 2 || len(bundle.Ballots) != 2 {
+		t.Fatalf("got %d voters and %d ballots, want 2 and 2", len(bundle.VoterRoll), len(bundle.Ballots))
+	}
+
+	// every entry must be included in its tx, which must be linked to the
+	// bundle state
+	stateAlh := schema.DigestFrom(bundle.State.TXHash)
+	entries := append([]AuditBundleEntry{bundle.Election}, bundle.VoterRoll...)
+	entries = append(entries, bundle.Ballots...)
+	for _, entry := range entries {
+		checkpoint, ok := bundle.Checkpoints[entry.TX]
+		if !ok {
+			t.Fatalf("no checkpoint for tx %d", entry.TX)
+		}
+		dualProof := schema.DualProofFrom(checkpoint)
+		if !store.VerifyDualProof(dualProof, entry.TX, bundle.State.TXID,
+			dualProof.SourceTxMetadata.Alh(), stateAlh) {
+			t.Errorf("checkpoint of tx %d does not verify against state tx %d", entry.TX, bundle.State.TXID)
+		}
+		digest := schema.DigestFrom(entry.Digest)
+		if len(entry.Digest) == 0 {
+			digest = database.EncodeKV(entry.Key, entry.Value).Digest()
+		}
+		eh := schema.DigestFrom(checkpoint.GetSourceTxMetadata().GetEH())
+		if !htree.VerifyInclusion(schema.InclusionProofFrom(entry.InclusionProof), digest, eh) {
+			t.Errorf("entry of tx %d is not included in its tx", entry.TX)
+		}
+	}
+
+	// the ballots as of a past tx are those issued up to it, as they were then
+	var past AuditBundle
+	pastTX := registeredState.GetTxId()
+	if status := doJSON(t, http.MethodGet, fmt.Sprintf("%s?tx=%d", bundleURL, pastTX), true, nil, &past); status != http.StatusOK {
+		t.Fatalf("exporting audit bundle at tx %d: got status %d, want %d", pastTX, status, http.StatusOK)
+	}
+	if len(past.VoterRoll) != 1 || len(past.Ballots) != 1 || string(past.Ballots[0].Value) != "\x00\x00" {
+		t.Errorf("got %d voters and ballots %+v at tx %d, want 1 voter and 1 ballot not cast",
+			len(past.VoterRoll), past.Ballots, pastTX)
+	}
+}
+
+func TestAuditBundleSigned(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	registerTestVoter(t, httpServer.URL, "alice", "north-1-a")
+	currentState, err := server.store.CurrentState(context.Background())
+	if err != nil {
+		t.Fatalf("error fetching current state: %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating signing key: %v", err)
+	}
+
+	bundle, err := BuildAuditBundle(context.Background(), server.store,
+		signer.NewSignerFromPKey(rand.Reader, key), currentState.GetTxId())
+	if err != nil {
+		t.Fatalf("error building audit bundle: %v", err)
+	}
+	state := schema.ImmutableState{
+		Db:        bundle.State.DB,
+		TxId:      bundle.State.TXID,
+		TxHash:    bundle.State.TXHash,
+		Signature: bundle.State.Signature,
+	}
+	if ok, err := state.CheckSignature(&key.PublicKey); err != nil || !ok {
+		t.Errorf("state signature does not verify against the signing key: %t, %v", ok, err)
+	}
+}
+
+// missingKeyStore is a store which has lost the values of a key
+type missingKeyStore struct {
+	memStore
+	key string
+}
+
+// VerifiableGetAt ...
+func (s missingKeyStore) VerifiableGetAt(
+	ctx context.Context,
+	key []byte,
+	atTx uint64,
+	proveSinceTx uint64,
+) (*schema.VerifiableEntry, error) {
+	if string(key) == s.key {
+		return nil, fmt.Errorf("key %s %w", key, ErrNotFound)
+	}
+	return s.memStore.VerifiableGetAt(ctx, key, atTx, proveSinceTx)
+}
+
+func TestAuditBundleMissingEntry(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	alice := registerTestVoter(t, httpServer.URL, "alice", "north-1-a")
+	state, err := server.store.CurrentState(context.Background())
+	if err != nil {
+		t.Fatalf("error fetching current state: %v", err)
+	}
+
+	// the ballot must not be silently left out of the bundle
+	store := missingKeyStore{
+		memStore: server.store.(instrumentedStore).Store.(memStore),
+		key:      ballotPrefix + alice.BallotID,
+	}
+	if _, err := BuildAuditBundle(context.Background(), store, nil, state.GetTxId()); err == nil ||
+		!strings.Contains(err.Error(), alice.BallotID) {
+		t.Errorf("building audit bundle without ballot %s: got %v, want an error", alice.BallotID, err)
+	} else if errors.Is(err, errSetAfterState) {
+		t.Errorf("building audit bundle without ballot %s: got %v, want an error other than %v",
+			alice.BallotID, err, errSetAfterState)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+const auditSweepDefaultLimit = 100
+
+// AuditSweepHistoryEntry is a value the ballot has had, along with the proofs
+// of its inclusion in the tx it was set in and of that tx in the sweep state
+type AuditSweepHistoryEntry struct {
+	TX             uint64                 `json:"tx"`
+	Vote           uint16                 `json:"vote"`
+	InclusionProof *schema.InclusionProof `json:"inclusion_proof"`
+	DualProof      *schema.DualProof      `json:"dual_proof"`
+}
+
+// AuditSweepBallot ...
+type AuditSweepBallot struct {
+	BallotID string                   `json:"ballot_id"`
+	Vote     uint16                   `json:"vote"`
+	History  []AuditSweepHistoryEntry `json:"history"`
+}
+
+// AuditSweepResponse ...
+type AuditSweepResponse struct {
+	StateTX    uint64             `json:"state_tx"`
+	Ballots    []AuditSweepBallot `json:"ballots"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func (s *Server) auditSweepBallot(ctx context.Context, ballotKey []byte, stateTX uint64) (*AuditSweepBallot, error) {
+	ballotID := strings.TrimPrefix(string(ballotKey), ballotPrefix)
+	historyEntries, err := historyAll(ctx, s.store, ballotKey)
+	if err != nil {
+		return nil, fmt.Errorf("error loading history for ballot %s: %v", ballotID, err)
+	}
+	ballot := AuditSweepBallot{BallotID: ballotID}
+	for _, historyEntry := range historyEntries {
+		if historyEntry.GetTx() > stateTX {
+			break
+		}
+		verifiableEntry, err := s.store.VerifiableGetAt(ctx,
+			ballotKey, historyEntry.GetTx(), stateTX)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching verifiable ballot %s at tx %d: %v",
+				ballotID, historyEntry.GetTx(), err)
+		}
+		vote := binary.BigEndian.Uint16(verifiableEntry.GetEntry().GetValue())
+		ballot.History = append(ballot.History, AuditSweepHistoryEntry{
+			TX:             historyEntry.GetTx(),
+			Vote:           vote,
+			InclusionProof: verifiableEntry.GetInclusionProof(),
+			DualProof:      verifiableEntry.GetVerifiableTx().GetDualProof(),
+		})
+		ballot.Vote = vote
+	}
+	if len(ballot.History) == 0 {
+		return nil, nil
+	}
+	return &ballot, nil
+}
+
+func (s *Server) getAuditSweepHandler(w http.ResponseWriter, r *http.Request) {
+	var stateTX uint64
+	if stateTXStr := r.URL.Query().Get("state_tx"); len(stateTXStr) > 0 {
+		var err error
+		if stateTX, err = strconv.ParseUint(stateTXStr, 10, 64); err != nil || stateTX == 0 {
+			writeErrorResponse(r, w, http.StatusBadRequest, err,
+				"state_tx query param is not a positive unsigned int")
+			return
+		}
+	} else {
+		state, err := s.store.CurrentState(r.Context())
+		if err != nil {
+			writeErrorResponse(r, w, http.StatusInternalServerError, err,
+				"error fetching current state")
+			return
+		}
+		stateTX = state.GetTxId()
+	}
+
+	limit := uint64(auditSweepDefaultLimit)
+	if limitStr := r.URL.Query().Get("limit"); len(limitStr) > 0 {
+		var err error
+		if limit, err = strconv.ParseUint(limitStr, 10, 32); err != nil ||
+			limit == 0 || limit > database.MaxKeyScanLimit {
+			writeErrorResponse(r, w, http.StatusBadRequest, err, fmt.Sprintf(
+				"limit query param must be between 1 and %d", database.MaxKeyScanLimit))
+			return
+		}
+	}
+
+	ballotEntries, nextCursor, err := scanPage(r.Context(), s.store,
+		[]byte(ballotPrefix), r.URL.Query().Get("cursor"), limit)
+	if errors.Is(err, errInvalidCursor) {
+		writeErrorResponse(r, w, http.StatusBadRequest, err, "cursor query param is invalid")
+		return
+	} else if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error scanning ballots")
+		return
+	}
+
+	resPayload := AuditSweepResponse{
+		StateTX:    stateTX,
+		Ballots:    make([]AuditSweepBallot, 0, len(ballotEntries)),
+		NextCursor: nextCursor,
+	}
+	for _, ballotEntry := range ballotEntries {
+		ballot, err := s.auditSweepBallot(r.Context(), ballotEntry.GetKey(), stateTX)
+		if err != nil {
+			writeErrorResponse(r, w, http.StatusInternalServerError, err,
+				"error sweeping ballots")
+			return
+		}
+		if ballot != nil {
+			// nil if the ballot has been created after the sweep state
+			resPayload.Ballots = append(resPayload.Ballots, *ballot)
+		}
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, &resPayload)
+}
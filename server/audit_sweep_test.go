@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/signer"
+)
+
+// TestVerifierSweep runs the sweep of the verifier CLI against the server,
+// with pages smaller than the ballots
+func TestVerifierSweep(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating signing key: %v", err)
+	}
+	server.stateSigner = signer.NewSignerFromPKey(rand.Reader, key)
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("error marshaling public key: %v", err)
+	}
+	dir := t.TempDir()
+	publicKeyFile := filepath.Join(dir, "signing.pub")
+	if err := ioutil.WriteFile(publicKeyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER}), 0600); err != nil {
+		t.Fatalf("error writing public key: %v", err)
+	}
+
+	for citizenID, vote := range map[string]uint16{"alice": NikkiHaley, "bob": KamalaHarris, "carol": 0} {
+		voter := registerTestVoter(t, httpServer.URL, citizenID, "north-1-a")
+		if vote == 0 {
+			continue
+		}
+		if status := doJSON(t, http.MethodPost, httpServer.URL+apiV1Prefix+"/elections/"+electionID+"/votes", false,
+			&VoteRequest{RegisterVoterResponse: *voter, Vote: vote}, nil); status != http.StatusNoContent {
+			t.Fatalf("voting as %s: got status %d, want %d", citizenID, status, http.StatusNoContent)
+		}
+	}
+
+	verifier := buildCommand(t, "./verifier")
+	sweep := func(args ...string) (string, error) {
+		out, err := exec.Command(verifier, append([]string{"sweep",
+			"-server", httpServer.URL, "-state", filepath.Join(dir, "state.json"), "-limit", "2"}, args...)...).
+			CombinedOutput()
+		return string(out), err
+	}
+	if out, err := sweep(); err == nil {
+		t.Errorf("sweeping without the public key of the server: got no error, output %s", out)
+	}
+	out, err := sweep("-pubkey", publicKeyFile)
+	if err != nil {
+		t.Fatalf("sweeping: %v: %s", err, out)
+	}
+	if !strings.Contains(out, "swept 3 ballots") || !strings.Contains(out, "2 cast, 1 not cast") ||
+		!strings.Contains(out, "coverage: 3 of 3 registered voters") {
+		t.Errorf("got sweep output %s, want 3 ballots swept, 2 cast, of 3 registered voters", out)
+	}
+}
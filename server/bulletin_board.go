@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+const bulletinBoardDefaultLimit = 100
+
+// BulletinBoardEntry is an entry of a tx, as published on the bulletin board:
+// its key is never exposed (it may hold PII, like the citizen ID, or secrets,
+// like the voter ID), only its type and, unless it is a voter or a citizen
+// entry, its digest; the digest of a citizen entry would be enough to tell
+// whether a citizen has registered, and when, by hashing the candidate citizen
+// IDs, and the one of a voter entry would expose a hash of the voter record
+type BulletinBoardEntry struct {
+	KeyType   string `json:"key_type"`
+	ValueHash []byte `json:"value_hash,omitempty"`
+	Digest    []byte `json:"digest,omitempty"`
+}
+
+// BulletinBoardTX holds everything needed to recompute the accumulated hash
+// (Alh) of the tx, which links it to the previous one, from its entries hash
+// (EH) and its number of entries; the EH is the root of the Merkle tree built
+// from the digests of the entries, in order, so it can only be recomputed from
+// the txs whose entries all have their digest published
+type BulletinBoardTX struct {
+	TXID    uint64               `json:"tx_id"`
+	Ts      int64                `json:"ts"`
+	PrevAlh []byte               `json:"prev_alh"`
+	EH      []byte               `json:"eh"`
+	BlTXID  uint64               `json:"bl_tx_id"`
+	BlRoot  []byte               `json:"bl_root"`
+	Alh     []byte               `json:"alh"`
+	Entries []BulletinBoardEntry `json:"entries"`
+}
+
+// BulletinBoardResponse ...
+type BulletinBoardResponse struct {
+	TXs    []BulletinBoardTX `json:"txs"`
+	NextTX uint64            `json:"next_tx"`
+}
+
+func keyType(key []byte) string {
+	switch {
+	case bytes.HasPrefix(key, []byte(voterPrefix)):
+		return "voter"
+	case bytes.HasPrefix(key, []byte(citizenPrefix)):
+		return "citizen"
+	case bytes.HasPrefix(key, []byte(ballotPrefix)):
+		return "ballot"
+	case bytes.HasPrefix(key, []byte(ballotPrecinctPrefix)):
+		return "ballot-precinct"
+	case bytes.Equal(key, []byte(electionKey)):
+		return "election"
+	case bytes.Equal(key, []byte(tallyKey)):
+		return "tally"
+	case bytes.HasPrefix(key, []byte(rlaKey)):
+		return "rla"
+	case bytes.HasPrefix(key, []byte(certificationKey)):
+		return "certification"
+	case bytes.HasPrefix(key, []byte(idempotencyPrefix)):
+		return "idempotency"
+	default:
+		return "other"
+	}
+}
+
+func bulletinBoardTX(tx *schema.Tx) BulletinBoardTX {
+	md := tx.GetMetadata()
+	alh := schema.TxMetadataFrom(md).Alh()
+	bbTX := BulletinBoardTX{
+		TXID:    md.GetId(),
+		Ts:      md.GetTs(),
+		PrevAlh: md.GetPrevAlh(),
+		EH:      md.GetEH(),
+		BlTXID:  md.GetBlTxId(),
+		BlRoot:  md.GetBlRoot(),
+		Alh:     alh[:],
+		Entries: make([]BulletinBoardEntry, 0, len(tx.GetEntries())),
+	}
+	for _, txEntry := range tx.GetEntries() {
+		entry := BulletinBoardEntry{KeyType: keyType(database.TrimPrefix(txEntry.GetKey()))}
+		if entry.KeyType != "voter" && entry.KeyType != "citizen" {
+			// same as the digest computed by immudb for each entry of the tx
+			digest := sha256.Sum256(append(append([]byte{}, txEntry.GetKey()...), txEntry.GetHValue()...))
+			entry.ValueHash, entry.Digest = txEntry.GetHValue(), digest[:]
+		}
+		bbTX.Entries = append(bbTX.Entries, entry)
+	}
+	return bbTX
+}
+
+func (s *Server) getBulletinBoardHandler(w http.ResponseWriter, r *http.Request) {
+	sinceTX := uint64(1)
+	if sinceTXStr := r.URL.Query().Get("since_tx"); len(sinceTXStr) > 0 {
+		var err error
+		if sinceTX, err = strconv.ParseUint(sinceTXStr, 10, 64); err != nil || sinceTX == 0 {
+			writeErrorResponse(r, w, http.StatusBadRequest, err,
+				"since_tx query param is not a positive unsigned int")
+			return
+		}
+	}
+	limit := uint64(bulletinBoardDefaultLimit)
+	if limitStr := r.URL.Query().Get("limit"); len(limitStr) > 0 {
+		var err error
+		if limit, err = strconv.ParseUint(limitStr, 10, 32); err != nil ||
+			limit == 0 || limit > database.MaxKeyScanLimit {
+			writeErrorResponse(r, w, http.StatusBadRequest, err, fmt.Sprintf(
+				"limit query param must be between 1 and %d", database.MaxKeyScanLimit))
+			return
+		}
+	}
+
+	resPayload := BulletinBoardResponse{
+		TXs:    []BulletinBoardTX{},
+		NextTX: sinceTX,
+	}
+
+	state, err := s.store.CurrentState(r.Context())
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error fetching current state")
+		return
+	}
+	if sinceTX > state.GetTxId() {
+		writeJSONResponse(r, w, http.StatusOK, &resPayload)
+		return
+	}
+
+	txs, err := s.store.TxScan(r.Context(), sinceTX, uint32(limit))
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error scanning txs")
+		return
+	}
+	for _, tx := range txs {
+		resPayload.TXs = append(resPayload.TXs, bulletinBoardTX(tx))
+		resPayload.NextTX = tx.GetMetadata().GetId() + 1
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, &resPayload)
+}
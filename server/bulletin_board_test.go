@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+func TestBulletinBoard(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	ctx := context.Background()
+	voter := registerTestVoter(t, httpServer.URL, "alice", "north-1-a")
+	registerTestVoter(t, httpServer.URL, "bob", "south-1-b")
+	if err := server.vote(ctx, &VoteRequest{RegisterVoterResponse: *voter, Vote: NikkiHaley}); err != nil {
+		t.Fatalf("error voting: %v", err)
+	}
+
+	resp, err := http.Get(httpServer.URL + apiV1Prefix + "/elections/" + electionID + "/bulletin-board")
+	if err != nil {
+		t.Fatalf("error fetching bulletin board: %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("fetching bulletin board: got status %d, %v, want %d", resp.StatusCode, err, http.StatusOK)
+	}
+	var board BulletinBoardResponse
+	if err := json.Unmarshal(body, &board); err != nil {
+		t.Fatalf("error JSON-unmarshaling bulletin board: %v", err)
+	}
+	var voters, citizens int
+	for _, tx := range board.TXs {
+		for _, entry := range tx.Entries {
+			hidden := entry.KeyType == "voter" || entry.KeyType == "citizen"
+			if hidden != (len(entry.Digest) == 0) || hidden != (len(entry.ValueHash) == 0) {
+				t.Errorf("tx %d: got %s entry with digest %x and value hash %x, want them only for entries without PII",
+					tx.TXID, entry.KeyType, entry.Digest, entry.ValueHash)
+			}
+			switch entry.KeyType {
+			case "voter":
+				voters++
+			case "citizen":
+				citizens++
+			}
+		}
+	}
+	// the registrations and the vote of alice
+	if voters != 3 || citizens != 2 {
+		t.Errorf("got %d voter and %d citizen entries, want 3 and 2", voters, citizens)
+	}
+
+	// the digests of the citizen entries, which can be computed from the citizen
+	// IDs, are nowhere in the response
+	txs, err := server.store.TxScan(ctx, 1, 1000)
+	if err != nil {
+		t.Fatalf("error scanning txs: %v", err)
+	}
+	for _, tx := range txs {
+		for _, txEntry := range tx.GetEntries() {
+			key := database.TrimPrefix(txEntry.GetKey())
+			if !bytes.HasPrefix(key, []byte(citizenPrefix)) {
+				continue
+			}
+			digest := sha256.Sum256(append(append([]byte{}, txEntry.GetKey()...), txEntry.GetHValue()...))
+			if bytes.Contains(body, []byte(base64.StdEncoding.EncodeToString(digest[:]))) {
+				t.Errorf("got the digest of entry %s on the bulletin board", key)
+			}
+		}
+	}
+
+	// the verifier mirrors and verifies it, across pages
+	out, err := exec.Command(buildCommand(t, "./verifier"), "mirror", "-server", httpServer.URL,
+		"-file", filepath.Join(t.TempDir(), "bulletin-board.jsonl"), "-limit", "2").CombinedOutput()
+	if err != nil {
+		t.Fatalf("mirroring bulletin board: %v: %s", err, out)
+	}
+	if want := fmt.Sprintf("mirrored %d new txs, head tx %d", len(txs), len(txs)); !strings.Contains(string(out), want) {
+		t.Errorf("got mirror output %s, want %q", out, want)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// The certification closes the election: it fixes the final results in a
+// canonical document, persisted in immudb, which the officials then sign. Once
+// the document has been persisted, no voter can register or vote anymore.
+const (
+	certificationKey             = "immuvoting:certification"
+	certificationSignaturePrefix = "immuvoting:certification:signature:"
+)
+
+var errElectionClosed = errors.New("election has been closed")
+
+// Official is an election official who can sign the results
+type Official struct {
+	Name string `json:"name"`
+	// Ed25519 public key
+	PublicKey []byte `json:"public_key"`
+}
+
+// LoadOfficials loads the officials from a JSON file: an array of their names
+// and (base64) public keys
+func LoadOfficials(file string) ([]Official, error) {
+	officialsBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading officials from %s: %v", file, err)
+	}
+	var officials []Official
+	if err := json.Unmarshal(officialsBytes, &officials); err != nil {
+		return nil, fmt.Errorf("error JSON-unmarshaling officials from %s: %v", file, err)
+	}
+	names := make(map[string]bool, len(officials))
+	for i, official := range officials {
+		if len(official.Name) == 0 || names[official.Name] {
+			return nil, fmt.Errorf("official %d has no name or a duplicate one", i)
+		}
+		names[official.Name] = true
+		if len(official.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("official %s has no valid Ed25519 public key", official.Name)
+		}
+	}
+	return officials, nil
+}
+
+// ResultsDocument is the canonical document of the final results, as of the
+// closing tx; the officials sign its JSON encoding, as persisted in immudb
+type ResultsDocument struct {
+	ElectionID string `json:"election_id"`
+	// SHA-256 of the election definition, as persisted in immudb
+	ElectionHash  []byte           `json:"election_hash"`
+	ClosingTXID   uint64           `json:"closing_tx_id"`
+	ClosingTXHash []byte           `json:"closing_tx_hash"`
+	Closed        time.Time        `json:"closed"`
+	Contests      []ContestResults `json:"contests"`
+	Turnout       ResultsTurnout   `json:"turnout"`
+}
+
+// ContestResults are the totals of a contest, one per candidate, in the order
+// of the election definition
+type ContestResults struct {
+	Name    string           `json:"name"`
+	Ballots uint64           `json:"ballots"`
+	Totals  []CandidateTotal `json:"totals"`
+}
+
+// CandidateTotal ...
+type CandidateTotal struct {
+	CandidateID uint16 `json:"candidate_id"`
+	Name        string `json:"name"`
+	Votes       uint64 `json:"votes"`
+}
+
+// ResultsTurnout ...
+type ResultsTurnout struct {
+	Registered uint64 `json:"registered"`
+	Voted      uint64 `json:"voted"`
+	Ballots    uint64 `json:"ballots"`
+}
+
+// CertificationSignature is the signature of the results document by an
+// official
+type CertificationSignature struct {
+	Official  string    `json:"official"`
+	PublicKey []byte    `json:"public_key"`
+	Signature []byte    `json:"signature"`
+	Signed    time.Time `json:"signed"`
+}
+
+// CertificationResponse ...
+type CertificationResponse struct {
+	// the results document, as persisted and signed
+	Document []byte `json:"document"`
+	// ID of the tx in which the document has been persisted
+	TXID       uint64                   `json:"tx_id"`
+	Results    *ResultsDocument         `json:"results"`
+	Signatures []CertificationSignature `json:"signatures"`
+}
+
+// SignCertificationRequest ...
+type SignCertificationRequest struct {
+	Official string `json:"official"`
+	// Ed25519 signature of the document
+	Signature []byte `json:"signature"`
+}
+
+// LoadCertification loads the closing tx of the election, if it has been closed
+func (s *Server) LoadCertification(ctx context.Context) error {
+	documentBytes, err := s.store.GetLatest(ctx, []byte(certificationKey))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error fetching results document: %v", err)
+	}
+	var document ResultsDocument
+	if err := json.Unmarshal(documentBytes, &document); err != nil {
+		return fmt.Errorf("error JSON-unmarshaling results document: %v", err)
+	}
+	s.tallyMu.Lock()
+	s.closedTX = document.ClosingTXID
+	s.tallyMu.Unlock()
+	return nil
+}
+
+// closeElection closes the election at the current tx and persists the results
+// document; it holds the tally lock, so that no vote is cast in between
+func (s *Server) closeElection(ctx context.Context) (*CertificationResponse, error) {
+	s.tallyMu.Lock()
+	defer s.tallyMu.Unlock()
+	if s.closedTX > 0 {
+		return nil, newAPIError(http.StatusConflict, ErrCodeElectionAlreadyClosed, nil,
+			fmt.Sprintf("election has already been closed at tx %d", s.closedTX))
+	}
+
+	state, err := s.store.CurrentState(ctx)
+	if err != nil {
+		return nil, internalError(err, "error fetching current state")
+	}
+	txs, err := s.store.TxScan(ctx, state.GetTxId(), 1)
+	if err != nil || len(txs) == 0 {
+		return nil, internalError(err, fmt.Sprintf("error fetching closing tx %d", state.GetTxId()))
+	}
+	electionBytes, err := s.store.GetLatest(ctx, []byte(electionKey))
+	if err != nil {
+		return nil, internalError(err, "error fetching election definition")
+	}
+	electionHash := sha256.Sum256(electionBytes)
+
+	contest := ContestResults{Name: election.Name, Ballots: s.tally.Ballots}
+	for _, candidate := range election.Candidates {
+		contest.Totals = append(contest.Totals, CandidateTotal{
+			CandidateID: candidate.ID,
+			Name:        candidate.Name,
+			Votes:       s.tally.Results[candidate.ID],
+		})
+	}
+	document := ResultsDocument{
+		ElectionID:    electionID,
+		ElectionHash:  electionHash[:],
+		ClosingTXID:   state.GetTxId(),
+		ClosingTXHash: state.GetTxHash(),
+		Closed:        time.Unix(txs[0].GetMetadata().GetTs(), 0).UTC(),
+		Contests:      []ContestResults{contest},
+		Turnout: ResultsTurnout{
+			Registered: s.tally.Registered,
+			Voted:      s.tally.Voted,
+			Ballots:    s.tally.Ballots,
+		},
+	}
+	// the document always marshals
+	documentBytes, _ := json.Marshal(&document)
+	txID, err := s.store.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: []byte(certificationKey), Value: documentBytes}}},
+	}})
+	if err != nil {
+		return nil, internalError(err, "error persisting results document")
+	}
+	s.closedTX = document.ClosingTXID
+	contextLogger(ctx).WithField("closing_tx_id", document.ClosingTXID).Info("election closed")
+
+	return &CertificationResponse{
+		Document:   documentBytes,
+		TXID:       txID,
+		Results:    &document,
+		Signatures: []CertificationSignature{},
+	}, nil
+}
+
+// certification returns the results document with its signatures
+func (s *Server) certification(ctx context.Context) (*CertificationResponse, error) {
+	documentEntry, err := s.store.GetLatestEntry(ctx, []byte(certificationKey))
+	if errors.Is(err, ErrNotFound) {
+		return nil, newAPIError(http.StatusNotFound, ErrCodeElectionNotClosed, nil,
+			"election has not been closed yet")
+	} else if err != nil {
+		return nil, internalError(err, "error fetching results document")
+	}
+	res := CertificationResponse{
+		Document:   documentEntry.GetValue(),
+		TXID:       documentEntry.GetTx(),
+		Signatures: []CertificationSignature{},
+	}
+	if err := json.Unmarshal(res.Document, &res.Results); err != nil {
+		return nil, internalError(err, "error JSON-unmarshaling results document")
+	}
+	if err := scanEach(ctx, s.store, []byte(certificationSignaturePrefix), func(entry *schema.Entry) error {
+		var signature CertificationSignature
+		if err := json.Unmarshal(entry.GetValue(), &signature); err != nil {
+			return fmt.Errorf("error JSON-unmarshaling signature %s: %v", entry.GetKey(), err)
+		}
+		res.Signatures = append(res.Signatures, signature)
+		return nil
+	}); err != nil {
+		return nil, internalError(err, "error scanning signatures")
+	}
+	return &res, nil
+}
+
+// signCertification verifies the signature of the results document by the
+// official and persists it, once per official
+func (s *Server) signCertification(ctx context.Context, req *SignCertificationRequest) (*CertificationResponse, error) {
+	var official *Official
+	for i := range s.officials {
+		if s.officials[i].Name == req.Official {
+			official = &s.officials[i]
+		}
+	}
+	if official == nil {
+		return nil, invalidRequestError(validationErrors{{Field: "official", Message: "no such official"}})
+	}
+
+	signatureKey := []byte(certificationSignaturePrefix + official.Name)
+	unlock := s.locks.Lock(string(signatureKey))
+	defer unlock()
+
+	res, err := s.certification(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(official.PublicKey, res.Document, req.Signature) {
+		return nil, newAPIError(http.StatusBadRequest, ErrCodeInvalidSignature, nil,
+			fmt.Sprintf("signature does not verify with the public key of official %s", official.Name))
+	}
+	if _, err := s.store.GetLatest(ctx, signatureKey); err == nil {
+		return nil, newAPIError(http.StatusConflict, ErrCodeAlreadySigned, nil,
+			fmt.Sprintf("official %s has already signed the results", official.Name))
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, internalError(err, "error checking existing signature")
+	}
+
+	signature := CertificationSignature{
+		Official:  official.Name,
+		PublicKey: official.PublicKey,
+		Signature: req.Signature,
+		Signed:    time.Now().UTC(),
+	}
+	signatureBytes, err := json.Marshal(&signature)
+	if err != nil {
+		return nil, internalError(err, "error JSON-marshaling signature")
+	}
+	if err := s.store.Set(ctx, signatureKey, signatureBytes); err != nil {
+		return nil, internalError(err, "error persisting signature")
+	}
+	res.Signatures = append(res.Signatures, signature)
+	return res, nil
+}
+
+func (s *Server) closeElectionHandler(w http.ResponseWriter, r *http.Request) {
+	resPayload, err := s.closeElection(r.Context())
+	if err != nil {
+		writeAPIError(r, w, err)
+		return
+	}
+	writeJSONResponse(r, w, http.StatusOK, resPayload)
+}
+
+func (s *Server) getCertificationHandler(w http.ResponseWriter, r *http.Request) {
+	resPayload, err := s.certification(r.Context())
+	if err != nil {
+		writeAPIError(r, w, err)
+		return
+	}
+	writeJSONResponse(r, w, http.StatusOK, resPayload)
+}
+
+func (s *Server) signCertificationHandler(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var payload SignCertificationRequest
+	if err := decoder.Decode(&payload); err != nil {
+		writeAPIErrorResponse(r, w, http.StatusBadRequest, ErrCodeInvalidJSON, nil,
+			fmt.Sprintf("error parsing request body: %v", err))
+		return
+	}
+	payload.Official = strings.TrimSpace(payload.Official)
+
+	resPayload, err := s.signCertification(r.Context(), &payload)
+	if err != nil {
+		writeAPIError(r, w, err)
+		return
+	}
+	writeJSONResponse(r, w, http.StatusOK, resPayload)
+}
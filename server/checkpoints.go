@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// how often the checkpoint hub polls the current state, while it has subscribers
+const checkpointPollInterval = time.Second
+
+// Checkpoint is a new state of the database, i.e. a committed tx
+type Checkpoint struct {
+	TXID uint64 `json:"tx_id"`
+	// base64 in JSON, like the tx_hash of the state
+	TXHash []byte `json:"tx_hash"`
+}
+
+// checkpointHub pushes the checkpoints to its subscribers as they happen: it
+// polls the current state (so that it also sees the txs committed by other
+// writers), but only while it has subscribers
+type checkpointHub struct {
+	store Store
+
+	mu          sync.Mutex
+	subscribers map[chan Checkpoint]struct{}
+	stop        chan struct{}
+}
+
+func newCheckpointHub(store Store) *checkpointHub {
+	return &checkpointHub{store: store, subscribers: map[chan Checkpoint]struct{}{}}
+}
+
+// Subscribe returns the channel of the checkpoints after the current one and
+// the func to unsubscribe; a subscriber which falls behind only gets the latest
+// checkpoint, since each one covers all the previous txs
+func (h *checkpointHub) Subscribe() (<-chan Checkpoint, func()) {
+	ch := make(chan Checkpoint, 1)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	if len(h.subscribers) == 1 {
+		h.stop = make(chan struct{})
+		go h.poll(h.stop)
+	}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers, ch)
+			if len(h.subscribers) == 0 {
+				close(h.stop)
+			}
+		})
+	}
+}
+
+func (h *checkpointHub) poll(stop chan struct{}) {
+	ticker := time.NewTicker(checkpointPollInterval)
+	defer ticker.Stop()
+	var latestTXID uint64
+	for {
+		state, err := h.store.CurrentState(context.Background())
+		if err != nil {
+			logger.WithError(err).Warn("error polling current state for checkpoints")
+		} else if state.GetTxId() != latestTXID {
+			// the first state is the baseline, not a new checkpoint
+			if latestTXID > 0 {
+				h.broadcast(Checkpoint{
+					TXID:   state.GetTxId(),
+					TXHash: state.GetTxHash(),
+				})
+			}
+			latestTXID = state.GetTxId()
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *checkpointHub) broadcast(checkpoint Checkpoint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- checkpoint:
+		default:
+			// replace the checkpoint not yet received with the latest one
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- checkpoint
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultAdminUser      = "admin"
+	defaultAdminPassword  = "admin"
+	defaultImmudbUser     = "immudb"
+	defaultImmudbPassword = "immudb"
+
+	configEnvPrefix = "IMMUVOTING"
+	redacted        = "********"
+)
+
+// Config is the configuration of the server; it is loaded, from lowest to
+// highest precedence, from the defaults, the config file (YAML, TOML or JSON),
+// the IMMUVOTING_* env vars (e.g. IMMUVOTING_ADMIN_PASSWORD) and the flags
+type Config struct {
+	Host string `mapstructure:"host" json:"host"`
+	Port int    `mapstructure:"port" json:"port"`
+	// port of the gRPC API, on the same host; 0 disables it
+	GRPCPort       int    `mapstructure:"grpc-port" json:"grpc-port"`
+	AdminUser      string `mapstructure:"admin-user" json:"admin-user"`
+	AdminPassword  string `mapstructure:"admin-password" json:"admin-password"`
+	Embedded       bool   `mapstructure:"embedded" json:"embedded"`
+	DataDir        string `mapstructure:"data-dir" json:"data-dir"`
+	ImmudbAddress  string `mapstructure:"immudb-address" json:"immudb-address"`
+	ImmudbDB       string `mapstructure:"immudb-db" json:"immudb-db"`
+	ImmudbUser     string `mapstructure:"immudb-user" json:"immudb-user"`
+	ImmudbPassword string `mapstructure:"immudb-password" json:"immudb-password"`
+	LocalStateDir  string `mapstructure:"local-state-dir" json:"local-state-dir"`
+	// TLS of the HTTP API
+	TLSCert           string        `mapstructure:"tls-cert" json:"tls-cert"`
+	TLSKey            string        `mapstructure:"tls-key" json:"tls-key"`
+	TLSReloadInterval time.Duration `mapstructure:"tls-reload-interval" json:"tls-reload-interval"`
+	// TLS (and mutual TLS, if a client cert is given) to immudb
+	ImmudbTLS           bool   `mapstructure:"immudb-tls" json:"immudb-tls"`
+	ImmudbTLSCA         string `mapstructure:"immudb-tls-ca" json:"immudb-tls-ca"`
+	ImmudbTLSCert       string `mapstructure:"immudb-tls-cert" json:"immudb-tls-cert"`
+	ImmudbTLSKey        string `mapstructure:"immudb-tls-key" json:"immudb-tls-key"`
+	ImmudbTLSServerName string `mapstructure:"immudb-tls-server-name" json:"immudb-tls-server-name"`
+	// how long to keep serving, with the readiness check failing, before
+	// shutting down, and how long to wait for the in-flight requests then
+	ShutdownDelay   time.Duration `mapstructure:"shutdown-delay" json:"shutdown-delay"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown-timeout" json:"shutdown-timeout"`
+	// how often to verify the tally against a full scan of the voters and the
+	// ballots
+	TallyReconcileInterval time.Duration `mapstructure:"tally-reconcile-interval" json:"tally-reconcile-interval"`
+	// JSON file of the officials who can sign the results
+	OfficialsFile string `mapstructure:"officials-file" json:"officials-file"`
+	// how many of the officials must sign the results for them to be
+	// certified, all of them if 0 (as the -quorum of verify-certification)
+	CertificationQuorum int `mapstructure:"certification-quorum" json:"certification-quorum"`
+	// ECDSA private key (PEM) the states served by the server are signed with
+	SigningKey string `mapstructure:"signing-key" json:"signing-key"`
+	// log format (json or text), level and output (stderr, stdout or a file)
+	LogFormat string `mapstructure:"log-format" json:"log-format"`
+	LogLevel  string `mapstructure:"log-level" json:"log-level"`
+	LogOutput string `mapstructure:"log-output" json:"log-output"`
+	// allows the default credentials, for local development only
+	Dev bool `mapstructure:"dev" json:"dev"`
+	// file the config has been loaded from, if any
+	File string `mapstructure:"-" json:"file,omitempty"`
+	// print the effective config and exit
+	PrintConfig bool `mapstructure:"print-config" json:"-"`
+}
+
+// LoadConfig loads and validates the config, given the command line args
+// (without the program name)
+func LoadConfig(args []string) (*Config, error) {
+	flags := pflag.NewFlagSet("immuvoting", pflag.ContinueOnError)
+	configFile := flags.String("config", "",
+		"config file (YAML, TOML or JSON); by default ./immuvoting.{yaml,toml,json}, if any")
+	flags.String("host", "localhost", "host (interface) the HTTP server listens on")
+	flags.Int("port", 8080, "port the HTTP server listens on")
+	flags.Int("grpc-port", 50051, "port the gRPC server listens on (TLS as for the HTTP API); 0 disables it")
+	flags.String("admin-user", defaultAdminUser, "immuvoting admin user")
+	flags.String("admin-password", defaultAdminPassword, "immuvoting admin password")
+	flags.Bool("embedded", false,
+		"run the immudb database in-process, from --data-dir, instead of connecting to an immudb server")
+	flags.String("data-dir", "./data", "data dir of the embedded immudb database")
+	flags.String("immudb-address", "localhost:3322", "address of the immudb server")
+	flags.String("immudb-db", "defaultdb", "immudb database")
+	flags.String("immudb-user", defaultImmudbUser, "immudb user")
+	flags.String("immudb-password", defaultImmudbPassword, "immudb password")
+	flags.String("local-state-dir", "", "dir where the immudb client keeps the last verified state")
+	flags.String("tls-cert", "", "TLS cert (PEM) of the HTTP API; serves HTTPS if set")
+	flags.String("tls-key", "", "TLS key (PEM) of the HTTP API")
+	flags.Duration("tls-reload-interval", 0,
+		"how often to check the TLS cert and key files for changes and reload them (e.g. 1m); 0 disables reloading")
+	flags.Bool("immudb-tls", false, "connect to immudb over TLS")
+	flags.String("immudb-tls-ca", "", "CA cert (PEM) of the immudb server; the system CAs are used if not set")
+	flags.String("immudb-tls-cert", "", "client cert (PEM) for mutual TLS to immudb")
+	flags.String("immudb-tls-key", "", "client key (PEM) for mutual TLS to immudb")
+	flags.String("immudb-tls-server-name", "", "name to verify the immudb server cert against, if not the host of immudb-address")
+	flags.Duration("shutdown-delay", 0,
+		"how long to keep serving, with /readyz failing, after SIGTERM before shutting down (e.g. 5s)")
+	flags.Duration("shutdown-timeout", 30*time.Second, "how long to wait for the in-flight requests on shutdown")
+	flags.Duration("tally-reconcile-interval", 10*time.Minute,
+		"how often to verify the tally against a full scan of the voters and the ballots; 0 disables it")
+	flags.String("officials-file", "",
+		"JSON file of the names and the Ed25519 public keys (base64) of the officials who sign the results; none can sign if not set")
+	flags.Int("certification-quorum", 0,
+		"how many of the officials must sign the results for them to be certified; 0 requires all of them "+
+			"(pass the same -quorum to verify-certification)")
+	flags.String("signing-key", "",
+		"ECDSA P-256 private key (PEM) the server signs its states with, e.g. those of the audit bundles; "+
+			"if not set, the states are only signed if immudb runs with a signing key")
+	flags.String("log-format", "json", "log format: json or text")
+	flags.String("log-level", "info", "log level: debug, info, warn or error")
+	flags.String("log-output", "stderr", "log output: stderr, stdout or a file path")
+	flags.Bool("dev", false, "allow the default credentials (for local development only)")
+	flags.Bool("print-config", false, "print the effective config, with secrets redacted, and exit")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	v := viper.New()
+	if err := v.BindPFlags(flags); err != nil {
+		return nil, fmt.Errorf("error binding flags: %v", err)
+	}
+	v.SetEnvPrefix(configEnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	if len(*configFile) > 0 {
+		v.SetConfigFile(*configFile)
+	} else {
+		v.SetConfigName("immuvoting")
+		v.AddConfigPath(".")
+	}
+	if err := v.ReadInConfig(); err != nil {
+		var notFoundErr viper.ConfigFileNotFoundError
+		if len(*configFile) > 0 || !errors.As(err, &notFoundErr) {
+			return nil, fmt.Errorf("error reading config file: %v", err)
+		}
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("error parsing config: %v", err)
+	}
+	config.File = v.ConfigFileUsed()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Validate ...
+func (c *Config) Validate() error {
+	var errs []string
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("port %d is not between 1 and 65535", c.Port))
+	}
+	if c.GRPCPort < 0 || c.GRPCPort > 65535 {
+		errs = append(errs, fmt.Sprintf("grpc-port %d is not between 0 and 65535", c.GRPCPort))
+	} else if c.GRPCPort == c.Port {
+		errs = append(errs, fmt.Sprintf("grpc-port %d is the same as port", c.GRPCPort))
+	}
+	if len(c.AdminUser) == 0 || len(c.AdminPassword) == 0 {
+		errs = append(errs, "admin-user and admin-password are required")
+	}
+	if c.Embedded {
+		if len(c.DataDir) == 0 {
+			errs = append(errs, "data-dir is required in embedded mode")
+		}
+	} else {
+		if _, _, err := net.SplitHostPort(c.ImmudbAddress); err != nil {
+			errs = append(errs, fmt.Sprintf("immudb-address %q is not a host:port address", c.ImmudbAddress))
+		}
+		if len(c.ImmudbUser) == 0 || len(c.ImmudbPassword) == 0 {
+			errs = append(errs, "immudb-user and immudb-password are required")
+		}
+	}
+	if (len(c.TLSCert) > 0) != (len(c.TLSKey) > 0) {
+		errs = append(errs, "tls-cert and tls-key must be set together")
+	}
+	if c.TLSReloadInterval < 0 {
+		errs = append(errs, "tls-reload-interval can not be negative")
+	} else if c.TLSReloadInterval > 0 && len(c.TLSCert) == 0 {
+		errs = append(errs, "tls-reload-interval requires tls-cert and tls-key")
+	}
+	if (len(c.ImmudbTLSCert) > 0) != (len(c.ImmudbTLSKey) > 0) {
+		errs = append(errs, "immudb-tls-cert and immudb-tls-key must be set together")
+	}
+	if !c.ImmudbTLS && (len(c.ImmudbTLSCA) > 0 || len(c.ImmudbTLSCert) > 0 || len(c.ImmudbTLSServerName) > 0) {
+		errs = append(errs, "immudb-tls-ca, immudb-tls-cert, immudb-tls-key and immudb-tls-server-name require immudb-tls")
+	}
+	if c.ShutdownDelay < 0 || c.ShutdownTimeout < 0 {
+		errs = append(errs, "shutdown-delay and shutdown-timeout can not be negative")
+	}
+	if c.TallyReconcileInterval < 0 {
+		errs = append(errs, "tally-reconcile-interval can not be negative")
+	}
+	if c.CertificationQuorum < 0 {
+		errs = append(errs, "certification-quorum can not be negative")
+	} else if c.CertificationQuorum > 0 && len(c.OfficialsFile) == 0 {
+		errs = append(errs, "certification-quorum requires officials-file")
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		errs = append(errs, fmt.Sprintf("log-format %q is neither json nor text", c.LogFormat))
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Sprintf("log-level %q is not valid", c.LogLevel))
+	}
+	if len(c.ImmudbDB) == 0 {
+		errs = append(errs, "immudb-db is required")
+	}
+	// the default passwords are well known, whatever the user they go with
+	if !c.Dev {
+		if c.AdminPassword == defaultAdminPassword {
+			errs = append(errs, "the default admin-password is only allowed with --dev")
+		}
+		if !c.Embedded && c.ImmudbPassword == defaultImmudbPassword {
+			errs = append(errs, "the default immudb-password is only allowed with --dev")
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Addr returns the address the HTTP server listens on
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.Host, fmt.Sprint(c.Port))
+}
+
+// GRPCAddr returns the address the gRPC server listens on
+func (c *Config) GRPCAddr() string {
+	return net.JoinHostPort(c.Host, fmt.Sprint(c.GRPCPort))
+}
+
+// Redacted returns a copy of the config with the secrets redacted
+func (c Config) Redacted() Config {
+	if len(c.AdminPassword) > 0 {
+		c.AdminPassword = redacted
+	}
+	if len(c.ImmudbPassword) > 0 {
+		c.ImmudbPassword = redacted
+	}
+	return c
+}
+
+// Print prints the effective config, with the secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	configBytes, err := json.MarshalIndent(c.Redacted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error JSON-marshaling config: %v", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", configBytes)
+	return err
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigDefaultPasswords(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		invalid string
+	}{
+		{args: []string{"--admin-password", "secret", "--immudb-password", "secret"}},
+		{args: []string{"--admin-user", "root", "--immudb-password", "secret"}, invalid: "admin-password"},
+		{args: []string{"--admin-password", "secret", "--immudb-user", "root"}, invalid: "immudb-password"},
+		{args: []string{"--admin-password", "secret", "--embedded"}},
+		{args: []string{"--dev", "--admin-user", "root", "--immudb-user", "root"}},
+	} {
+		_, err := LoadConfig(tc.args)
+		switch {
+		case len(tc.invalid) == 0 && err != nil:
+			t.Errorf("loading config %v: got %v, want no error", tc.args, err)
+		case len(tc.invalid) > 0 && (err == nil || !strings.Contains(err.Error(), "default "+tc.invalid)):
+			t.Errorf("loading config %v: got %v, want the default %s refused", tc.args, err, tc.invalid)
+		}
+	}
+}
+
+func TestConfigCertificationQuorum(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		invalid string
+	}{
+		{args: []string{"--certification-quorum", "2", "--officials-file", "officials.json"}},
+		{args: []string{"--certification-quorum", "-1"}, invalid: "can not be negative"},
+		{args: []string{"--certification-quorum", "2"}, invalid: "requires officials-file"},
+	} {
+		_, err := LoadConfig(append(tc.args, "--dev"))
+		switch {
+		case len(tc.invalid) == 0 && err != nil:
+			t.Errorf("loading config %v: got %v, want no error", tc.args, err)
+		case len(tc.invalid) > 0 && (err == nil || !strings.Contains(err.Error(), tc.invalid)):
+			t.Errorf("loading config %v: got %v, want %q", tc.args, err, tc.invalid)
+		}
+	}
+}
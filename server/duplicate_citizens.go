@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+// DuplicateVoter is one of the voters registered for the same citizen
+type DuplicateVoter struct {
+	VoterID      string    `json:"voter_id"`
+	BallotID     string    `json:"ballot_id"`
+	RegisteredTX uint64    `json:"registered_tx"`
+	Approved     time.Time `json:"approved"`
+	Voted        time.Time `json:"voted"`
+	Vote         uint16    `json:"vote"`
+	Revoked      time.Time `json:"revoked"`
+	// the citizen reference resolves to this voter
+	Referenced bool `json:"referenced"`
+}
+
+// DuplicateCitizen is a citizen for whom more than one voter (and ballot) has
+// been registered
+type DuplicateCitizen struct {
+	CitizenID string           `json:"citizen_id"`
+	Voters    []DuplicateVoter `json:"voters"`
+	// the voter which is kept when resolving: the first one who voted, else the
+	// one the citizen reference resolves to, else the first one registered
+	Keep     string `json:"keep"`
+	Resolved bool   `json:"resolved"`
+	// more than one of the voters has cast a ballot: ballots can not be
+	// changed, so this has to be adjudicated manually
+	NeedsAdjudication bool `json:"needs_adjudication"`
+}
+
+// DuplicateCitizensReport ...
+type DuplicateCitizensReport struct {
+	Duplicates []DuplicateCitizen `json:"duplicates"`
+}
+
+// registrationBallotID returns the ballot registered in the same tx as the voter
+func (s *Server) registrationBallotID(ctx context.Context, voterKey []byte) (string, uint64, error) {
+	// the first value of the voter is the registration
+	historyEntries, err := s.store.History(ctx, voterKey, 0, 1)
+	if err != nil {
+		return "", 0, fmt.Errorf("error loading history for voter key %s: %v", voterKey, err)
+	}
+	if len(historyEntries) == 0 {
+		return "", 0, fmt.Errorf("voter key %s has no history", voterKey)
+	}
+	registeredTX := historyEntries[0].GetTx()
+	txs, err := s.store.TxScan(ctx, registeredTX, 1)
+	if err != nil {
+		return "", 0, fmt.Errorf("error loading tx %d: %v", registeredTX, err)
+	}
+	for _, tx := range txs {
+		for _, txEntry := range tx.GetEntries() {
+			if key := database.TrimPrefix(txEntry.GetKey()); bytes.HasPrefix(key, []byte(ballotPrefix)) {
+				return strings.TrimPrefix(string(key), ballotPrefix), registeredTX, nil
+			}
+		}
+	}
+	return "", registeredTX, nil
+}
+
+func (s *Server) findDuplicateCitizens(ctx context.Context) (*DuplicateCitizensReport, error) {
+	voterEntries, err := scanAll(ctx, s.store, []byte(voterPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("error scanning voters: %v", err)
+	}
+	type citizenVoter struct {
+		key   []byte
+		voter Voter
+	}
+	votersByCitizen := make(map[string][]citizenVoter)
+	for _, voterEntry := range voterEntries {
+		var voter Voter
+		if err := json.Unmarshal(voterEntry.GetValue(), &voter); err != nil {
+			return nil, fmt.Errorf("error JSON-unmarshaling voter with key %s: %v", voterEntry.GetKey(), err)
+		}
+		votersByCitizen[voter.CitizenID] = append(
+			votersByCitizen[voter.CitizenID], citizenVoter{key: voterEntry.GetKey(), voter: voter})
+	}
+
+	report := DuplicateCitizensReport{Duplicates: []DuplicateCitizen{}}
+	for citizenID, citizenVoters := range votersByCitizen {
+		if len(citizenVoters) < 2 {
+			continue
+		}
+		duplicate := DuplicateCitizen{CitizenID: citizenID}
+		citizenEntry, err := s.store.GetLatestEntry(ctx, []byte(citizenPrefix+citizenID))
+		if err != nil {
+			// the citizen ID is PII: the error refers to one of its voters instead
+			return nil, fmt.Errorf("error fetching citizen reference of voter %s: %v",
+				strings.TrimPrefix(string(citizenVoters[0].key), voterPrefix), err)
+		}
+		var nbCast int
+		for _, cv := range citizenVoters {
+			ballotID, registeredTX, err := s.registrationBallotID(ctx, cv.key)
+			if err != nil {
+				return nil, err
+			}
+			duplicateVoter := DuplicateVoter{
+				VoterID:      strings.TrimPrefix(string(cv.key), voterPrefix),
+				BallotID:     ballotID,
+				RegisteredTX: registeredTX,
+				Approved:     cv.voter.RegistrationApproved,
+				Voted:        cv.voter.Voted,
+				Revoked:      cv.voter.Revoked,
+				Referenced:   bytes.Equal(citizenEntry.GetKey(), cv.key),
+			}
+			if len(ballotID) > 0 {
+				ballotBytes, err := s.store.GetLatest(ctx, []byte(ballotPrefix+ballotID))
+				if err != nil {
+					return nil, fmt.Errorf("error fetching ballot %s: %v", ballotID, err)
+				}
+				duplicateVoter.Vote = binary.BigEndian.Uint16(ballotBytes)
+			}
+			if duplicateVoter.Vote != 0 {
+				nbCast++
+			}
+			duplicate.Voters = append(duplicate.Voters, duplicateVoter)
+		}
+		sort.Slice(duplicate.Voters, func(i, j int) bool {
+			return duplicate.Voters[i].RegisteredTX < duplicate.Voters[j].RegisteredTX
+		})
+		duplicate.NeedsAdjudication = nbCast > 1
+
+		keep := -1
+		for i, duplicateVoter := range duplicate.Voters {
+			if !duplicateVoter.Voted.IsZero() &&
+				(keep < 0 || duplicateVoter.Voted.Before(duplicate.Voters[keep].Voted)) {
+				keep = i
+			}
+		}
+		for i, duplicateVoter := range duplicate.Voters {
+			if keep < 0 && duplicateVoter.Referenced {
+				keep = i
+			}
+		}
+		if keep < 0 {
+			keep = 0
+		}
+		duplicate.Keep = duplicate.Voters[keep].VoterID
+		duplicate.Resolved = duplicate.Voters[keep].Referenced && duplicate.Voters[keep].Revoked.IsZero()
+		for i, duplicateVoter := range duplicate.Voters {
+			if i != keep && duplicateVoter.Revoked.IsZero() {
+				duplicate.Resolved = false
+			}
+		}
+
+		report.Duplicates = append(report.Duplicates, duplicate)
+	}
+	sort.Slice(report.Duplicates, func(i, j int) bool {
+		return report.Duplicates[i].CitizenID < report.Duplicates[j].CitizenID
+	})
+	return &report, nil
+}
+
+// resolveDuplicateCitizen revokes all voters of the citizen but the kept one and
+// points the citizen reference to it, in a single tx
+func (s *Server) resolveDuplicateCitizen(ctx context.Context, duplicate *DuplicateCitizen) error {
+	citizenKey := []byte(citizenPrefix + duplicate.CitizenID)
+	keptVoterKey := []byte(voterPrefix + duplicate.Keep)
+	lockKeys := []string{string(citizenKey)}
+	for _, duplicateVoter := range duplicate.Voters {
+		lockKeys = append(lockKeys, voterPrefix+duplicateVoter.VoterID)
+	}
+	unlock := s.locks.Lock(lockKeys...)
+	defer unlock()
+
+	var ops []*schema.Op
+	// the revoked voters
+	var revoked []Voter
+	for _, duplicateVoter := range duplicate.Voters {
+		voterKey := []byte(voterPrefix + duplicateVoter.VoterID)
+		voterBytes, err := s.store.GetLatest(ctx, voterKey)
+		if err != nil {
+			return fmt.Errorf("error fetching voter %s: %v", duplicateVoter.VoterID, err)
+		}
+		var voter Voter
+		if err := json.Unmarshal(voterBytes, &voter); err != nil {
+			return fmt.Errorf("error JSON-unmarshaling voter %s: %v", duplicateVoter.VoterID, err)
+		}
+		if duplicateVoter.VoterID == duplicate.Keep {
+			if !voter.Revoked.IsZero() {
+				return fmt.Errorf("voter %s to keep has been revoked", duplicate.Keep)
+			}
+			continue
+		}
+		if !voter.Revoked.IsZero() {
+			continue
+		}
+		voter.Revoked = time.Now()
+		if voterBytes, err = json.Marshal(&voter); err != nil {
+			return fmt.Errorf("error JSON-marshaling voter %s: %v", duplicateVoter.VoterID, err)
+		}
+		ops = append(ops, &schema.Op{
+			Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: voterKey, Value: voterBytes}}})
+		revoked = append(revoked, voter)
+	}
+
+	citizenEntry, err := s.store.GetLatestEntry(ctx, citizenKey)
+	if err != nil {
+		return fmt.Errorf("error fetching citizen reference of voter %s: %v", duplicate.Keep, err)
+	}
+	if !bytes.Equal(citizenEntry.GetKey(), keptVoterKey) {
+		ops = append(ops, &schema.Op{
+			Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{Key: citizenKey, ReferencedKey: keptVoterKey}}})
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+	if err := s.execAllAndTally(ctx, ops, func(tally *Tally) {
+		for _, voter := range revoked {
+			tally.count(voter.Precinct, func(counts *Counts) {
+				counts.Registered--
+				// the ballot cast by the voter is orphaned
+				if !voter.Voted.IsZero() {
+					counts.Voted--
+					counts.Orphaned++
+				}
+			})
+		}
+	}); err != nil {
+		return fmt.Errorf("error persisting resolution of the citizen of voter %s: %w", duplicate.Keep, err)
+	}
+	return nil
+}
+
+func (s *Server) getDuplicateCitizensHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := s.findDuplicateCitizens(r.Context())
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error looking for duplicate citizens")
+		return
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, report)
+}
+
+func (s *Server) resolveDuplicateCitizensHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := s.findDuplicateCitizens(r.Context())
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error looking for duplicate citizens")
+		return
+	}
+	for i := range report.Duplicates {
+		if report.Duplicates[i].Resolved {
+			continue
+		}
+		if err := s.resolveDuplicateCitizen(r.Context(), &report.Duplicates[i]); errors.Is(err, errElectionClosed) {
+			writeAPIErrorResponse(r, w, http.StatusForbidden, ErrCodeElectionClosed, err,
+				"election has been closed: the registrations can not be revoked anymore")
+			return
+		} else if err != nil {
+			writeErrorResponse(r, w, http.StatusInternalServerError, err,
+				"error resolving duplicate citizens")
+			return
+		}
+	}
+
+	if report, err = s.findDuplicateCitizens(r.Context()); err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error looking for duplicate citizens")
+		return
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, report)
+}
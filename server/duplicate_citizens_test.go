@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// registerDuplicateVoter registers the citizen once more, as the server did
+// before the registrations of the same citizen were serialized
+func registerDuplicateVoter(t *testing.T, server *Server, citizenID string, precinct string) *RegisterVoterResponse {
+	t.Helper()
+	voterID, _ := uuid()
+	ballotID, _ := uuid()
+	voterKey := []byte(voterPrefix + voterID)
+	voterBytes, _ := json.Marshal(&Voter{
+		RegisterVoterRequest: RegisterVoterRequest{CitizenID: citizenID, Precinct: precinct},
+		RegistrationApproved: time.Now(),
+	})
+	if err := server.execAllAndTally(context.Background(), []*schema.Op{
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: voterKey, Value: voterBytes}}},
+		{Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{Key: []byte(citizenPrefix + citizenID), ReferencedKey: voterKey}}},
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: []byte(ballotPrefix + ballotID), Value: make([]byte, 2)}}},
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: []byte(ballotPrecinctPrefix + ballotID), Value: []byte(precinct)}}},
+	}, func(tally *Tally) {
+		tally.count(precinct, func(counts *Counts) { counts.Registered++ })
+	}); err != nil {
+		t.Fatalf("error registering duplicate of citizen %s: %v", citizenID, err)
+	}
+	return &RegisterVoterResponse{VoterID: voterID, BallotID: ballotID}
+}
+
+func TestResolveDuplicateCitizens(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	ctx := context.Background()
+	vote := func(voter *RegisterVoterResponse, vote uint16) {
+		if err := server.vote(ctx, &VoteRequest{RegisterVoterResponse: *voter, Vote: vote}); err != nil {
+			t.Fatalf("error voting as %s: %v", voter.VoterID, err)
+		}
+	}
+	// both voters of alice vote, none of those of bob
+	vote(registerTestVoter(t, httpServer.URL, "alice", "north-1-a"), NikkiHaley)
+	vote(registerDuplicateVoter(t, server, "alice", "north-1-a"), KamalaHarris)
+	registerTestVoter(t, httpServer.URL, "bob", "south-1-b")
+	registerDuplicateVoter(t, server, "bob", "south-1-b")
+
+	var report DuplicateCitizensReport
+	if status := doJSON(t, http.MethodPost,
+		httpServer.URL+apiV1Prefix+"/admin/elections/"+electionID+"/duplicate-citizens/resolve",
+		true, nil, &report); status != http.StatusOK {
+		t.Fatalf("resolving duplicate citizens: got status %d, want %d", status, http.StatusOK)
+	}
+	if len(report.Duplicates) != 2 || !report.Duplicates[0].NeedsAdjudication ||
+		!report.Duplicates[0].Resolved || !report.Duplicates[1].Resolved {
+		t.Fatalf("got report %+v, want alice, to adjudicate, and bob, both resolved", report)
+	}
+
+	// the revoked voter of alice is neither registered nor voted, but the
+	// ballot cast is still counted, as orphaned
+	want := &Counts{Registered: 2, Voted: 1, Ballots: 2, Orphaned: 1,
+		Results: map[uint16]uint64{NikkiHaley: 1, KamalaHarris: 1}}
+	if !reflect.DeepEqual(&server.tally.Counts, want) {
+		t.Errorf("got tally %+v, want %+v", server.tally.Counts, *want)
+	}
+	scanned, err := server.scanTally(ctx)
+	if err != nil {
+		t.Fatalf("error scanning tally: %v", err)
+	}
+	if !reflect.DeepEqual(scanned, server.tally) {
+		t.Errorf("got scanned tally %+v, want %+v", scanned.Counts, server.tally.Counts)
+	}
+
+	// the tally deltas of all txs add up to the same counts
+	txs, err := server.store.TxScan(ctx, 1, 1000)
+	if err != nil {
+		t.Fatalf("error scanning txs: %v", err)
+	}
+	var registered, voted int64
+	var ballots, orphaned uint64
+	for _, tx := range txs {
+		delta, err := server.txTally(ctx, tx)
+		if err != nil {
+			t.Fatalf("error computing tally of tx %d: %v", tx.GetMetadata().GetId(), err)
+		}
+		registered, voted = registered+delta.Registered, voted+delta.Voted
+		ballots, orphaned = ballots+delta.Ballots, orphaned+delta.Orphaned
+	}
+	if registered != 2 || voted != 1 || ballots != 2 || orphaned != 1 {
+		t.Errorf("got tally deltas adding up to %d registered, %d voted, %d ballots and %d orphaned, want 2, 1, 2 and 1",
+			registered, voted, ballots, orphaned)
+	}
+
+	// the ballot of the revoked voter has not been changed
+	var cast int
+	for _, duplicateVoter := range report.Duplicates[0].Voters {
+		ballotBytes, err := server.store.GetLatest(ctx, []byte(ballotPrefix+duplicateVoter.BallotID))
+		if err != nil {
+			t.Fatalf("error fetching ballot %s: %v", duplicateVoter.BallotID, err)
+		}
+		if binary.BigEndian.Uint16(ballotBytes) != 0 {
+			cast++
+		}
+	}
+	if cast != 2 {
+		t.Errorf("got %d ballots of alice cast, want 2", cast)
+	}
+}
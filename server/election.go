@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	electionKey = "immuvoting:election"
+	// the server runs a single election: the /api/v1 routes address it by this
+	// ID, so that they can address more elections in the future
+	electionID = "default"
+)
+
+// Candidate ...
+type Candidate struct {
+	ID   uint16 `json:"id"`
+	Name string `json:"name"`
+}
+
+// levels of the geographic units, from the top
+const (
+	unitLevelRegion   = "region"
+	unitLevelDistrict = "district"
+	unitLevelPrecinct = "precinct"
+)
+
+// Unit is a geographic unit of the election: a region, made of districts, made
+// of precincts; the voters register in a precinct
+type Unit struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Units []Unit `json:"units,omitempty"`
+}
+
+// Election is the definition of the election, persisted in immudb so that it
+// is covered by the same proofs as the voters and the ballots
+type Election struct {
+	Name       string      `json:"name"`
+	Candidates []Candidate `json:"candidates"`
+	// the regions, with their districts and their precincts
+	Regions []Unit `json:"regions"`
+}
+
+var election = Election{
+	Name: "immuvoting demo election",
+	Candidates: []Candidate{
+		{ID: NikkiHaley, Name: "Nikki Haley"},
+		{ID: KamalaHarris, Name: "Kamala Harris"},
+	},
+	Regions: []Unit{
+		{ID: "north", Name: "North", Units: []Unit{
+			{ID: "north-1", Name: "North 1", Units: []Unit{
+				{ID: "north-1-a", Name: "North 1A"},
+				{ID: "north-1-b", Name: "North 1B"},
+			}},
+			{ID: "north-2", Name: "North 2", Units: []Unit{
+				{ID: "north-2-a", Name: "North 2A"},
+			}},
+		}},
+		{ID: "south", Name: "South", Units: []Unit{
+			{ID: "south-1", Name: "South 1", Units: []Unit{
+				{ID: "south-1-a", Name: "South 1A"},
+				{ID: "south-1-b", Name: "South 1B"},
+			}},
+		}},
+	},
+}
+
+// unitLevels are the levels of the units, by depth
+var unitLevels = []string{unitLevelRegion, unitLevelDistrict, unitLevelPrecinct}
+
+// validate checks that the units are exactly three levels deep and that their
+// IDs are unique across all levels, so that a unit is identified by its ID
+func (e *Election) validate() error {
+	ids := map[string]bool{}
+	var validateUnits func(units []Unit, depth int) error
+	validateUnits = func(units []Unit, depth int) error {
+		for _, unit := range units {
+			if len(unit.ID) == 0 || ids[unit.ID] {
+				return fmt.Errorf("%s ID %q is empty or not unique", unitLevels[depth], unit.ID)
+			}
+			ids[unit.ID] = true
+			if depth == len(unitLevels)-1 {
+				if len(unit.Units) > 0 {
+					return fmt.Errorf("precinct %s has units", unit.ID)
+				}
+				continue
+			}
+			if len(unit.Units) == 0 {
+				return fmt.Errorf("%s %s has no units", unitLevels[depth], unit.ID)
+			}
+			if err := validateUnits(unit.Units, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return validateUnits(e.Regions, 0)
+}
+
+// hasPrecinct returns true if the precinct is one of the election
+func (e *Election) hasPrecinct(id string) bool {
+	for _, region := range e.Regions {
+		for _, district := range region.Units {
+			for _, precinct := range district.Units {
+				if precinct.ID == id {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// persistElection stores the election definition, unless the same one has
+// already been stored before (e.g. on a previous run); a changed definition is
+// stored as a new value, so its history stays auditable
+func persistElection(ctx context.Context, store Store, e *Election) error {
+	if err := e.validate(); err != nil {
+		return fmt.Errorf("election definition is invalid: %v", err)
+	}
+	electionBytes, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error JSON-marshaling election definition: %v", err)
+	}
+	if persisted, err := store.GetLatest(ctx, []byte(electionKey)); err == nil {
+		if bytes.Equal(persisted, electionBytes) {
+			return nil
+		}
+		logger.Warn("election definition has changed: persisting the new one")
+	} else if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("error fetching election definition: %v", err)
+	}
+	return store.Set(ctx, []byte(electionKey), electionBytes)
+}
+
+// ElectionResponse ...
+type ElectionResponse struct {
+	ID string `json:"id"`
+	Election
+}
+
+// electionScoped middleware: it rejects the requests for elections other than
+// the one run by the server
+func electionScoped(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if id := mux.Vars(r)["election_id"]; id != electionID {
+			writeAPIErrorResponse(r, w, http.StatusNotFound, ErrCodeElectionNotFound, nil,
+				fmt.Sprintf("no such election %s", id))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) getElectionsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(r, w, http.StatusOK, []ElectionResponse{{ID: electionID, Election: election}})
+}
+
+func (s *Server) getElectionHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(r, w, http.StatusOK, &ElectionResponse{ID: electionID, Election: election})
+}
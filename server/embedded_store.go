@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+	immudb_logger "github.com/codenotary/immudb/pkg/logger"
+	"github.com/golang/protobuf/ptypes/empty"
+)
+
+// EmbeddedStore runs the immudb database in-process, without an immudb server:
+// the data, the proofs and the states are exactly those immudb would produce.
+// immudb keeps its index and its Merkle trees on disk, so the store always
+// needs a dir (tests use the in-memory memstore instead).
+type EmbeddedStore struct {
+	db     database.DB
+	dbName string
+}
+
+// NewEmbeddedStore opens the database with the given name in the given dir,
+// creating it if it does not exist yet
+func NewEmbeddedStore(dir string, dbName string, synced bool) (*EmbeddedStore, error) {
+	opts := database.DefaultOption().
+		WithDbRootPath(dir).
+		WithDbName(dbName).
+		WithStoreOptions(store.DefaultOptions().WithSynced(synced))
+	logger := immudb_logger.NewSimpleLoggerWithLevel(
+		"immuvoting-embedded-store", os.Stderr, immudb_logger.LogWarn)
+
+	var db database.DB
+	var err error
+	if _, statErr := os.Stat(filepath.Join(dir, dbName)); os.IsNotExist(statErr) {
+		db, err = database.NewDb(opts, logger)
+	} else {
+		db, err = database.OpenDb(opts, logger)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening embedded database %s in %s: %v", dbName, dir, err)
+	}
+	return &EmbeddedStore{db: db, dbName: dbName}, nil
+}
+
+// Close ...
+func (s *EmbeddedStore) Close() error {
+	return s.db.Close()
+}
+
+func embeddedErr(err error) error {
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return fmt.Errorf("key %w: %v", ErrNotFound, err)
+	}
+	return err
+}
+
+// Ping ...
+func (s *EmbeddedStore) Ping(ctx context.Context) error {
+	health, err := s.db.Health(new(empty.Empty))
+	if err != nil {
+		return err
+	}
+	if !health.GetStatus() {
+		return errors.New("embedded immudb database reports itself unhealthy")
+	}
+	return nil
+}
+
+// Database ...
+func (s *EmbeddedStore) Database() string {
+	return s.dbName
+}
+
+// Get ...
+func (s *EmbeddedStore) Get(ctx context.Context, key []byte, txID uint64) ([]byte, error) {
+	entry, err := s.db.Get(&schema.KeyRequest{Key: key, AtTx: txID})
+	if err != nil {
+		return nil, embeddedErr(err)
+	}
+	return entry.GetValue(), nil
+}
+
+// GetLatest ...
+func (s *EmbeddedStore) GetLatest(ctx context.Context, key []byte) ([]byte, error) {
+	entry, err := s.GetLatestEntry(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return entry.GetValue(), nil
+}
+
+// GetLatestEntry waits for the index to include all committed txs
+func (s *EmbeddedStore) GetLatestEntry(ctx context.Context, key []byte) (*schema.Entry, error) {
+	state, err := s.db.CurrentState()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.db.Get(&schema.KeyRequest{Key: key, SinceTx: state.GetTxId()})
+	if err != nil {
+		return nil, embeddedErr(err)
+	}
+	return entry, nil
+}
+
+// VerifiedGet returns the entry as is: the database runs in-process, so there
+// is no server in between whose answers would have to be verified
+func (s *EmbeddedStore) VerifiedGet(ctx context.Context, key []byte) (*schema.Entry, error) {
+	return s.GetLatestEntry(ctx, key)
+}
+
+// VerifiableGetAt ...
+func (s *EmbeddedStore) VerifiableGetAt(
+	ctx context.Context,
+	key []byte,
+	atTx uint64,
+	proveSinceTx uint64,
+) (*schema.VerifiableEntry, error) {
+	verifiableEntry, err := s.db.VerifiableGet(&schema.VerifiableGetRequest{
+		KeyRequest:   &schema.KeyRequest{Key: key, AtTx: atTx},
+		ProveSinceTx: proveSinceTx,
+	})
+	if err != nil {
+		return nil, embeddedErr(err)
+	}
+	return verifiableEntry, nil
+}
+
+// Set ...
+func (s *EmbeddedStore) Set(ctx context.Context, key []byte, value []byte) error {
+	_, err := s.db.Set(&schema.SetRequest{KVs: []*schema.KeyValue{{Key: key, Value: value}}})
+	return err
+}
+
+// ExecAll ...
+func (s *EmbeddedStore) ExecAll(ctx context.Context, ops *schema.ExecAllRequest) (uint64, error) {
+	txMeta, err := s.db.ExecAll(ops)
+	if err != nil {
+		return 0, err
+	}
+	return txMeta.GetId(), nil
+}
+
+// Scan ...
+func (s *EmbeddedStore) Scan(
+	ctx context.Context,
+	prefix []byte,
+	limit uint64,
+	seekKey []byte,
+	desc bool,
+) ([]*schema.Entry, error) {
+	entries, err := s.db.Scan(&schema.ScanRequest{
+		Prefix:  prefix,
+		Limit:   limit,
+		SeekKey: seekKey,
+		Desc:    desc,
+		NoWait:  true,
+		SinceTx: math.MaxUint64,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries.GetEntries(), nil
+}
+
+// History ...
+func (s *EmbeddedStore) History(ctx context.Context, key []byte, offset uint64, limit uint64) ([]*schema.Entry, error) {
+	entries, err := s.db.History(&schema.HistoryRequest{Key: key, Offset: offset, Limit: int32(limit)})
+	if err != nil {
+		return nil, embeddedErr(err)
+	}
+	return entries.GetEntries(), nil
+}
+
+// CurrentState ...
+func (s *EmbeddedStore) CurrentState(ctx context.Context) (*schema.ImmutableState, error) {
+	return s.db.CurrentState()
+}
+
+// VerifiableTXByID ...
+func (s *EmbeddedStore) VerifiableTXByID(ctx context.Context, serverTX uint64, localTX uint64) (*schema.VerifiableTx, error) {
+	return s.db.VerifiableTxByID(&schema.VerifiableTxRequest{Tx: serverTX, ProveSinceTx: localTX})
+}
+
+// TxScan ...
+func (s *EmbeddedStore) TxScan(ctx context.Context, initialTX uint64, limit uint32) ([]*schema.Tx, error) {
+	txList, err := s.db.TxScan(&schema.TxScanRequest{InitialTx: initialTX, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return txList.GetTxs(), nil
+}
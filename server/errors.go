@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Error codes of the JSON error envelope: they are part of the API, so clients
+// can branch on them instead of parsing the messages; never change or reuse them
+const (
+	// generic codes, one per HTTP status (see errorCodeForStatus)
+	ErrCodeBadRequest          = "BAD_REQUEST"
+	ErrCodeUnauthorized        = "UNAUTHORIZED"
+	ErrCodeForbidden           = "FORBIDDEN"
+	ErrCodeNotFound            = "NOT_FOUND"
+	ErrCodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	ErrCodeConflict            = "CONFLICT"
+	ErrCodeUnprocessableEntity = "UNPROCESSABLE_ENTITY"
+	ErrCodeInternal            = "INTERNAL_ERROR"
+	ErrCodeUnavailable         = "UNAVAILABLE"
+
+	// request codes
+	ErrCodeInvalidJSON          = "INVALID_JSON"
+	ErrCodeValidationFailed     = "VALIDATION_FAILED"
+	ErrCodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+
+	// voting codes
+	ErrCodeElectionNotFound        = "ELECTION_NOT_FOUND"
+	ErrCodeUnitNotFound            = "UNIT_NOT_FOUND"
+	ErrCodeTallyNotFound           = "TALLY_NOT_FOUND"
+	ErrCodeAlreadyRegistered       = "ALREADY_REGISTERED"
+	ErrCodeVoterNotFound           = "VOTER_NOT_FOUND"
+	ErrCodeRegistrationNotApproved = "REGISTRATION_NOT_APPROVED"
+	ErrCodeRegistrationRevoked     = "REGISTRATION_REVOKED"
+	ErrCodeAlreadyVoted            = "ALREADY_VOTED"
+	ErrCodeBallotNotFound          = "BALLOT_NOT_FOUND"
+	ErrCodeBallotAlreadyCast       = "BALLOT_ALREADY_CAST"
+	ErrCodeElectionClosed          = "ELECTION_CLOSED"
+
+	// audit codes
+	ErrCodeRLANotCommitted     = "RLA_NOT_COMMITTED"
+	ErrCodeRLASeedNotCommitted = "RLA_SEED_NOT_COMMITTED"
+	ErrCodeRLAAlreadyCommitted = "RLA_ALREADY_COMMITTED"
+	ErrCodeRLAFrameChanged     = "RLA_FRAME_CHANGED"
+	ErrCodeRLAConcluded        = "RLA_CONCLUDED"
+
+	// certification codes
+	ErrCodeElectionAlreadyClosed = "ELECTION_ALREADY_CLOSED"
+	ErrCodeElectionNotClosed     = "ELECTION_NOT_CLOSED"
+	ErrCodeInvalidSignature      = "INVALID_SIGNATURE"
+	ErrCodeAlreadySigned         = "ALREADY_SIGNED"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIError ...
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// the invalid fields of the request, if any (VALIDATION_FAILED)
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError is a validation error of a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationErrors is the error returned by the validate methods of the
+// requests, one entry per invalid field
+type validationErrors []FieldError
+
+func (errs validationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Field+": "+err.Message)
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// add appends the field error
+func (errs *validationErrors) add(field string, msg string) {
+	*errs = append(*errs, FieldError{Field: field, Message: msg})
+}
+
+// err returns nil if there are no field errors (a nil validationErrors in an
+// error interface would not be nil)
+func (errs validationErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// apiError is an error of the business logic, shared by the HTTP and the gRPC
+// APIs, with the HTTP status and the code to return to the client; the cause
+// (if any) is only logged
+type apiError struct {
+	status  int
+	code    string
+	msg     string
+	cause   error
+	details []FieldError
+}
+
+func newAPIError(status int, code string, cause error, msg string) *apiError {
+	return &apiError{status: status, code: code, msg: msg, cause: cause}
+}
+
+// internalError returns the apiError of an unexpected failure
+func internalError(cause error, msg string) *apiError {
+	return newAPIError(http.StatusInternalServerError, ErrCodeInternal, cause, msg)
+}
+
+// invalidRequestError returns the apiError of a failed validation (with the
+// field errors, if err is a validationErrors)
+func invalidRequestError(err error) *apiError {
+	apiErr := newAPIError(http.StatusBadRequest, ErrCodeValidationFailed, nil, "request is invalid: "+err.Error())
+	var fieldErrs validationErrors
+	if errors.As(err, &fieldErrs) {
+		apiErr.details = fieldErrs
+	}
+	return apiErr
+}
+
+func (e *apiError) Error() string {
+	if e.cause != nil {
+		return e.msg + ": " + e.cause.Error()
+	}
+	return e.msg
+}
+
+func (e *apiError) Unwrap() error {
+	return e.cause
+}
+
+// errorCodeForStatus returns the generic error code of the HTTP status
+func errorCodeForStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusUnprocessableEntity:
+		return ErrCodeUnprocessableEntity
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	}
+	if statusCode >= http.StatusInternalServerError {
+		return ErrCodeInternal
+	}
+	return ErrCodeBadRequest
+}
+
+// writeValidationErrorResponse writes the field errors of a failed validation
+// (or just the message, if err is not a validationErrors)
+func writeValidationErrorResponse(r *http.Request, w http.ResponseWriter, err error) {
+	writeAPIError(r, w, invalidRequestError(err))
+}
+
+// writeAPIError writes the error returned by the business logic: an apiError,
+// else an internal error
+func writeAPIError(r *http.Request, w http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = internalError(err, "internal error")
+	}
+	writeAPIErrorResponse(r, w, apiErr.status, apiErr.code, apiErr.cause, apiErr.msg, apiErr.details...)
+}
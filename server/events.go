@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// how often a comment is sent on an idle stream, so that proxies do not
+	// close it
+	eventsKeepAliveInterval = 15 * time.Second
+	// how long the clients wait before reconnecting
+	eventsRetry = 3 * time.Second
+
+	eventCheckpoint = "checkpoint"
+	eventTally      = "tally"
+	eventElection   = "election"
+
+	// types of the election events
+	electionEventDefined               = "election_defined"
+	electionEventRLACommitted          = "rla_committed"
+	electionEventRLASeedCommitted      = "rla_seed_committed"
+	electionEventRLAInterpretationDone = "rla_interpretation_recorded"
+	electionEventClosed                = "election_closed"
+	electionEventResultsSigned         = "results_signed"
+)
+
+// TallyEvent is the change of the stats (see GetStatsResponse) made by a tx:
+// the registrations (minus the revocations), the voters who voted (minus the
+// revoked ones who had voted), the ballots cast, by vote, and the ballots
+// orphaned by the revocations
+type TallyEvent struct {
+	TXID       uint64            `json:"tx_id"`
+	Registered int64             `json:"registered"`
+	Voted      int64             `json:"voted"`
+	Ballots    uint64            `json:"ballots"`
+	Orphaned   uint64            `json:"orphaned"`
+	Results    map[uint16]uint64 `json:"results"`
+}
+
+func (e *TallyEvent) empty() bool {
+	return e.Registered == 0 && e.Voted == 0 && e.Ballots == 0 && e.Orphaned == 0
+}
+
+// ElectionEvent is a change of the lifecycle of the election made by a tx
+type ElectionEvent struct {
+	TXID uint64 `json:"tx_id"`
+	Type string `json:"type"`
+}
+
+// event is a Server-Sent Event; the checkpoint event is the last one of its
+// tx and carries its ID, so that a client resuming from it has received all
+// the events of the tx
+type event struct {
+	id   uint64
+	name string
+	data interface{}
+}
+
+// txTally returns the change of the stats made by the tx
+func (s *Server) txTally(ctx context.Context, tx *schema.Tx) (*TallyEvent, error) {
+	txID := tx.GetMetadata().GetId()
+	tally := TallyEvent{TXID: txID, Results: map[uint16]uint64{}}
+	for _, txEntry := range tx.GetEntries() {
+		key := database.TrimPrefix(txEntry.GetKey())
+		switch keyType(key) {
+		case "voter":
+			value, err := s.store.Get(ctx, key, txID)
+			if err != nil {
+				return nil, fmt.Errorf("error fetching voter at tx %d: %v", txID, err)
+			}
+			var voter Voter
+			if err := json.Unmarshal(value, &voter); err != nil {
+				contextLogger(ctx).WithError(err).WithField("key", string(key)).
+					Error("error JSON-unmarshaling voter")
+				continue
+			}
+			// a revoked voter can not vote, and a voter can not vote before being
+			// registered: each write of a voter is one of these transitions
+			switch {
+			case !voter.Revoked.IsZero():
+				tally.Registered--
+				if !voter.Voted.IsZero() {
+					tally.Voted--
+					tally.Orphaned++
+				}
+			case !voter.Voted.IsZero():
+				tally.Voted++
+			default:
+				tally.Registered++
+			}
+		case "ballot":
+			value, err := s.store.Get(ctx, key, txID)
+			if err != nil {
+				return nil, fmt.Errorf("error fetching ballot at tx %d: %v", txID, err)
+			}
+			switch vote := binary.BigEndian.Uint16(value); vote {
+			case KamalaHarris, NikkiHaley:
+				tally.Results[vote]++
+				tally.Ballots++
+			case 0:
+				// nothing to do: the ballot has just been issued
+			default:
+				contextLogger(ctx).WithFields(logrus.Fields{
+					"key":  string(key),
+					"vote": vote,
+				}).Error("ballot has invalid vote")
+			}
+		}
+	}
+	return &tally, nil
+}
+
+// txEvents returns the events of the tx: the tally delta and the election
+// events (if any), then the checkpoint
+func (s *Server) txEvents(ctx context.Context, tx *schema.Tx) ([]event, error) {
+	txID := tx.GetMetadata().GetId()
+	tally, err := s.txTally(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	var events []event
+	if !tally.empty() {
+		events = append(events, event{name: eventTally, data: tally})
+	}
+	for _, txEntry := range tx.GetEntries() {
+		key := database.TrimPrefix(txEntry.GetKey())
+		switch keyType(key) {
+		case "election":
+			events = append(events, event{name: eventElection,
+				data: &ElectionEvent{TXID: txID, Type: electionEventDefined}})
+		case "rla":
+			eventType := electionEventRLACommitted
+			switch {
+			case bytes.Equal(key, []byte(rlaSeedKey)):
+				eventType = electionEventRLASeedCommitted
+			case bytes.HasPrefix(key, []byte(rlaInterpretationPrefix)):
+				eventType = electionEventRLAInterpretationDone
+			}
+			events = append(events, event{name: eventElection,
+				data: &ElectionEvent{TXID: txID, Type: eventType}})
+		case "certification":
+			eventType := electionEventClosed
+			if bytes.HasPrefix(key, []byte(certificationSignaturePrefix)) {
+				eventType = electionEventResultsSigned
+			}
+			events = append(events, event{name: eventElection,
+				data: &ElectionEvent{TXID: txID, Type: eventType}})
+		}
+	}
+	bbTX := bulletinBoardTX(tx)
+	return append(events, event{
+		id:   txID,
+		name: eventCheckpoint,
+		data: &Checkpoint{TXID: txID, TXHash: bbTX.Alh},
+	}), nil
+}
+
+// writeEvent writes the event in the text/event-stream format
+func writeEvent(w http.ResponseWriter, e event) error {
+	data, err := json.Marshal(e.data)
+	if err != nil {
+		return fmt.Errorf("error JSON-marshaling %s event: %v", e.name, err)
+	}
+	if e.id > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", e.id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, data)
+	return err
+}
+
+// getEventsHandler streams the events of the txs committed from now on (or
+// after the since_tx query param, or the Last-Event-ID header sent by the
+// reconnecting EventSource clients) as Server-Sent Events
+func (s *Server) getEventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorResponse(r, w, http.StatusInternalServerError, nil, "streaming is not supported")
+		return
+	}
+
+	state, err := s.store.CurrentState(r.Context())
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err, "error fetching current state")
+		return
+	}
+	latestTX := state.GetTxId()
+	sinceTXStr := r.Header.Get("Last-Event-ID")
+	if len(sinceTXStr) == 0 {
+		sinceTXStr = r.URL.Query().Get("since_tx")
+	}
+	if len(sinceTXStr) > 0 {
+		sinceTX, err := strconv.ParseUint(sinceTXStr, 10, 64)
+		if err != nil {
+			writeErrorResponse(r, w, http.StatusBadRequest, err,
+				"since_tx query param (or Last-Event-ID header) is not an unsigned int")
+			return
+		}
+		if sinceTX < latestTX {
+			latestTX = sinceTX
+		}
+	}
+
+	// subscribe before catching up, so that no tx is missed in between
+	checkpoints, unsubscribe := s.checkpoints.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// e.g. nginx buffers the responses by default
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	// sendUpTo sends the events of the txs after the latest one sent, up to
+	// (and including) the given one
+	sendUpTo := func(txID uint64) error {
+		for latestTX < txID {
+			txs, err := s.store.TxScan(r.Context(), latestTX+1, bulletinBoardDefaultLimit)
+			if err != nil {
+				return fmt.Errorf("error scanning txs after tx %d: %v", latestTX, err)
+			}
+			if len(txs) == 0 {
+				return nil
+			}
+			for _, tx := range txs {
+				events, err := s.txEvents(r.Context(), tx)
+				if err != nil {
+					return err
+				}
+				for _, e := range events {
+					if err := writeEvent(w, e); err != nil {
+						return err
+					}
+				}
+				latestTX = tx.GetMetadata().GetId()
+			}
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	logger := requestLogger(r)
+	if err := sendUpTo(state.GetTxId()); err != nil {
+		logger.WithError(err).Warn("error sending events")
+		return
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.drained:
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case checkpoint := <-checkpoints:
+			if err := sendUpTo(checkpoint.TXID); err != nil {
+				logger.WithError(err).Warn("error sending events")
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is a Server-Sent Event, as received by a client
+type sseEvent struct {
+	id   string
+	name string
+	data string
+}
+
+// String describes the event, e.g. "tally at tx 3: 0 registered, 1 voted, 1
+// ballots, 0 orphaned"
+func (e sseEvent) String() string {
+	switch e.name {
+	case eventTally:
+		var tally TallyEvent
+		json.Unmarshal([]byte(e.data), &tally)
+		return fmt.Sprintf("tally at tx %d: %d registered, %d voted, %d ballots, %d orphaned",
+			tally.TXID, tally.Registered, tally.Voted, tally.Ballots, tally.Orphaned)
+	case eventElection:
+		var election ElectionEvent
+		json.Unmarshal([]byte(e.data), &election)
+		return fmt.Sprintf("election at tx %d: %s", election.TXID, election.Type)
+	case eventCheckpoint:
+		var checkpoint Checkpoint
+		json.Unmarshal([]byte(e.data), &checkpoint)
+		return fmt.Sprintf("checkpoint %s: tx %d", e.id, checkpoint.TXID)
+	}
+	return e.name + ": " + e.data
+}
+
+// streamEvents opens the event stream of the election with the given headers
+// and returns the channel of its events, closed at the end of the stream
+func streamEvents(t *testing.T, url string, header http.Header) <-chan sseEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("error creating request GET %s: %v", url, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error executing request GET %s: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK ||
+		resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET %s: got status %d, content type %s, want %d, text/event-stream",
+			url, resp.StatusCode, resp.Header.Get("Content-Type"), http.StatusOK)
+	}
+	t.Cleanup(func() {
+		cancel()
+		resp.Body.Close()
+	})
+
+	events := make(chan sseEvent, 100)
+	go func() {
+		defer close(events)
+		var e sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case len(line) == 0:
+				if len(e.name) > 0 {
+					events <- e
+				}
+				e = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+// receiveEvents receives the events of the stream up to (and including) the
+// checkpoint of the tx
+func receiveEvents(t *testing.T, events <-chan sseEvent, txID uint64) []string {
+	t.Helper()
+	var received []string
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("stream ended before the checkpoint of tx %d, after %q", txID, received)
+			}
+			received = append(received, e.String())
+			if e.name == eventCheckpoint && e.id == strconv.FormatUint(txID, 10) {
+				return received
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for the checkpoint of tx %d, after %q", txID, received)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	ctx := context.Background()
+	eventsURL := httpServer.URL + apiV1Prefix + "/elections/" + electionID + "/events"
+	currentTX := func() uint64 {
+		state, err := server.store.CurrentState(ctx)
+		if err != nil {
+			t.Fatalf("error fetching current state: %v", err)
+		}
+		return state.GetTxId()
+	}
+
+	// the stream starts with the txs after since_tx, then goes on live
+	live := streamEvents(t, eventsURL+"?since_tx=0", nil)
+	vote := func(voter *RegisterVoterResponse, vote uint16) {
+		if status := doJSON(t, http.MethodPost, httpServer.URL+apiV1Prefix+"/elections/"+electionID+"/votes",
+			false, &VoteRequest{RegisterVoterResponse: *voter, Vote: vote}, nil); status != http.StatusNoContent {
+			t.Fatalf("voting as %s: got status %d, want %d", voter.VoterID, status, http.StatusNoContent)
+		}
+	}
+	vote(registerTestVoter(t, httpServer.URL, "alice", "north-1-a"), NikkiHaley)
+	votedTX := currentTX()
+	// the duplicate voter of alice who voted is revoked
+	vote(registerDuplicateVoter(t, server, "alice", "north-1-a"), KamalaHarris)
+	if status := doJSON(t, http.MethodPost,
+		httpServer.URL+apiV1Prefix+"/admin/elections/"+electionID+"/duplicate-citizens/resolve",
+		true, nil, nil); status != http.StatusOK {
+		t.Fatalf("resolving duplicate citizens: got status %d, want %d", status, http.StatusOK)
+	}
+	if status := doJSON(t, http.MethodPost,
+		httpServer.URL+apiV1Prefix+"/admin/elections/"+electionID+"/certification",
+		true, nil, nil); status != http.StatusOK {
+		t.Fatalf("closing election: got status %d, want %d", status, http.StatusOK)
+	}
+	closedTX := currentTX()
+
+	received := receiveEvents(t, live, closedTX)
+	want := []string{
+		"election at tx 1: election_defined",
+		"checkpoint 1: tx 1",
+		// the tally persisted by the server on startup
+		"checkpoint 2: tx 2",
+		"tally at tx 3: 1 registered, 0 voted, 0 ballots, 0 orphaned",
+		"checkpoint 3: tx 3",
+		"tally at tx 4: 0 registered, 1 voted, 1 ballots, 0 orphaned",
+		"checkpoint 4: tx 4",
+		"tally at tx 5: 1 registered, 0 voted, 0 ballots, 0 orphaned",
+		"checkpoint 5: tx 5",
+		"tally at tx 6: 0 registered, 1 voted, 1 ballots, 0 orphaned",
+		"checkpoint 6: tx 6",
+		"tally at tx 7: -1 registered, -1 voted, 0 ballots, 1 orphaned",
+		"checkpoint 7: tx 7",
+		"election at tx 8: election_closed",
+		"checkpoint 8: tx 8",
+	}
+	if strings.Join(received, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got events\n%s\nwant\n%s", strings.Join(received, "\n"), strings.Join(want, "\n"))
+	}
+	if votedTX != 4 || closedTX != 8 {
+		t.Fatalf("got vote at tx %d and closing at tx %d, want 4 and 8", votedTX, closedTX)
+	}
+
+	// a reconnecting client resumes after the last checkpoint received, and
+	// its Last-Event-ID header takes precedence over the since_tx query param
+	resumed := streamEvents(t, eventsURL+"?since_tx=0",
+		http.Header{"Last-Event-ID": []string{strconv.FormatUint(votedTX, 10)}})
+	if received := receiveEvents(t, resumed, closedTX); strings.Join(received, "\n") != strings.Join(want[7:], "\n") {
+		t.Errorf("resuming after tx %d: got events\n%s\nwant\n%s",
+			votedTX, strings.Join(received, "\n"), strings.Join(want[7:], "\n"))
+	}
+	resumed = streamEvents(t, eventsURL+"?since_tx=7", nil)
+	if received := receiveEvents(t, resumed, closedTX); strings.Join(received, "\n") != strings.Join(want[13:], "\n") {
+		t.Errorf("resuming after tx 7: got events\n%s\nwant\n%s",
+			strings.Join(received, "\n"), strings.Join(want[13:], "\n"))
+	}
+
+	// an invalid resume point is rejected
+	if status := doJSON(t, http.MethodGet, eventsURL+"?since_tx=latest", false, nil, nil); status != http.StatusBadRequest {
+		t.Errorf("invalid since_tx: got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,130 @@
+// Generates a local CA and the certs signed by it, to try out (and test) TLS
+// for the HTTP API and mutual TLS to immudb: a server cert, for the HTTP API
+// and for immudb, and a client cert, for immuvoting to authenticate to immudb.
+// Not meant for production: use certs issued by a proper CA there.
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	dir := flag.String("dir", "./certs", "dir to write the certs and keys to")
+	hosts := flag.String("hosts", "localhost,127.0.0.1", "comma-separated host names and IPs of the server cert")
+	validFor := flag.Duration("valid-for", 365*24*time.Hour, "validity of the certs")
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0700); err != nil {
+		log.Fatalf("error creating dir %s: %v", *dir, err)
+	}
+	notAfter := time.Now().Add(*validFor)
+
+	caKey, caCert, err := newCert(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "immuvoting local CA"},
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}, nil, nil)
+	if err != nil {
+		log.Fatalf("error generating CA cert: %v", err)
+	}
+	write(*dir, "ca", caKey, caCert)
+
+	serverTemplate := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "immuvoting server"},
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range strings.Split(*hosts, ",") {
+		host = strings.TrimSpace(host)
+		if ip := net.ParseIP(host); ip != nil {
+			serverTemplate.IPAddresses = append(serverTemplate.IPAddresses, ip)
+		} else if len(host) > 0 {
+			serverTemplate.DNSNames = append(serverTemplate.DNSNames, host)
+		}
+	}
+	serverKey, serverCert, err := newCert(serverTemplate, caCert, caKey)
+	if err != nil {
+		log.Fatalf("error generating server cert: %v", err)
+	}
+	write(*dir, "server", serverKey, serverCert)
+
+	clientKey, clientCert, err := newCert(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "immuvoting client"},
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, caKey)
+	if err != nil {
+		log.Fatalf("error generating client cert: %v", err)
+	}
+	write(*dir, "client", clientKey, clientCert)
+
+	log.Printf("wrote ca, server and client certs and keys to %s", *dir)
+}
+
+// newCert generates a key and a cert for it, signed by the given CA or
+// self-signed if no CA is given
+func newCert(
+	template *x509.Certificate,
+	caCert *x509.Certificate,
+	caKey *ecdsa.PrivateKey,
+) (*ecdsa.PrivateKey, *x509.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating key: %v", err)
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating serial number: %v", err)
+	}
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now().Add(-time.Hour)
+	if caCert == nil {
+		caCert, caKey = template, key
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating cert: %v", err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing cert: %v", err)
+	}
+	return key, cert, nil
+}
+
+// write writes <name>.pem and <name>-key.pem
+func write(dir string, name string, key *ecdsa.PrivateKey, cert *x509.Certificate) {
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		log.Fatalf("error marshaling %s key: %v", name, err)
+	}
+	certFile := filepath.Join(dir, name+".pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(
+		&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644); err != nil {
+		log.Fatalf("error writing %s: %v", certFile, err)
+	}
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600); err != nil {
+		log.Fatalf("error writing %s: %v", keyFile, err)
+	}
+}
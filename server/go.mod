@@ -5,6 +5,13 @@ go 1.16
 require (
 	github.com/codenotary/immudb v0.9.2-0.20210218165443-053d9a7cb548
 	github.com/golang/protobuf v1.4.3
+	github.com/gorilla/mux v1.8.0
+	github.com/prometheus/client_golang v1.5.1
+	github.com/sirupsen/logrus v1.4.2
+	github.com/spf13/pflag v1.0.3
+	github.com/spf13/viper v1.6.3
 	golang.org/x/crypto v0.0.0-20201208171446-5f87f3452ae9
+	google.golang.org/genproto v0.0.0-20201207150747-9ee31aac76e7
 	google.golang.org/grpc v1.34.0
+	google.golang.org/protobuf v1.25.0
 )
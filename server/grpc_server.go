@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/golang/protobuf/proto"
+	"github.com/padurean/immuvoting/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// domain of the ErrorInfo details of the gRPC errors
+const grpcErrorDomain = "immuvoting"
+
+// GRPCServer returns the gRPC server of the API (see pb/immuvoting.proto), with
+// the given options (e.g. the TLS credentials)
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(grpcUnaryInterceptor),
+		grpc.ChainStreamInterceptor(grpcStreamInterceptor))
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterImmuvotingServer(grpcServer, &immuvotingGRPCServer{s: s})
+	return grpcServer
+}
+
+// immuvotingGRPCServer implements the gRPC API with the business logic of the
+// HTTP API; it returns its errors as they are, the interceptors log them and
+// convert them to gRPC statuses
+type immuvotingGRPCServer struct {
+	pb.UnimplementedImmuvotingServer
+	s *Server
+}
+
+func (g *immuvotingGRPCServer) RegisterVoter(
+	ctx context.Context,
+	req *pb.RegisterVoterRequest,
+) (*pb.RegisterVoterResponse, error) {
+	res, err := g.s.registerVoter(ctx, &RegisterVoterRequest{
+		CitizenID: req.GetCitizenId(),
+		Name:      req.GetName(),
+		Address:   req.GetAddress(),
+		Email:     req.GetEmail(),
+		Precinct:  req.GetPrecinct(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RegisterVoterResponse{VoterId: res.VoterID, BallotId: res.BallotID}, nil
+}
+
+func (g *immuvotingGRPCServer) Vote(ctx context.Context, req *pb.VoteRequest) (*pb.VoteResponse, error) {
+	if req.GetVote() > math.MaxUint16 {
+		return nil, invalidRequestError(validationErrors{{Field: "vote", Message: "invalid vote"}})
+	}
+	if err := g.s.vote(ctx, &VoteRequest{
+		RegisterVoterResponse: RegisterVoterResponse{VoterID: req.GetVoterId(), BallotID: req.GetBallotId()},
+		Vote:                  uint16(req.GetVote()),
+	}); err != nil {
+		return nil, err
+	}
+	return &pb.VoteResponse{}, nil
+}
+
+func (g *immuvotingGRPCServer) GetVoterStatus(
+	ctx context.Context,
+	req *pb.GetVoterStatusRequest,
+) (*pb.VoterStatus, error) {
+	res, err := g.s.voterStatus(ctx, req.GetVoterId())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.VoterStatus{
+		Approved: grpcTimestamp(res.RegistrationApproved),
+		Voted:    grpcTimestamp(res.Voted),
+		Revoked:  grpcTimestamp(res.Revoked),
+	}, nil
+}
+
+func (g *immuvotingGRPCServer) GetBallot(ctx context.Context, req *pb.GetBallotRequest) (*pb.Ballot, error) {
+	res, err := g.s.ballot(ctx, req.GetBallotId())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Ballot{BallotId: res.BallotID, Vote: uint32(res.Vote)}, nil
+}
+
+func (g *immuvotingGRPCServer) GetState(ctx context.Context, _ *pb.GetStateRequest) (*pb.State, error) {
+	state, err := g.s.store.CurrentState(ctx)
+	if err != nil {
+		return nil, internalError(err, "error fetching current state")
+	}
+	return g.pbState(state.GetTxId(), state.GetTxHash(), state.GetSignature())
+}
+
+// pbState returns the state signed by the server, as the HTTP API does (see
+// state), so that the verifiers can check it against the public key of the
+// server; without a signer, the signature is the one of immudb, if any
+func (g *immuvotingGRPCServer) pbState(txID uint64, txHash []byte, signature *schema.Signature) (*pb.State, error) {
+	signedState := schema.ImmutableState{
+		Db:        g.s.store.Database(),
+		TxId:      txID,
+		TxHash:    txHash,
+		Signature: signature,
+	}
+	if err := signState(g.s.stateSigner, &signedState); err != nil {
+		return nil, internalError(err, "error signing state")
+	}
+	return &pb.State{
+		TxId:      signedState.GetTxId(),
+		TxHash:    signedState.GetTxHash(),
+		Db:        signedState.GetDb(),
+		Signature: signedState.GetSignature(),
+	}, nil
+}
+
+func (g *immuvotingGRPCServer) GetVerifiableTx(
+	ctx context.Context,
+	req *pb.GetVerifiableTxRequest,
+) (*schema.VerifiableTx, error) {
+	return g.s.verifiableTX(ctx, req.GetServerTx(), req.GetLocalTx())
+}
+
+func (g *immuvotingGRPCServer) GetStats(ctx context.Context, req *pb.GetStatsRequest) (*pb.Stats, error) {
+	statsReq := GetStatsRequest{Unit: req.GetUnit(), AsOfTX: req.GetAsOfTx()}
+	if req.GetAsOfTime() != nil {
+		statsReq.AsOfTime = req.GetAsOfTime().AsTime()
+	}
+	res, err := g.s.stats(ctx, &statsReq)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Stats{
+		Registered: res.Registered,
+		Voted:      res.Voted,
+		Ballots:    res.Ballots,
+		Results:    pbResults(res.Results),
+		Unit:       res.Unit,
+		Units:      pbUnitStats(res.Units),
+		TxId:       res.TXID,
+		Orphaned:   res.Orphaned,
+	}, nil
+}
+
+func pbResults(results map[uint16]uint64) map[uint32]uint64 {
+	pbResults := make(map[uint32]uint64, len(results))
+	for candidate, votes := range results {
+		pbResults[uint32(candidate)] = votes
+	}
+	return pbResults
+}
+
+func pbUnitStats(units []UnitStats) []*pb.UnitStats {
+	pbUnits := make([]*pb.UnitStats, 0, len(units))
+	for _, unit := range units {
+		pbUnits = append(pbUnits, &pb.UnitStats{
+			Id:         unit.ID,
+			Name:       unit.Name,
+			Level:      unit.Level,
+			Registered: unit.Registered,
+			Voted:      unit.Voted,
+			Ballots:    unit.Ballots,
+			Results:    pbResults(unit.Results),
+			Units:      pbUnitStats(unit.Units),
+			Orphaned:   unit.Orphaned,
+		})
+	}
+	return pbUnits
+}
+
+func (g *immuvotingGRPCServer) WatchCheckpoints(
+	_ *pb.WatchCheckpointsRequest,
+	stream pb.Immuvoting_WatchCheckpointsServer,
+) error {
+	// subscribe before fetching the current state, not to miss any checkpoint
+	checkpoints, unsubscribe := g.s.checkpoints.Subscribe()
+	defer unsubscribe()
+
+	state, err := g.s.store.CurrentState(stream.Context())
+	if err != nil {
+		return internalError(err, "error fetching current state")
+	}
+	latestTXID := state.GetTxId()
+	pbState, err := g.pbState(latestTXID, state.GetTxHash(), state.GetSignature())
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(pbState); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-g.s.drained:
+			return newAPIError(http.StatusServiceUnavailable, ErrCodeUnavailable, nil, "server is shutting down")
+		case checkpoint := <-checkpoints:
+			if checkpoint.TXID <= latestTXID {
+				continue
+			}
+			latestTXID = checkpoint.TXID
+			pbState, err := g.pbState(checkpoint.TXID, checkpoint.TXHash, nil)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(pbState); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcTimestamp returns the timestamp of the time, nil if the time is zero
+func grpcTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// grpcContext assigns the call a correlation ID, the one sent by the client in
+// the x-request-id metadata if valid, and returns it in the same header
+func grpcContext(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	requestID = validOrNewRequestID(requestID)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID)); err != nil {
+		logger.WithError(err).Error("error setting request ID header")
+	}
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+func grpcUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx = grpcContext(ctx)
+	started := time.Now()
+	res, err := handler(ctx, req)
+	return res, grpcObserve(ctx, info.FullMethod, started, err)
+}
+
+func grpcStreamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx := grpcContext(stream.Context())
+	started := time.Now()
+	err := handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+	return grpcObserve(ctx, info.FullMethod, started, err)
+}
+
+// contextServerStream is the server stream with the context of the call
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcObserve logs and measures the call, and converts its error to a gRPC
+// status
+func grpcObserve(ctx context.Context, method string, started time.Time, err error) error {
+	statusErr := grpcError(err)
+	code := status.Code(statusErr)
+	grpcRequests.WithLabelValues(method, code.String()).Inc()
+	grpcRequestDuration.WithLabelValues(method).Observe(time.Since(started).Seconds())
+
+	entry := contextLogger(ctx).WithFields(logrus.Fields{"grpc_method": method, "grpc_code": code.String()})
+	var apiErr *apiError
+	switch {
+	case err == nil:
+		entry.Info(code.String())
+	case errors.As(err, &apiErr):
+		entry = entry.WithField("code", apiErr.code)
+		if apiErr.cause != nil {
+			entry = entry.WithError(apiErr.cause)
+		}
+		if apiErr.status >= http.StatusInternalServerError {
+			entry.Error(apiErr.msg)
+		} else {
+			entry.Warn(apiErr.msg)
+		}
+	default:
+		entry.WithError(err).Error(code.String())
+	}
+	return statusErr
+}
+
+// grpcError converts the error of the business logic to a gRPC status with an
+// ErrorInfo detail whose reason is the error code (and a BadRequest detail with
+// the field errors, if any); other errors (e.g. of the stream) are returned as
+// they are
+func grpcError(err error) error {
+	var apiErr *apiError
+	if err == nil || !errors.As(err, &apiErr) {
+		return err
+	}
+	st := status.New(grpcCode(apiErr.status), apiErr.msg)
+	details := []proto.Message{&errdetails.ErrorInfo{Reason: apiErr.code, Domain: grpcErrorDomain}}
+	if len(apiErr.details) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, fieldErr := range apiErr.details {
+			badRequest.FieldViolations = append(badRequest.FieldViolations,
+				&errdetails.BadRequest_FieldViolation{Field: fieldErr.Field, Description: fieldErr.Message})
+		}
+		details = append(details, badRequest)
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// grpcCode returns the gRPC code of the HTTP status
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/signer"
+	"github.com/padurean/immuvoting/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPCClient serves the gRPC API of the server over an in-memory
+// connection and returns a client of it
+func newTestGRPCClient(t *testing.T, server *Server) pb.ImmuvotingClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := server.GRPCServer()
+	go grpcServer.Serve(listener)
+	conn, err := grpc.DialContext(context.Background(), "bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("error dialing gRPC server: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+	})
+	return pb.NewImmuvotingClient(conn)
+}
+
+// verifyPBState checks that the state is signed with the key, as the verifiers
+// do with the states of the HTTP API
+func verifyPBState(t *testing.T, state *pb.State, key *ecdsa.PrivateKey) {
+	t.Helper()
+	signedState := schema.ImmutableState{
+		Db:        state.GetDb(),
+		TxId:      state.GetTxId(),
+		TxHash:    state.GetTxHash(),
+		Signature: state.GetSignature(),
+	}
+	if state.GetDb() != "defaultdb" || state.GetSignature() == nil {
+		t.Fatalf("got state of db %q with signature %v, want a signed state of defaultdb",
+			state.GetDb(), state.GetSignature())
+	}
+	if ok, err := signedState.CheckSignature(&key.PublicKey); err != nil || !ok {
+		t.Errorf("signature of state at tx %d does not verify against the signing key: %t, %v",
+			state.GetTxId(), ok, err)
+	}
+}
+
+func TestGRPCServer(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating signing key: %v", err)
+	}
+	server.stateSigner = signer.NewSignerFromPKey(rand.Reader, key)
+	client := newTestGRPCClient(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	registered, err := client.RegisterVoter(ctx, &pb.RegisterVoterRequest{
+		CitizenId: "alice",
+		Name:      "Alice",
+		Address:   "1 Main Street",
+		Email:     "alice@example.com",
+		Precinct:  "north-1-a",
+	})
+	if err != nil {
+		t.Fatalf("error registering voter: %v", err)
+	}
+	vote := &pb.VoteRequest{VoterId: registered.GetVoterId(), BallotId: registered.GetBallotId(), Vote: NikkiHaley}
+	if _, err := client.Vote(ctx, vote); err != nil {
+		t.Fatalf("error voting: %v", err)
+	}
+	// the errors of the business logic carry the error code of the HTTP API
+	_, err = client.Vote(ctx, vote)
+	if st := status.Convert(err); st.Code() != codes.AlreadyExists || len(st.Details()) == 0 ||
+		st.Details()[0].(*errdetails.ErrorInfo).GetReason() != ErrCodeAlreadyVoted {
+		t.Errorf("voting twice: got %v, want %s with reason %s", err, codes.AlreadyExists, ErrCodeAlreadyVoted)
+	}
+
+	// a duplicate of alice votes too, then is revoked: their ballot is orphaned
+	duplicate := registerDuplicateVoter(t, server, "alice", "north-1-a")
+	if err := server.vote(ctx, &VoteRequest{RegisterVoterResponse: *duplicate, Vote: KamalaHarris}); err != nil {
+		t.Fatalf("error voting as the duplicate: %v", err)
+	}
+	if status := doJSON(t, http.MethodPost,
+		httpServer.URL+apiV1Prefix+"/admin/elections/"+electionID+"/duplicate-citizens/resolve",
+		true, nil, nil); status != http.StatusOK {
+		t.Fatalf("resolving duplicate citizens: got status %d, want %d", status, http.StatusOK)
+	}
+	stats, err := client.GetStats(ctx, &pb.GetStatsRequest{Unit: "north"})
+	if err != nil {
+		t.Fatalf("error fetching stats: %v", err)
+	}
+	httpStats, err := server.stats(ctx, &GetStatsRequest{Unit: "north"})
+	if err != nil {
+		t.Fatalf("error fetching HTTP stats: %v", err)
+	}
+	if stats.GetRegistered() != 1 || stats.GetVoted() != 1 || stats.GetBallots() != 2 || stats.GetOrphaned() != 1 ||
+		stats.GetOrphaned() != httpStats.Orphaned || len(stats.GetUnits()) != 2 ||
+		stats.GetUnits()[0].GetOrphaned() != 1 {
+		t.Errorf("got stats %v, want 1 registered, 1 voted, 2 ballots and 1 orphaned, in north and north-1", stats)
+	}
+
+	state, err := client.GetState(ctx, &pb.GetStateRequest{})
+	if err != nil {
+		t.Fatalf("error fetching state: %v", err)
+	}
+	verifyPBState(t, state, key)
+
+	// the checkpoints are signed too
+	stream, err := client.WatchCheckpoints(ctx, &pb.WatchCheckpointsRequest{})
+	if err != nil {
+		t.Fatalf("error watching checkpoints: %v", err)
+	}
+	current, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("error receiving current state: %v", err)
+	}
+	verifyPBState(t, current, key)
+	registerTestVoter(t, httpServer.URL, "bob", "south-1-b")
+	checkpoint, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("error receiving checkpoint: %v", err)
+	}
+	if checkpoint.GetTxId() <= current.GetTxId() {
+		t.Errorf("got checkpoint at tx %d, want one after tx %d", checkpoint.GetTxId(), current.GetTxId())
+	}
+	verifyPBState(t, checkpoint, key)
+}
@@ -1,22 +1,20 @@
 package main
 
 import (
-	"crypto/rand"
+	"context"
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
-	"math/big"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/api/schema"
-	"github.com/codenotary/immudb/pkg/database"
 	"google.golang.org/grpc/status"
 )
 
@@ -26,6 +24,10 @@ const (
 	voterPrefix   = "immuvoting:voter:"
 	citizenPrefix = "immuvoting:citizen:"
 	ballotPrefix  = "immuvoting:ballot:"
+	// the precinct each ballot has been issued for, set along with the ballot,
+	// so that the ballots can be counted by precinct without being linked to
+	// their voters
+	ballotPrecinctPrefix = "immuvoting:ballot-precinct:"
 )
 
 // builds the middleware chain recursively
@@ -41,7 +43,8 @@ func cors(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Idempotency-Key, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed, X-Request-ID")
 		if r.Method == "OPTIONS" {
 			return
 		}
@@ -50,54 +53,57 @@ func cors(handler http.HandlerFunc) http.HandlerFunc {
 }
 
 // basicAuth middleware
-func basicAuth(handler http.HandlerFunc) http.HandlerFunc {
+func (s *Server) basicAuth(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user, pass, ok := r.BasicAuth()
 		if !ok ||
-			subtle.ConstantTimeCompare([]byte(user), []byte(adminUser)) != 1 ||
-			subtle.ConstantTimeCompare([]byte(pass), []byte(adminPass)) != 1 {
+			subtle.ConstantTimeCompare([]byte(user), []byte(s.adminUser)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(s.adminPassword)) != 1 {
 			w.Header().Set(
 				"WWW-Authenticate",
 				`Basic realm="Please enter your username and password"`)
-			w.WriteHeader(401)
-			w.Write([]byte("You are Unauthorized to access the application.\n"))
+			writeErrorResponse(r, w, http.StatusUnauthorized, nil,
+				"invalid or missing admin credentials")
 			return
 		}
 		handler(w, r)
 	}
 }
 
-func corsAndBasicAuth(handler http.HandlerFunc) http.HandlerFunc {
-	return chain(handler, cors, basicAuth)
+func (s *Server) corsAndBasicAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return chain(handler, cors, s.basicAuth)
 }
 
 // RegisterVoterRequest ...
 type RegisterVoterRequest struct {
-	CitizenID string `json:"citizen_id"`
-	Name      string `json:"name"`
-	Address   string `json:"address"`
-	Email     string `json:"email"`
+	CitizenID string `json:"citizen_id" pii:"true"`
+	Name      string `json:"name" pii:"true"`
+	Address   string `json:"address" pii:"true"`
+	Email     string `json:"email" pii:"true"`
+	// ID of the precinct of the voter, one of the election
+	Precinct string `json:"precinct"`
 }
 
 func (req *RegisterVoterRequest) validate() error {
-	var errs []string
+	var errs validationErrors
 	if len(req.CitizenID) == 0 {
-		errs = append(errs, "citizen ID is missing")
+		errs.add("citizen_id", "citizen ID is missing")
 	}
 	if len(req.Name) == 0 {
-		errs = append(errs, "name is missing")
+		errs.add("name", "name is missing")
 	}
 	if len(req.Address) == 0 {
-		errs = append(errs, "address is missing")
+		errs.add("address", "address is missing")
 	}
 	if !isEmailValid(req.Email) {
-		errs = append(errs, "email is invalid")
+		errs.add("email", "email is invalid")
 	}
-
-	if len(errs) > 0 {
-		return errors.New(strings.Join(errs, ", "))
+	if len(req.Precinct) == 0 {
+		errs.add("precinct", "precinct is missing")
+	} else if !election.hasPrecinct(req.Precinct) {
+		errs.add("precinct", "no such precinct")
 	}
-	return nil
+	return errs.err()
 }
 
 // Voter ...
@@ -105,6 +111,8 @@ type Voter struct {
 	RegisterVoterRequest
 	RegistrationApproved time.Time `json:"registration_approved"`
 	Voted                time.Time `json:"voted"`
+	// set when the registration is revoked, e.g. as a duplicate of the citizen
+	Revoked time.Time `json:"revoked"`
 }
 
 // RegisterVoterResponse ...
@@ -113,74 +121,83 @@ type RegisterVoterResponse struct {
 	BallotID string `json:"ballot_id"`
 }
 
-func registerVoterHandler(w http.ResponseWriter, r *http.Request) {
-	if !isHTTPMethodValid(r, w, http.MethodPost) {
-		return
-	}
-
+func (s *Server) registerVoterHandler(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	var payload RegisterVoterRequest
 	err := decoder.Decode(&payload)
 	if err != nil {
-		writeErrorResponse(r, w, http.StatusBadRequest, nil,
+		writeAPIErrorResponse(r, w, http.StatusBadRequest, ErrCodeInvalidJSON, nil,
 			fmt.Sprintf("error parsing request body: %v", err))
 		return
 	}
-	if err := payload.validate(); err != nil {
-		writeErrorResponse(r, w, http.StatusBadRequest, nil, err.Error())
+
+	resPayload, err := s.registerVoter(r.Context(), &payload)
+	if err != nil {
+		writeAPIError(r, w, err)
 		return
 	}
+	writeJSONResponse(r, w, http.StatusOK, resPayload)
+}
 
-	citizenKey := []byte(citizenPrefix + payload.CitizenID)
-	if _, err := immudbClient.Get(citizenKey, 0); err == nil {
-		writeErrorResponse(r, w, http.StatusTooManyRequests, err, "already registered")
-		return
+// registerVoter registers the voter and issues their ballot
+func (s *Server) registerVoter(ctx context.Context, req *RegisterVoterRequest) (*RegisterVoterResponse, error) {
+	if err := req.validate(); err != nil {
+		return nil, invalidRequestError(err)
+	}
+
+	citizenKey := []byte(citizenPrefix + req.CitizenID)
+
+	// the check below and the write must be atomic: concurrent registrations of
+	// the same citizen wait for each other and then read the latest reference
+	unlock := s.locks.Lock(string(citizenKey))
+	defer unlock()
+
+	if _, err := s.store.GetLatest(ctx, citizenKey); err == nil {
+		return nil, newAPIError(http.StatusConflict, ErrCodeAlreadyRegistered, nil,
+			"citizen is already registered")
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, internalError(err, "error checking existing registration")
 	}
 
 	voterID, err := uuid()
 	if err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error generating voter ID")
-		return
+		return nil, internalError(err, "error generating voter ID")
 	}
 	voterKey := []byte(voterPrefix + voterID)
 	voterBytes, err := json.Marshal(&Voter{
-		RegisterVoterRequest: payload,
+		RegisterVoterRequest: *req,
 		RegistrationApproved: time.Now()})
 	if err != nil {
-		writeErrorResponse(r, w, http.StatusUnprocessableEntity, nil,
-			fmt.Sprintf("error JSON-marshaling voter: %v", err))
-		return
+		return nil, internalError(err, "error JSON-marshaling voter")
 	}
 
 	ballotID, err := uuid()
 	if err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error generating ballot ID")
-		return
+		return nil, internalError(err, "error generating ballot ID")
 	}
 	ballotKey := []byte(ballotPrefix + ballotID)
 	ballotValue := make([]byte, 2)
 	binary.BigEndian.PutUint16(ballotValue, 0)
 
-	if _, err := immudbClient.ExecAll(&schema.ExecAllRequest{
-		Operations: []*schema.Op{
-			{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: voterKey, Value: voterBytes}}},
-			{Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{Key: citizenKey, ReferencedKey: voterKey}}},
-			{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: ballotKey, Value: ballotValue}}},
-		},
-	}); err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error persisting voter registration")
-		return
-	}
-
-	resPayload := RegisterVoterResponse{
+	if err := s.execAllAndTally(ctx, []*schema.Op{
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: voterKey, Value: voterBytes}}},
+		{Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{Key: citizenKey, ReferencedKey: voterKey}}},
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: ballotKey, Value: ballotValue}}},
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{
+			Key: []byte(ballotPrecinctPrefix + ballotID), Value: []byte(req.Precinct)}}},
+	}, func(tally *Tally) {
+		tally.count(req.Precinct, func(counts *Counts) { counts.Registered++ })
+	}); errors.Is(err, errElectionClosed) {
+		return nil, newAPIError(http.StatusForbidden, ErrCodeElectionClosed, nil, err.Error())
+	} else if err != nil {
+		return nil, internalError(err, "error persisting voter registration")
+	}
+	registrations.Inc()
+
+	return &RegisterVoterResponse{
 		VoterID:  voterID,
 		BallotID: ballotID,
-	}
-
-	writeJSONResponse(r, w, http.StatusOK, &resPayload)
+	}, nil
 }
 
 // VoteRequest ...
@@ -190,153 +207,188 @@ type VoteRequest struct {
 }
 
 func (req *VoteRequest) validate() error {
-	var errs []string
+	var errs validationErrors
 	if len(req.VoterID) == 0 {
-		errs = append(errs, "voter ID is missing")
+		errs.add("voter_id", "voter ID is missing")
 	}
 	if len(req.BallotID) == 0 {
-		errs = append(errs, "ballot ID is missing")
+		errs.add("ballot_id", "ballot ID is missing")
 	}
 	if req.Vote == 0 {
-		errs = append(errs, "vote is missing")
+		errs.add("vote", "vote is missing")
 	} else if req.Vote != KamalaHarris && req.Vote != NikkiHaley {
-		errs = append(errs, "invalid vote")
-	}
-
-	if len(errs) > 0 {
-		return errors.New(strings.Join(errs, ", "))
+		errs.add("vote", "invalid vote")
 	}
-	return nil
+	return errs.err()
 }
 
-func voteHandler(w http.ResponseWriter, r *http.Request) {
-	if !isHTTPMethodValid(r, w, http.MethodPost) {
-		return
-	}
-
+func (s *Server) voteHandler(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	var payload VoteRequest
 	err := decoder.Decode(&payload)
 	if err != nil {
-		writeErrorResponse(r, w, http.StatusBadRequest, nil,
+		writeAPIErrorResponse(r, w, http.StatusBadRequest, ErrCodeInvalidJSON, nil,
 			fmt.Sprintf("error parsing request body: %v", err))
 		return
 	}
-	if err := payload.validate(); err != nil {
-		writeErrorResponse(r, w, http.StatusBadRequest, nil, err.Error())
+
+	if err := s.vote(r.Context(), &payload); err != nil {
+		writeAPIError(r, w, err)
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// vote casts the ballot
+func (s *Server) vote(ctx context.Context, req *VoteRequest) error {
+	if err := req.validate(); err != nil {
+		return invalidRequestError(err)
+	}
 
-	voterKey := []byte(voterPrefix + payload.VoterID)
-	voterBytes, err := immudbClient.Get(voterKey, 0)
+	// the voter can also be given by citizen ID: the lock must be on the voter
+	// key it resolves to, whatever the alias used by each concurrent vote
+	voterKey, err := s.resolveVoterKey(ctx, req.VoterID)
 	if err != nil {
-		// try to get voter also by citizen ID
-		citizenKey := []byte(citizenPrefix + payload.VoterID)
-		voterBytes, err = immudbClient.Get(citizenKey, 0)
-		if err != nil {
-			writeErrorResponse(r, w, http.StatusNotFound, err,
-				"voter has never been registered")
-			return
-		}
+		return err
+	}
+	ballotKey := []byte(ballotPrefix + req.BallotID)
+
+	// the checks below and the write must be atomic: concurrent votes on the
+	// same voter or ballot wait for each other and then read the latest values
+	unlock := s.locks.Lock(string(voterKey), string(ballotKey))
+	defer unlock()
+
+	voterBytes, err := s.store.GetLatest(ctx, voterKey)
+	if err != nil {
+		return internalError(err, "error fetching voter")
 	}
 	var voter Voter
 	if err := json.Unmarshal(voterBytes, &voter); err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error JSON-unmarshaling persisted voter")
-		return
+		return internalError(err, "error JSON-unmarshaling persisted voter")
 	}
 	if voter.RegistrationApproved.IsZero() {
-		writeErrorResponse(r, w, http.StatusForbidden, nil,
+		return newAPIError(http.StatusForbidden, ErrCodeRegistrationNotApproved, nil,
 			"voter registration has never been approved")
-		return
+	}
+	if !voter.Revoked.IsZero() {
+		return newAPIError(http.StatusForbidden, ErrCodeRegistrationRevoked, nil,
+			"voter registration has been revoked")
 	}
 	if !voter.Voted.IsZero() {
-		writeErrorResponse(r, w, http.StatusForbidden, nil,
+		return newAPIError(http.StatusConflict, ErrCodeAlreadyVoted, nil,
 			"voter has already voted")
-		return
 	}
 
-	ballotKey := []byte(ballotPrefix + payload.BallotID)
-	ballotBytes, err := immudbClient.Get(ballotKey, 0)
-	if err != nil {
-		writeErrorResponse(r, w, http.StatusNotFound, err, "no such ballot")
-		return
+	ballotBytes, err := s.store.GetLatest(ctx, ballotKey)
+	if errors.Is(err, ErrNotFound) {
+		return newAPIError(http.StatusNotFound, ErrCodeBallotNotFound, nil, "no such ballot")
+	} else if err != nil {
+		return internalError(err, "error fetching ballot")
 	}
 	existingVote := binary.BigEndian.Uint16(ballotBytes)
 	if existingVote > 0 {
-		writeErrorResponse(r, w, http.StatusForbidden, nil,
+		return newAPIError(http.StatusConflict, ErrCodeBallotAlreadyCast, nil,
 			"ballot has been already cast before")
-		return
+	}
+	// none if the ballot has been issued before the precincts were introduced
+	ballotPrecinct, err := s.store.GetLatest(ctx, []byte(ballotPrecinctPrefix+req.BallotID))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return internalError(err, "error fetching ballot precinct")
 	}
 
 	voter.Voted = time.Now()
 	voterBytes, err = json.Marshal(&voter)
 	if err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error JSON-marshaling voter before persisting it")
-		return
+		return internalError(err, "error JSON-marshaling voter before persisting it")
 	}
 
 	ballotValue := make([]byte, 2)
-	binary.BigEndian.PutUint16(ballotValue, payload.Vote)
-
-	if _, err := immudbClient.ExecAll(&schema.ExecAllRequest{
-		Operations: []*schema.Op{
-			{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: voterKey, Value: voterBytes}}},
-			{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: ballotKey, Value: ballotValue}}},
-		},
-	}); err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error persisting updated voter and ballot")
-		return
-	}
+	binary.BigEndian.PutUint16(ballotValue, req.Vote)
+
+	if err := s.execAllAndTally(ctx, []*schema.Op{
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: voterKey, Value: voterBytes}}},
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: ballotKey, Value: ballotValue}}},
+	}, func(tally *Tally) {
+		tally.count(voter.Precinct, func(counts *Counts) { counts.Voted++ })
+		tally.count(string(ballotPrecinct), func(counts *Counts) {
+			counts.Ballots++
+			counts.Results[req.Vote]++
+		})
+	}); errors.Is(err, errElectionClosed) {
+		return newAPIError(http.StatusForbidden, ErrCodeElectionClosed, nil, err.Error())
+	} else if err != nil {
+		return internalError(err, "error persisting updated voter and ballot")
+	}
+	votesCast.Inc()
+	return nil
+}
 
-	w.WriteHeader(http.StatusNoContent)
+// resolveVoterKey returns the key of the voter with the given voter ID or, if
+// there is none, of the voter the given citizen ID refers to
+func (s *Server) resolveVoterKey(ctx context.Context, voterID string) ([]byte, error) {
+	voterKey := []byte(voterPrefix + voterID)
+	if _, err := s.store.GetLatestEntry(ctx, voterKey); err == nil {
+		return voterKey, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, internalError(err, "error fetching voter")
+	}
+	citizenEntry, err := s.store.GetLatestEntry(ctx, []byte(citizenPrefix+voterID))
+	if errors.Is(err, ErrNotFound) {
+		return nil, newAPIError(http.StatusNotFound, ErrCodeVoterNotFound, nil,
+			"voter has never been registered")
+	} else if err != nil {
+		return nil, internalError(err, "error fetching voter")
+	}
+	// the citizen key is a reference: the entry is the one of the voter key
+	return citizenEntry.GetKey(), nil
 }
 
 // GetVoterStatusResponse ...
 type GetVoterStatusResponse struct {
 	RegistrationApproved time.Time `json:"approved"`
 	Voted                time.Time `json:"voted"`
+	Revoked              time.Time `json:"revoked"`
 }
 
-func getVoterStatusHandler(w http.ResponseWriter, r *http.Request) {
-	if !isHTTPMethodValid(r, w, http.MethodGet) {
+func (s *Server) getVoterStatusHandler(w http.ResponseWriter, r *http.Request) {
+	resPayload, err := s.voterStatus(r.Context(), requestParam(r, "voter_id"))
+	if err != nil {
+		writeAPIError(r, w, err)
 		return
 	}
+	writeJSONResponse(r, w, http.StatusOK, resPayload)
+}
 
-	voterID := r.URL.Query().Get("voter_id")
+// voterStatus returns the registration status of the voter, by voter ID or
+// citizen ID
+func (s *Server) voterStatus(ctx context.Context, voterID string) (*GetVoterStatusResponse, error) {
 	if len(voterID) == 0 {
-		writeErrorResponse(r, w, http.StatusBadRequest, nil,
-			"voter_id query param is missing")
-		return
+		return nil, invalidRequestError(validationErrors{{Field: "voter_id", Message: "voter ID is missing"}})
 	}
 
 	voterKey := []byte(voterPrefix + voterID)
-	voterBytes, err := immudbClient.Get(voterKey, 0)
+	voterBytes, err := s.store.Get(ctx, voterKey, 0)
 	if err != nil {
 		// try to get voter also by citizen ID
 		citizenKey := []byte(citizenPrefix + voterID)
-		voterBytes, err = immudbClient.Get(citizenKey, 0)
-		if err != nil {
-			writeErrorResponse(r, w, http.StatusNotFound, err,
+		voterBytes, err = s.store.Get(ctx, citizenKey, 0)
+		if errors.Is(err, ErrNotFound) {
+			return nil, newAPIError(http.StatusNotFound, ErrCodeVoterNotFound, nil,
 				"voter has never been registered")
-			return
+		} else if err != nil {
+			return nil, internalError(err, "error fetching voter")
 		}
 	}
 	var voter Voter
 	if err := json.Unmarshal(voterBytes, &voter); err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error JSON-unmarshaling persisted voter")
-		return
+		return nil, internalError(err, "error JSON-unmarshaling persisted voter")
 	}
 
-	resPayload := GetVoterStatusResponse{
+	return &GetVoterStatusResponse{
 		RegistrationApproved: voter.RegistrationApproved,
 		Voted:                voter.Voted,
-	}
-
-	writeJSONResponse(r, w, http.StatusOK, &resPayload)
+		Revoked:              voter.Revoked,
+	}, nil
 }
 
 // GetBallotResponse ...
@@ -345,31 +397,33 @@ type GetBallotResponse struct {
 	Vote     uint16 `json:"vote"`
 }
 
-func getBallotHandler(w http.ResponseWriter, r *http.Request) {
-	if !isHTTPMethodValid(r, w, http.MethodGet) {
+func (s *Server) getBallotHandler(w http.ResponseWriter, r *http.Request) {
+	resPayload, err := s.ballot(r.Context(), requestParam(r, "ballot_id"))
+	if err != nil {
+		writeAPIError(r, w, err)
 		return
 	}
+	writeJSONResponse(r, w, http.StatusOK, resPayload)
+}
 
-	ballotID := r.URL.Query().Get("ballot_id")
+// ballot returns the ballot (its vote is 0 if it has not been cast yet)
+func (s *Server) ballot(ctx context.Context, ballotID string) (*GetBallotResponse, error) {
 	if len(ballotID) == 0 {
-		writeErrorResponse(r, w, http.StatusBadRequest, nil,
-			"ballot_id query param is missing")
-		return
+		return nil, invalidRequestError(validationErrors{{Field: "ballot_id", Message: "ballot ID is missing"}})
 	}
 
 	ballotKey := []byte(ballotPrefix + ballotID)
-	ballotBytes, err := immudbClient.Get(ballotKey, 0)
-	if err != nil {
-		writeErrorResponse(r, w, http.StatusNotFound, err, "no such ballot")
-		return
+	ballotBytes, err := s.store.Get(ctx, ballotKey, 0)
+	if errors.Is(err, ErrNotFound) {
+		return nil, newAPIError(http.StatusNotFound, ErrCodeBallotNotFound, nil, "no such ballot")
+	} else if err != nil {
+		return nil, internalError(err, "error fetching ballot")
 	}
 
-	resPayload := GetBallotResponse{
+	return &GetBallotResponse{
 		BallotID: ballotID,
 		Vote:     binary.BigEndian.Uint16(ballotBytes),
-	}
-
-	writeJSONResponse(r, w, http.StatusOK, &resPayload)
+	}, nil
 }
 
 // RandomBallotResponse ...
@@ -378,42 +432,48 @@ type RandomBallotResponse struct {
 	History []uint16 `json:"history"`
 }
 
-func getRandomBallotHandler(w http.ResponseWriter, r *http.Request) {
-	if !isHTTPMethodValid(r, w, http.MethodGet) {
-		return
+// randomBallot returns a random ballot, in constant time whatever the number of
+// ballots: the ballot IDs are random, so the first ballot from a random ID on
+// (wrapping around) is a random one
+func (s *Server) randomBallot(ctx context.Context) (*schema.Entry, error) {
+	randomID, err := uuid()
+	if err != nil {
+		return nil, fmt.Errorf("error generating random ballot ID: %v", err)
 	}
+	for _, seekKey := range [][]byte{[]byte(ballotPrefix + randomID), nil} {
+		ballotEntries, err := s.store.Scan(ctx, []byte(ballotPrefix), 1, seekKey, false)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning ballots: %v", err)
+		}
+		if len(ballotEntries) > 0 {
+			return ballotEntries[0], nil
+		}
+	}
+	return nil, nil
+}
 
-	ballotEntries, err := immudbClient.Scan(
-		[]byte(ballotPrefix), database.MaxKeyScanLimit, nil, false)
+func (s *Server) getRandomBallotHandler(w http.ResponseWriter, r *http.Request) {
+	randomBallotEntry, err := s.randomBallot(r.Context())
 	if err != nil {
 		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error scanning ballots")
+			"error picking random ballot")
 		return
 	}
-
-	if len(ballotEntries) == 0 {
-		writeErrorResponse(r, w, http.StatusNotFound, err,
+	if randomBallotEntry == nil {
+		writeErrorResponse(r, w, http.StatusNotFound, nil,
 			"no ballots have been cast yet")
 		return
 	}
 
-	randomBigInt, err := rand.Int(rand.Reader, big.NewInt(int64(len(ballotEntries))))
-	if err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error generating random number")
-		return
-	}
-	randomBallotEntry := ballotEntries[randomBigInt.Int64()]
-
-	historyEntries, err := immudbClient.History(randomBallotEntry.GetKey())
+	historyEntries, err := historyAll(r.Context(), s.store, randomBallotEntry.GetKey())
 	if err != nil {
 		writeErrorResponse(r, w, http.StatusInternalServerError, err,
 			fmt.Sprintf("error loading history for random ballot %s",
 				strings.TrimPrefix(string(randomBallotEntry.GetKey()), ballotPrefix)))
 		return
 	}
-	history := make([]uint16, 0, len(historyEntries.GetEntries()))
-	for _, historyEntry := range historyEntries.GetEntries() {
+	history := make([]uint16, 0, len(historyEntries))
+	for _, historyEntry := range historyEntries {
 		history = append(history, binary.BigEndian.Uint16(historyEntry.GetValue()))
 	}
 
@@ -430,40 +490,59 @@ func getRandomBallotHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetStateResponse ...
 type GetStateResponse struct {
+	DB     string `json:"db"`
 	TXID   uint64 `json:"tx_id"`
 	TXHash string `json:"tx_hash"`
+	// signature of the state by the server (see signState), if it has been
+	// signed, which the verifiers check against the public key of the server
+	// before exchanging the state with their peers
+	Signature *schema.Signature `json:"signature,omitempty"`
 }
 
-func getStateHandler(w http.ResponseWriter, r *http.Request) {
-	if !isHTTPMethodValid(r, w, http.MethodGet) {
-		return
-	}
-	state, err := immudbClient.CurrentState()
+func (s *Server) getStateHandler(w http.ResponseWriter, r *http.Request) {
+	resPayload, err := s.state(r.Context())
 	if err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error fetching current state")
+		writeAPIError(r, w, err)
 		return
 	}
-	resPayload := GetStateResponse{
-		TXID: state.TxId, TXHash: base64.StdEncoding.EncodeToString(state.TxHash)}
-	writeJSONResponse(r, w, http.StatusOK, &resPayload)
+	writeJSONResponse(r, w, http.StatusOK, resPayload)
 }
 
-func getVerifiableTransactionHandler(w http.ResponseWriter, r *http.Request) {
-	if !isHTTPMethodValid(r, w, http.MethodGet) {
-		return
-	}
+// state returns the current state of the database
+func (s *Server) state(ctx context.Context) (*GetStateResponse, error) {
+	state, err := s.store.CurrentState(ctx)
+	if err != nil {
+		return nil, internalError(err, "error fetching current state")
+	}
+	// the store may share its state, which must not be signed in place
+	signedState := schema.ImmutableState{
+		Db:        s.store.Database(),
+		TxId:      state.GetTxId(),
+		TxHash:    state.GetTxHash(),
+		Signature: state.GetSignature(),
+	}
+	if err := signState(s.stateSigner, &signedState); err != nil {
+		return nil, internalError(err, "error signing current state")
+	}
+	return &GetStateResponse{
+		DB:        signedState.GetDb(),
+		TXID:      signedState.GetTxId(),
+		TXHash:    base64.StdEncoding.EncodeToString(signedState.GetTxHash()),
+		Signature: signedState.GetSignature(),
+	}, nil
+}
 
-	serverTXStr := r.URL.Query().Get("server_tx")
+func (s *Server) getVerifiableTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	serverTXStr := requestParam(r, "server_tx")
 	if len(serverTXStr) == 0 {
 		writeErrorResponse(r, w, http.StatusBadRequest, nil,
-			"server_tx query param is missing")
+			"server_tx param is missing")
 		return
 	}
 	serverTX, err := strconv.ParseUint(serverTXStr, 10, 64)
 	if err != nil {
 		writeErrorResponse(r, w, http.StatusBadRequest, err,
-			"server_tx query param is not an unisigned int")
+			"server_tx param is not an unisigned int")
 		return
 	}
 
@@ -480,80 +559,74 @@ func getVerifiableTransactionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	verifiableTX, err := immudbClient.VerifiableTXByID(serverTX, localTX)
+	verifiableTX, err := s.verifiableTX(r.Context(), serverTX, localTX)
 	if err != nil {
-		httpErrCode := http.StatusInternalServerError
-		if grpcStatus, ok := status.FromError(err); ok && grpcStatus.Message() == "tx not found" {
-			httpErrCode = http.StatusNotFound
-		}
-		writeErrorResponse(r, w, httpErrCode, err, "error fetching verifiable transaction")
+		writeAPIError(r, w, err)
 		return
 	}
-	writeJSONResponse(r, w, http.StatusOK, &verifiableTX)
+	writeJSONResponse(r, w, http.StatusOK, verifiableTX)
 }
 
-// GetStatsResponse ...
-type GetStatsResponse struct {
-	Registered uint64            `json:"registered"`
-	Voted      uint64            `json:"voted"`
-	Ballots    uint64            `json:"ballots"`
-	Results    map[uint16]uint64 `json:"results"`
+// verifiableTX returns the server tx with the proof of its consistency with the
+// local tx (the latest verified by the client)
+func (s *Server) verifiableTX(ctx context.Context, serverTX uint64, localTX uint64) (*schema.VerifiableTx, error) {
+	verifiableTX, err := s.store.VerifiableTXByID(ctx, serverTX, localTX)
+	if err != nil {
+		// the immudb server answers with a gRPC status, the embedded database
+		// with the error of its store
+		if grpcStatus, ok := status.FromError(err); (ok && grpcStatus.Message() == "tx not found") ||
+			errors.Is(err, store.ErrTxNotFound) {
+			return nil, newAPIError(http.StatusNotFound, ErrCodeNotFound, err, "error fetching verifiable transaction")
+		}
+		return nil, internalError(err, "error fetching verifiable transaction")
+	}
+	return verifiableTX, nil
 }
 
-func getStatsHandler(w http.ResponseWriter, r *http.Request) {
-	if !isHTTPMethodValid(r, w, http.MethodGet) {
-		return
-	}
+// GetStatsRequest are the query params of the stats
+type GetStatsRequest struct {
+	// ID of the unit to return the stats of, if any
+	Unit string
+	// the stats as of this tx, or as of the latest tx committed at this time,
+	// if any
+	AsOfTX   uint64
+	AsOfTime time.Time
+}
 
-	resPayload := GetStatsResponse{
-		Results: make(map[uint16]uint64),
-	}
+// GetStatsResponse are the stats of the election, or of a unit, and those of
+// the units below
+type GetStatsResponse struct {
+	// ID of the tx in which the tally of the stats has been written: the same
+	// stats are returned as of any tx since then, until the next write
+	TXID uint64 `json:"tx_id"`
+	// ID of the unit of the stats, if filtered by unit
+	Unit string `json:"unit,omitempty"`
+	Counts
+	Units []UnitStats `json:"units"`
+}
 
-	voterEntries, err := immudbClient.Scan(
-		[]byte(voterPrefix), database.MaxKeyScanLimit, nil, false)
-	if err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error scanning voters")
-		return
-	}
-	resPayload.Registered = uint64(len(voterEntries))
-	for _, voterEntry := range voterEntries {
-		var voter Voter
-		if err := json.Unmarshal(voterEntry.GetValue(), &voter); err != nil {
-			log.Print(fmt.Sprintf(
-				"ERROR JSON-unmarshaling voter %s with key %s: %v",
-				voterEntry.GetValue(), voterEntry.GetKey(), err))
-			continue
+func (s *Server) getStatsHandler(w http.ResponseWriter, r *http.Request) {
+	req := GetStatsRequest{Unit: r.URL.Query().Get("unit")}
+	if asOfTXStr := r.URL.Query().Get("as_of_tx"); len(asOfTXStr) > 0 {
+		var err error
+		if req.AsOfTX, err = strconv.ParseUint(asOfTXStr, 10, 64); err != nil || req.AsOfTX == 0 {
+			writeErrorResponse(r, w, http.StatusBadRequest, err,
+				"as_of_tx query param is not a positive unsigned int")
+			return
 		}
-		if !voter.Voted.IsZero() {
-			resPayload.Voted++
+	}
+	if asOfTimeStr := r.URL.Query().Get("as_of_time"); len(asOfTimeStr) > 0 {
+		var err error
+		if req.AsOfTime, err = time.Parse(time.RFC3339, asOfTimeStr); err != nil {
+			writeErrorResponse(r, w, http.StatusBadRequest, err,
+				"as_of_time query param is not an RFC 3339 time")
+			return
 		}
 	}
-
-	ballotEntries, err := immudbClient.Scan(
-		[]byte(ballotPrefix), database.MaxKeyScanLimit, nil, false)
+	resPayload, err := s.stats(r.Context(), &req)
 	if err != nil {
-		writeErrorResponse(r, w, http.StatusInternalServerError, err,
-			"error scanning ballots")
+		writeAPIError(r, w, err)
 		return
 	}
-
-	for _, ballotEntry := range ballotEntries {
-		vote := binary.BigEndian.Uint16(ballotEntry.GetValue())
-		switch vote {
-		case KamalaHarris:
-			resPayload.Results[KamalaHarris] = resPayload.Results[KamalaHarris] + 1
-			resPayload.Ballots++
-		case NikkiHaley:
-			resPayload.Results[NikkiHaley] = resPayload.Results[NikkiHaley] + 1
-			resPayload.Ballots++
-		case 0:
-			// nothing to do: this ballot has not been cast yet
-		default:
-			log.Print(fmt.Sprintf(
-				"ERROR: ballot %s has invalid vote %d", ballotEntry.GetKey(), vote))
-		}
-	}
-
-	writeJSONResponse(r, w, http.StatusOK, &resPayload)
+	writeJSONResponse(r, w, http.StatusOK, resPayload)
 }
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/signer"
+)
+
+func TestRegisterAndVote(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	electionURL := httpServer.URL + apiV1Prefix + "/elections/" + electionID
+
+	alice := registerTestVoter(t, httpServer.URL, "alice", "north-1-a")
+	registerTestVoter(t, httpServer.URL, "bob", "south-1-b")
+	if status := doJSON(t, http.MethodPost, electionURL+"/voters", false, &RegisterVoterRequest{
+		CitizenID: "alice",
+		Name:      "Alice again",
+		Address:   "2 Main Street",
+		Email:     "alice@example.com",
+		Precinct:  "north-1-a",
+	}, nil); status != http.StatusConflict {
+		t.Fatalf("registering citizen twice: got status %d, want %d", status, http.StatusConflict)
+	}
+
+	vote := &VoteRequest{RegisterVoterResponse: *alice, Vote: KamalaHarris}
+	if status := doJSON(t, http.MethodPost, electionURL+"/votes", false, vote, nil); status != http.StatusNoContent {
+		t.Fatalf("voting: got status %d, want %d", status, http.StatusNoContent)
+	}
+	if status := doJSON(t, http.MethodPost, electionURL+"/votes", false, vote, nil); status != http.StatusConflict {
+		t.Fatalf("voting twice: got status %d, want %d", status, http.StatusConflict)
+	}
+	unknown := &VoteRequest{
+		RegisterVoterResponse: RegisterVoterResponse{VoterID: "no-such-voter", BallotID: alice.BallotID},
+		Vote:                  NikkiHaley,
+	}
+	if status := doJSON(t, http.MethodPost, electionURL+"/votes", false, unknown, nil); status != http.StatusNotFound {
+		t.Fatalf("voting as unknown voter: got status %d, want %d", status, http.StatusNotFound)
+	}
+
+	var stats GetStatsResponse
+	if status := doJSON(t, http.MethodGet, electionURL+"/stats", false, nil, &stats); status != http.StatusOK {
+		t.Fatalf("fetching stats: got status %d, want %d", status, http.StatusOK)
+	}
+	if stats.Registered != 2 || stats.Voted != 1 || stats.Ballots != 1 ||
+		stats.Results[KamalaHarris] != 1 || stats.Results[NikkiHaley] != 0 {
+		t.Errorf("got stats %+v, want 2 registered, 1 voted and 1 ballot for candidate %d",
+			stats.Counts, KamalaHarris)
+	}
+
+	var north GetStatsResponse
+	if status := doJSON(t, http.MethodGet, electionURL+"/stats?unit=north", false, nil, &north); status != http.StatusOK {
+		t.Fatalf("fetching stats of region: got status %d, want %d", status, http.StatusOK)
+	}
+	if north.Registered != 1 || north.Voted != 1 || north.Ballots != 1 {
+		t.Errorf("got stats of region north %+v, want 1 registered, 1 voted and 1 ballot", north.Counts)
+	}
+}
+
+func TestVerifiableTX(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	registerTestVoter(t, httpServer.URL, "alice", "north-1-a")
+	state, err := server.store.CurrentState(context.Background())
+	if err != nil {
+		t.Fatalf("error fetching current state: %v", err)
+	}
+
+	var vTX schema.VerifiableTx
+	url := fmt.Sprintf("%s%s/verifiable-txs/%d?local_tx=1", httpServer.URL, apiV1Prefix, state.GetTxId())
+	if status := doJSON(t, http.MethodGet, url, false, nil, &vTX); status != http.StatusOK {
+		t.Fatalf("fetching verifiable tx: got status %d, want %d", status, http.StatusOK)
+	}
+	localAlh := schema.DualProofFrom(vTX.GetDualProof()).SourceTxMetadata.Alh()
+	if !store.VerifyDualProof(schema.DualProofFrom(vTX.GetDualProof()), 1, state.GetTxId(),
+		localAlh, schema.DigestFrom(state.GetTxHash())) {
+		t.Errorf("dual proof of tx %d since tx 1 does not verify", state.GetTxId())
+	}
+
+	url = fmt.Sprintf("%s%s/verifiable-txs/%d?local_tx=1", httpServer.URL, apiV1Prefix, state.GetTxId()+1)
+	if status := doJSON(t, http.MethodGet, url, false, nil, nil); status != http.StatusNotFound {
+		t.Errorf("fetching unknown verifiable tx: got status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestStateSigned(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating signing key: %v", err)
+	}
+	server.stateSigner = signer.NewSignerFromPKey(rand.Reader, key)
+	registerTestVoter(t, httpServer.URL, "alice", "north-1-a")
+
+	var state GetStateResponse
+	if status := doJSON(t, http.MethodGet, httpServer.URL+"/state", false, nil, &state); status != http.StatusOK {
+		t.Fatalf("fetching state: got status %d, want %d", status, http.StatusOK)
+	}
+	txHash, err := base64.StdEncoding.DecodeString(state.TXHash)
+	if err != nil {
+		t.Fatalf("error decoding tx hash %s: %v", state.TXHash, err)
+	}
+	signedState := schema.ImmutableState{
+		Db:        state.DB,
+		TxId:      state.TXID,
+		TxHash:    txHash,
+		Signature: state.Signature,
+	}
+	if ok, err := signedState.CheckSignature(&key.PublicKey); err != nil || !ok {
+		t.Errorf("state signature does not verify against the signing key: %t, %v", ok, err)
+	}
+}
+
+// TestVoteConcurrent fires many concurrent votes for each voter, by voter ID
+// and by citizen ID, and checks that each voter votes exactly once
+func TestVoteConcurrent(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	const nbVoters, concurrency = 20, 10
+	citizenIDs := make([]string, nbVoters)
+	voters := make([]*RegisterVoterResponse, nbVoters)
+	for i := range voters {
+		citizenIDs[i] = fmt.Sprintf("citizen-%d", i)
+		voters[i] = registerTestVoter(t, httpServer.URL, citizenIDs[i], "north-1-a")
+	}
+
+	// all votes of all voters are fired at once
+	accepted := make([]int32, nbVoters)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, voter := range voters {
+		for j := 0; j < concurrency; j++ {
+			req := &VoteRequest{RegisterVoterResponse: *voter, Vote: NikkiHaley}
+			if j%2 == 1 {
+				req.VoterID, req.Vote = citizenIDs[i], KamalaHarris
+			}
+			wg.Add(1)
+			go func(i int, req *VoteRequest) {
+				defer wg.Done()
+				<-start
+				err := server.vote(context.Background(), req)
+				var apiErr *apiError
+				switch {
+				case err == nil:
+					atomic.AddInt32(&accepted[i], 1)
+				case !errors.As(err, &apiErr) || apiErr.status != http.StatusConflict:
+					t.Errorf("voting as %s: got %v, want a conflict", req.VoterID, err)
+				}
+			}(i, req)
+		}
+	}
+	close(start)
+	wg.Wait()
+
+	for i, voter := range voters {
+		if accepted[i] != 1 {
+			t.Errorf("voter %s: got %d votes accepted, want 1", voter.VoterID, accepted[i])
+		}
+		var status GetVoterStatusResponse
+		if code := doJSON(t, http.MethodGet, fmt.Sprintf("%s/voter-status?voter_id=%s", httpServer.URL, voter.VoterID),
+			false, nil, &status); code != http.StatusOK || status.Voted.IsZero() {
+			t.Errorf("voter %s: got status %d, %+v, want voted", voter.VoterID, code, status)
+		}
+		// the vote by citizen ID must have updated the voter, not a new key
+		if _, err := server.store.GetLatest(context.Background(), []byte(voterPrefix+citizenIDs[i])); !errors.Is(err, ErrNotFound) {
+			t.Errorf("citizen %s: got %v for the voter key of the citizen ID, want %v", citizenIDs[i], err, ErrNotFound)
+		}
+	}
+	tally, err := server.scanTally(context.Background())
+	if err != nil {
+		t.Fatalf("error scanning tally: %v", err)
+	}
+	if tally.Voted != nbVoters || tally.Ballots != nbVoters {
+		t.Errorf("got %d voted and %d ballots, want %d", tally.Voted, tally.Ballots, nbVoters)
+	}
+}
+
+// TestLegacyRoutes checks that only the routes of the first releases are still
+// served unversioned
+func TestLegacyRoutes(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	for _, route := range []string{"/state", "/stats"} {
+		if status := doJSON(t, http.MethodGet, httpServer.URL+route, false, nil, nil); status != http.StatusOK {
+			t.Errorf("GET %s: got status %d, want %d", route, status, http.StatusOK)
+		}
+	}
+	for _, route := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/bulletin-board"},
+		{http.MethodGet, "/audit-sweep"},
+		{http.MethodGet, "/rla"},
+		{http.MethodGet, "/rla/sample"},
+		{http.MethodGet, "/admin/audit-bundle"},
+		{http.MethodPost, "/admin/rla"},
+		{http.MethodPost, "/admin/rla/interpretation"},
+		{http.MethodGet, "/admin/duplicate-citizens"},
+		{http.MethodPost, "/admin/duplicate-citizens/resolve"},
+	} {
+		if status := doJSON(t, route.method, httpServer.URL+route.path, true, nil, nil); status != http.StatusNotFound {
+			t.Errorf("%s %s: got status %d, want %d", route.method, route.path, status, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// HealthResponse ...
+type HealthResponse struct {
+	Status string `json:"status"`
+	TXID   uint64 `json:"tx_id,omitempty"`
+}
+
+// Drain makes the readiness check fail from now on, so that orchestrators stop
+// routing requests to the server before it shuts down, and ends the streams
+func (s *Server) Drain() {
+	atomic.StoreInt32(&s.draining, 1)
+	s.drainOnce.Do(func() { close(s.drained) })
+}
+
+// healthzHandler is the liveness check: the process is up and serving HTTP
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(r, w, http.StatusOK, &HealthResponse{Status: "ok"})
+}
+
+// readyzHandler is the readiness check: the server is not shutting down,
+// immudb is reachable and the current state is readable
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&s.draining) == 1 {
+		writeErrorResponse(r, w, http.StatusServiceUnavailable, nil, "server is shutting down")
+		return
+	}
+	if err := s.store.Ping(r.Context()); err != nil {
+		writeErrorResponse(r, w, http.StatusServiceUnavailable, err, "immudb is not reachable")
+		return
+	}
+	state, err := s.store.CurrentState(r.Context())
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusServiceUnavailable, err, "error fetching current state")
+		return
+	}
+	if state.GetTxId() == 0 {
+		writeErrorResponse(r, w, http.StatusServiceUnavailable, nil,
+			fmt.Sprintf("database %s is empty", s.store.Database()))
+		return
+	}
+	writeJSONResponse(r, w, http.StatusOK, &HealthResponse{Status: "ok", TXID: state.GetTxId()})
+}
@@ -4,9 +4,11 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"regexp"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
 // NoOpWriter ...
@@ -27,18 +29,28 @@ func isEmailValid(e string) bool {
 	return emailRegex.MatchString(e)
 }
 
-func isHTTPMethodValid(
-	r *http.Request,
-	w http.ResponseWriter,
-	method string) bool {
+// methodNotAllowedHandler is the router's handler of the requests whose path
+// matches a route, but not their method
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	errMsg := fmt.Sprintf(
+		"%s http method is not supported on %s resource", r.Method, r.URL.Path)
+	writeErrorResponse(r, w, http.StatusMethodNotAllowed, nil, errMsg)
+}
 
-	if r.Method != method {
-		errMsg := fmt.Sprintf(
-			"%s http method is not supported on %s resource", r.Method, r.URL.Path)
-		writeErrorResponse(r, w, http.StatusMethodNotAllowed, nil, errMsg)
-		return false
+// notFoundHandler is the router's handler of the requests matching no route
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeErrorResponse(r, w, http.StatusNotFound, nil,
+		fmt.Sprintf("no such resource %s", r.URL.Path))
+}
+
+// requestParam returns the path param of the request with the given name (on
+// the /api/v1 routes) or else the query param with the same name (on the
+// legacy routes)
+func requestParam(r *http.Request, name string) string {
+	if value, ok := mux.Vars(r)[name]; ok {
+		return value
 	}
-	return true
+	return r.URL.Query().Get(name)
 }
 
 func writeJSONResponse(
@@ -47,15 +59,14 @@ func writeJSONResponse(
 	statusCode int,
 	body interface{}) {
 
-	statusText := http.StatusText(statusCode)
-	log.Print(fmt.Sprintf(
-		"%s %s %d %s",
-		r.Method, r.URL.Path, statusCode, statusText))
+	requestLogger(r).WithField("status", statusCode).Info(http.StatusText(statusCode))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(body)
 }
 
+// writeErrorResponse writes the JSON error envelope with the generic code of
+// the HTTP status (see writeAPIErrorResponse for specific codes)
 func writeErrorResponse(
 	r *http.Request,
 	w http.ResponseWriter,
@@ -63,16 +74,37 @@ func writeErrorResponse(
 	err error,
 	msg string) {
 
-	statusText := http.StatusText(statusCode)
-	msgErr := msg
+	writeAPIErrorResponse(r, w, statusCode, errorCodeForStatus(statusCode), err, msg)
+}
+
+// writeAPIErrorResponse logs the error and writes the JSON error envelope; err
+// is only logged, msg is returned to the client
+func writeAPIErrorResponse(
+	r *http.Request,
+	w http.ResponseWriter,
+	statusCode int,
+	code string,
+	err error,
+	msg string,
+	details ...FieldError) {
+
+	entry := requestLogger(r).WithFields(logrus.Fields{"status": statusCode, "code": code})
 	if err != nil {
-		msgErr += ": " + err.Error()
+		entry = entry.WithError(err)
 	}
-	log.Print(fmt.Sprintf(
-		"%s %s %d %s - ERROR: %s",
-		r.Method, r.URL.Path, statusCode, statusText, msgErr))
+	if statusCode >= http.StatusInternalServerError {
+		entry.Error(msg)
+	} else {
+		entry.Warn(msg)
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	w.Write([]byte(http.StatusText(statusCode) + ": " + msg))
+	json.NewEncoder(w).Encode(&ErrorResponse{Error: APIError{
+		Code:      code,
+		Message:   msg,
+		Details:   details,
+		RequestID: requestIDFromContext(r.Context()),
+	}})
 }
 
 func uuid() (string, error) {
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	idempotencyPrefix    = "immuvoting:idempotency:"
+	idempotencyHeader    = "Idempotency-Key"
+	idempotencyReplayed  = "Idempotent-Replayed"
+	idempotencyMaxKeyLen = 255
+	// retries within this window replay the original response
+	idempotencyWindow = 24 * time.Hour
+)
+
+// idempotentResponse is the first response to a request with an idempotency
+// key, persisted in immudb so that it survives server restarts
+type idempotentResponse struct {
+	RequestHash []byte    `json:"request_hash"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// responseRecorder buffers the response of a handler, so that it can be
+// persisted before being sent
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) Header() http.Header {
+	return rr.header
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	return rr.body.Write(b)
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+}
+
+func (rr *responseRecorder) flush(w http.ResponseWriter) {
+	for k, v := range rr.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rr.status)
+	w.Write(rr.body.Bytes())
+}
+
+// idempotent middleware: the first response to a request carrying an
+// Idempotency-Key header is persisted and replayed verbatim to the retries of
+// the same request (e.g. by mobile clients on flaky networks); server errors
+// (5xx) are not persisted, so those requests can be retried for real
+func (s *Server) idempotent(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(idempotencyHeader)
+		if len(idempotencyKey) == 0 {
+			handler(w, r)
+			return
+		}
+		if len(idempotencyKey) > idempotencyMaxKeyLen {
+			writeErrorResponse(r, w, http.StatusBadRequest, nil, fmt.Sprintf(
+				"%s header must have at most %d characters", idempotencyHeader, idempotencyMaxKeyLen))
+			return
+		}
+
+		reqBody, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeErrorResponse(r, w, http.StatusBadRequest, nil,
+				fmt.Sprintf("error reading request body: %v", err))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+		reqHash := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), reqBody...))
+
+		key := []byte(idempotencyPrefix + r.URL.Path + ":" + idempotencyKey)
+		unlock := s.locks.Lock(string(key))
+		defer unlock()
+
+		persistedBytes, err := s.store.GetLatest(r.Context(), key)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			writeErrorResponse(r, w, http.StatusInternalServerError, err,
+				"error fetching idempotent response")
+			return
+		}
+		if err == nil {
+			var persisted idempotentResponse
+			if err := json.Unmarshal(persistedBytes, &persisted); err != nil {
+				writeErrorResponse(r, w, http.StatusInternalServerError, err,
+					"error JSON-unmarshaling idempotent response")
+				return
+			}
+			if time.Since(persisted.Created) < idempotencyWindow {
+				if !bytes.Equal(persisted.RequestHash, reqHash[:]) {
+					writeAPIErrorResponse(r, w, http.StatusUnprocessableEntity, ErrCodeIdempotencyKeyReused, nil, fmt.Sprintf(
+						"%s has already been used for a different request", idempotencyHeader))
+					return
+				}
+				requestLogger(r).WithField("status", persisted.Status).Info("replayed idempotent response")
+				if len(persisted.ContentType) > 0 {
+					w.Header().Set("Content-Type", persisted.ContentType)
+				}
+				w.Header().Set(idempotencyReplayed, "true")
+				w.WriteHeader(persisted.Status)
+				w.Write(persisted.Body)
+				return
+			}
+		}
+
+		rec := &responseRecorder{header: make(http.Header)}
+		handler(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status < http.StatusInternalServerError {
+			responseBytes, err := json.Marshal(&idempotentResponse{
+				RequestHash: reqHash[:],
+				Status:      rec.status,
+				ContentType: rec.header.Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+				Created:     time.Now(),
+			})
+			if err == nil {
+				err = s.store.Set(r.Context(), key, responseBytes)
+			}
+			if err != nil {
+				// the request has been handled anyway: its response is sent,
+				// but a retry will not be able to replay it
+				requestLogger(r).WithError(err).Error("error persisting idempotent response")
+			}
+		}
+		rec.flush(w)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// doIdempotent sends the JSON of the payload with the idempotency key and
+// returns the response status, headers and body
+func doIdempotent(
+	t *testing.T, url string, idempotencyKey string, payload interface{}) (int, http.Header, []byte) {
+	t.Helper()
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("error JSON-marshaling request payload: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payloadBytes))
+	if err != nil {
+		t.Fatalf("error creating request POST %s: %v", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyHeader, idempotencyKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error executing request POST %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading response of POST %s: %v", url, err)
+	}
+	return resp.StatusCode, resp.Header, body
+}
+
+func errorCode(t *testing.T, body []byte) string {
+	t.Helper()
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		t.Fatalf("error JSON-unmarshaling error response %s: %v", body, err)
+	}
+	return errResp.Error.Code
+}
+
+func TestIdempotency(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	votersPath := apiV1Prefix + "/elections/" + electionID + "/voters"
+	votersURL := httpServer.URL + votersPath
+	registration := func(citizenID string) *RegisterVoterRequest {
+		return &RegisterVoterRequest{
+			CitizenID: citizenID,
+			Name:      "Voter " + citizenID,
+			Address:   "1 Main Street",
+			Email:     citizenID + "@example.com",
+			Precinct:  "north-1-a",
+		}
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		status, header, body := doIdempotent(t, votersURL, "key-replay", registration("1001"))
+		if status != http.StatusOK || header.Get(idempotencyReplayed) != "" {
+			t.Fatalf("1st request: got status %d, replayed %q, want %d, not replayed",
+				status, header.Get(idempotencyReplayed), http.StatusOK)
+		}
+		replayedStatus, replayedHeader, replayedBody := doIdempotent(
+			t, votersURL, "key-replay", registration("1001"))
+		if replayedStatus != status || replayedHeader.Get(idempotencyReplayed) != "true" {
+			t.Fatalf("retry: got status %d, replayed %q, want %d, replayed",
+				replayedStatus, replayedHeader.Get(idempotencyReplayed), status)
+		}
+		if !bytes.Equal(replayedBody, body) {
+			t.Errorf("retry: got body %s, want %s", replayedBody, body)
+		}
+		// the retry must not have registered the citizen again
+		status, _, body = doIdempotent(t, votersURL, "key-replay-other", registration("1001"))
+		if status != http.StatusConflict || errorCode(t, body) != ErrCodeAlreadyRegistered {
+			t.Errorf("new key: got status %d, body %s, want %d %s",
+				status, body, http.StatusConflict, ErrCodeAlreadyRegistered)
+		}
+	})
+
+	t.Run("key reused for a different request", func(t *testing.T) {
+		if status, _, body := doIdempotent(t, votersURL, "key-reused", registration("1002")); status != http.StatusOK {
+			t.Fatalf("1st request: got status %d, body %s, want %d", status, body, http.StatusOK)
+		}
+		status, header, body := doIdempotent(t, votersURL, "key-reused", registration("1003"))
+		if status != http.StatusUnprocessableEntity || errorCode(t, body) != ErrCodeIdempotencyKeyReused {
+			t.Errorf("different body: got status %d, body %s, want %d %s",
+				status, body, http.StatusUnprocessableEntity, ErrCodeIdempotencyKeyReused)
+		}
+		if header.Get(idempotencyReplayed) != "" {
+			t.Errorf("different body: got replayed %q, want not replayed", header.Get(idempotencyReplayed))
+		}
+		// the 2nd request must not have been handled
+		if status, _, body := doIdempotent(t, votersURL, "key-reused-other", registration("1003")); status != http.StatusOK {
+			t.Errorf("registering citizen 1003: got status %d, body %s, want %d", status, body, http.StatusOK)
+		}
+	})
+
+	t.Run("window expired", func(t *testing.T) {
+		if status, _, body := doIdempotent(t, votersURL, "key-expired", registration("1004")); status != http.StatusOK {
+			t.Fatalf("1st request: got status %d, body %s, want %d", status, body, http.StatusOK)
+		}
+		// age the persisted response past the window
+		key := []byte(idempotencyPrefix + votersPath + ":key-expired")
+		persistedBytes, err := server.store.GetLatest(context.Background(), key)
+		if err != nil {
+			t.Fatalf("error fetching idempotent response: %v", err)
+		}
+		var persisted idempotentResponse
+		if err := json.Unmarshal(persistedBytes, &persisted); err != nil {
+			t.Fatalf("error JSON-unmarshaling idempotent response: %v", err)
+		}
+		persisted.Created = time.Now().Add(-idempotencyWindow - time.Minute)
+		persistedBytes, err = json.Marshal(&persisted)
+		if err != nil {
+			t.Fatalf("error JSON-marshaling idempotent response: %v", err)
+		}
+		if err := server.store.Set(context.Background(), key, persistedBytes); err != nil {
+			t.Fatalf("error persisting idempotent response: %v", err)
+		}
+
+		// the retry is handled for real
+		status, header, body := doIdempotent(t, votersURL, "key-expired", registration("1004"))
+		if status != http.StatusConflict || errorCode(t, body) != ErrCodeAlreadyRegistered {
+			t.Errorf("retry after the window: got status %d, body %s, want %d %s",
+				status, body, http.StatusConflict, ErrCodeAlreadyRegistered)
+		}
+		if header.Get(idempotencyReplayed) != "" {
+			t.Errorf("retry after the window: got replayed %q, want not replayed", header.Get(idempotencyReplayed))
+		}
+	})
+
+	t.Run("server errors not persisted", func(t *testing.T) {
+		var nbCalls int
+		handler := server.idempotent(func(w http.ResponseWriter, r *http.Request) {
+			nbCalls++
+			if nbCalls == 1 {
+				writeErrorResponse(r, w, http.StatusServiceUnavailable, nil, "unavailable")
+				return
+			}
+			writeJSONResponse(r, w, http.StatusOK, map[string]int{"call": nbCalls})
+		})
+		do := func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/server-errors", bytes.NewReader([]byte(`{}`)))
+			req.Header.Set(idempotencyHeader, "key-server-error")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			return rec
+		}
+
+		if rec := do(); rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("1st request: got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+		}
+		rec := do()
+		if rec.Code != http.StatusOK || rec.Header().Get(idempotencyReplayed) != "" {
+			t.Fatalf("retry after a server error: got status %d, replayed %q, want %d, not replayed",
+				rec.Code, rec.Header().Get(idempotencyReplayed), http.StatusOK)
+		}
+		rec = do()
+		if rec.Code != http.StatusOK || rec.Header().Get(idempotencyReplayed) != "true" {
+			t.Errorf("retry after a success: got status %d, replayed %q, want %d, replayed",
+				rec.Code, rec.Header().Get(idempotencyReplayed), http.StatusOK)
+		}
+		if nbCalls != 2 {
+			t.Errorf("got %d handler calls, want 2", nbCalls)
+		}
+	})
+}
@@ -3,12 +3,13 @@ package main
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/codenotary/immudb/embedded/store"
@@ -21,7 +22,9 @@ import (
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 )
 
 // ImmudbConfig ...
@@ -31,10 +34,21 @@ type ImmudbConfig struct {
 	User          string
 	Password      string
 	LocalStateDir string
+	// TLS to immudb: the CA file is optional (the system CAs are used if
+	// empty), the cert and key are only needed for mutual TLS
+	TLS           bool
+	TLSCAFile     string
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSServerName string
 }
 
 // ImmudbClient ...
 type ImmudbClient struct {
+	// ID of the last tx written by this client (first field, to be 64-bit
+	// aligned for atomic access)
+	lastTX uint64
+
 	Config *ImmudbConfig
 
 	Dial            func(string, ...grpc.DialOption) (*grpc.ClientConn, error)
@@ -85,6 +99,32 @@ func (c *ImmudbClient) ensureConnected(force bool) error {
 	return nil
 }
 
+// transportCredentials returns the dial option securing the gRPC connection
+func (c *ImmudbClient) transportCredentials() (grpc.DialOption, error) {
+	if !c.Config.TLS {
+		return grpc.WithInsecure(), nil
+	}
+	rootCAs, err := loadCertPool(c.Config.TLSCAFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    rootCAs,
+		ServerName: c.Config.TLSServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if len(c.Config.TLSCertFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(c.Config.TLSCertFile, c.Config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"error loading immudb client cert %s and key %s: %v",
+				c.Config.TLSCertFile, c.Config.TLSKeyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+}
+
 // Connect ...
 func (c *ImmudbClient) Connect() error {
 	if c == nil {
@@ -94,11 +134,16 @@ func (c *ImmudbClient) Connect() error {
 	// 0. Close previous connection (if any - check must be inside CloseConnection)
 	c.CloseConnection(c.grpcConn)
 
+	transportCredentials, err := c.transportCredentials()
+	if err != nil {
+		return err
+	}
+
 	// 1. Dial to login and obtain token
 	var maxSize int = 512 * 10e6
 	conn, err := c.Dial(
 		c.Config.Address,
-		grpc.WithInsecure(),
+		transportCredentials,
 		grpc.WithTimeout(10*time.Second),
 		grpc.WithBlock(),
 		grpc.WithMaxMsgSize(maxSize),
@@ -124,7 +169,7 @@ func (c *ImmudbClient) Connect() error {
 
 	// 2. Dial again with the obtained token
 	dialOpts := []grpc.DialOption{
-		grpc.WithInsecure(),
+		transportCredentials,
 		grpc.WithTimeout(10 * time.Second),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                2000 * time.Second,
@@ -199,9 +244,32 @@ func (c *ImmudbClient) Connect() error {
 	return nil
 }
 
-// Ping ...
-func (c *ImmudbClient) Ping() error {
-	return c.ensureConnected(false)
+// callContext returns the context of an immudb call made on behalf of ctx: only
+// the request ID of ctx (if any) is propagated, as gRPC metadata, not its
+// cancellation, so that e.g. a vote is never interrupted half-way because its
+// client went away
+func (c *ImmudbClient) callContext(ctx context.Context) context.Context {
+	if requestID := requestIDFromContext(ctx); len(requestID) > 0 {
+		return metadata.AppendToOutgoingContext(c.ctx, requestIDMetadataKey, requestID)
+	}
+	return c.ctx
+}
+
+// Ping checks that immudb is reachable and reports itself healthy
+func (c *ImmudbClient) Ping(ctx context.Context) error {
+	if err := c.ensureConnected(false); err != nil {
+		return err
+	}
+	e := new(empty.Empty)
+	health, err := c.execute(
+		func() (interface{}, error) { return c.immudbClient.Health(c.callContext(ctx), e) })
+	if err != nil {
+		return err
+	}
+	if !health.(*schema.HealthResponse).GetStatus() {
+		return errors.New("immudb reports itself unhealthy")
+	}
+	return nil
 }
 
 // Disconnect ...
@@ -229,10 +297,13 @@ func isTokenExpired(err error) bool {
 func (c *ImmudbClient) execute(f func() (interface{}, error)) (interface{}, error) {
 	res, err := f()
 	if err != nil && isTokenExpired(err) {
-		log.Printf("got error '%s' => reconnecting to immudb ...", err)
+		logger.WithError(err).Warn("immudb session expired, reconnecting")
 		if err = c.ensureConnected(true); err == nil {
-			log.Print("successfully reconnected to immudb")
+			immudbReconnects.WithLabelValues("success").Inc()
+			logger.Info("reconnected to immudb")
 			res, err = f()
+		} else {
+			immudbReconnects.WithLabelValues("failure").Inc()
 		}
 	}
 	return res, err
@@ -282,14 +353,19 @@ func (c *ImmudbClient) CreateUser(
 	return nil
 }
 
+// Database ...
+func (c *ImmudbClient) Database() string {
+	return c.Config.DB
+}
+
 // Get ...
-func (c *ImmudbClient) Get(key []byte, txID uint64) ([]byte, error) {
+func (c *ImmudbClient) Get(ctx context.Context, key []byte, txID uint64) ([]byte, error) {
 	if err := c.ensureConnected(false); err != nil {
 		return nil, err
 	}
 	sKey := &schema.KeyRequest{Key: key, AtTx: txID}
 	item, err := c.execute(
-		func() (interface{}, error) { return c.immudbClient.Get(c.ctx, sKey) })
+		func() (interface{}, error) { return c.immudbClient.Get(c.callContext(ctx), sKey) })
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return nil, fmt.Errorf("key %w: %v", ErrNotFound, err)
@@ -299,8 +375,55 @@ func (c *ImmudbClient) Get(key []byte, txID uint64) ([]byte, error) {
 	return item.(*schema.Entry).Value, nil
 }
 
+// GetLatest returns the value of the key, waiting for immudb to index at least
+// up to the last tx written by this client: the index is updated
+// asynchronously, so Get may return a value older than the last write
+func (c *ImmudbClient) GetLatest(ctx context.Context, key []byte) ([]byte, error) {
+	entry, err := c.GetLatestEntry(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return entry.GetValue(), nil
+}
+
+// GetLatestEntry is like GetLatest, but returns the whole entry (e.g. to know
+// which key a reference resolves to)
+func (c *ImmudbClient) GetLatestEntry(ctx context.Context, key []byte) (*schema.Entry, error) {
+	if err := c.ensureConnected(false); err != nil {
+		return nil, err
+	}
+	sinceTX := atomic.LoadUint64(&c.lastTX)
+	if sinceTX == 0 {
+		// nothing written yet by this client: wait for all existing txs
+		state, err := c.CurrentState(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sinceTX = state.GetTxId()
+	}
+	sKey := &schema.KeyRequest{Key: key, SinceTx: sinceTX}
+	item, err := c.execute(
+		func() (interface{}, error) { return c.immudbClient.Get(c.callContext(ctx), sKey) })
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, fmt.Errorf("key %w: %v", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return item.(*schema.Entry), nil
+}
+
+func (c *ImmudbClient) wroteTX(txID uint64) {
+	for {
+		lastTX := atomic.LoadUint64(&c.lastTX)
+		if txID <= lastTX || atomic.CompareAndSwapUint64(&c.lastTX, lastTX, txID) {
+			return
+		}
+	}
+}
+
 // VerifiedGet ...
-func (c *ImmudbClient) VerifiedGet(key []byte) (*schema.Entry, error) {
+func (c *ImmudbClient) VerifiedGet(ctx context.Context, key []byte) (*schema.Entry, error) {
 	err := c.StateService.CacheLock()
 	if err != nil {
 		return nil, err
@@ -311,7 +434,7 @@ func (c *ImmudbClient) VerifiedGet(key []byte) (*schema.Entry, error) {
 		return nil, err
 	}
 
-	state, err := c.StateService.GetState(c.ctx, c.Config.DB)
+	state, err := c.StateService.GetState(c.callContext(ctx), c.Config.DB)
 	if err != nil {
 		return nil, err
 	}
@@ -322,7 +445,7 @@ func (c *ImmudbClient) VerifiedGet(key []byte) (*schema.Entry, error) {
 	}
 
 	verifiableEntryItf, err := c.execute(func() (interface{}, error) {
-		return c.immudbClient.VerifiableGet(c.ctx, verifiableGetReq)
+		return c.immudbClient.VerifiableGet(c.callContext(ctx), verifiableGetReq)
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), "key not found") {
@@ -409,14 +532,18 @@ func (c *ImmudbClient) VerifiedGet(key []byte) (*schema.Entry, error) {
 }
 
 // Set ...
-func (c *ImmudbClient) Set(key []byte, value []byte) error {
+func (c *ImmudbClient) Set(ctx context.Context, key []byte, value []byte) error {
 	if err := c.ensureConnected(false); err != nil {
 		return err
 	}
 	kv := &schema.SetRequest{KVs: []*schema.KeyValue{{Key: key, Value: value}}}
-	_, err := c.execute(
-		func() (interface{}, error) { return c.immudbClient.Set(c.ctx, kv) })
-	return err
+	txMeta, err := c.execute(
+		func() (interface{}, error) { return c.immudbClient.Set(c.callContext(ctx), kv) })
+	if err != nil {
+		return err
+	}
+	c.wroteTX(txMeta.(*schema.TxMetadata).GetId())
+	return nil
 }
 
 // Reference ...
@@ -432,6 +559,7 @@ func (c *ImmudbClient) Reference(reference []byte, key []byte) error {
 
 // Scan ...
 func (c *ImmudbClient) Scan(
+	ctx context.Context,
 	prefix []byte,
 	limit uint64,
 	seekKey []byte,
@@ -449,7 +577,7 @@ func (c *ImmudbClient) Scan(
 		SinceTx: math.MaxUint64,
 	}
 	itemList, err := c.execute(
-		func() (interface{}, error) { return c.immudbClient.Scan(c.ctx, so) })
+		func() (interface{}, error) { return c.immudbClient.Scan(c.callContext(ctx), so) })
 	if err != nil {
 		return nil, err
 	}
@@ -485,15 +613,16 @@ func (c *ImmudbClient) CountAll() (uint64, error) {
 }
 
 // ExecAll execute several commands in a transaction.
-func (c *ImmudbClient) ExecAll(ops *schema.ExecAllRequest) (uint64, error) {
+func (c *ImmudbClient) ExecAll(ctx context.Context, ops *schema.ExecAllRequest) (uint64, error) {
 	if err := c.ensureConnected(false); err != nil {
 		return 0, err
 	}
 	txMeta, err := c.execute(
-		func() (interface{}, error) { return c.immudbClient.ExecAll(c.ctx, ops) })
+		func() (interface{}, error) { return c.immudbClient.ExecAll(c.callContext(ctx), ops) })
 	if err != nil {
 		return 0, err
 	}
+	c.wroteTX(txMeta.(*schema.TxMetadata).GetId())
 	return txMeta.(*schema.TxMetadata).GetId(), nil
 }
 
@@ -509,43 +638,97 @@ func (c *ImmudbClient) CleanIndex() error {
 }
 
 // CurrentState fetches the current server state
-func (c *ImmudbClient) CurrentState() (*schema.ImmutableState, error) {
+func (c *ImmudbClient) CurrentState(ctx context.Context) (*schema.ImmutableState, error) {
 	if err := c.ensureConnected(false); err != nil {
 		return nil, err
 	}
 	e := new(empty.Empty)
 	currentState, err := c.execute(
-		func() (interface{}, error) { return c.immudbClient.CurrentState(c.ctx, e) })
-	return currentState.(*schema.ImmutableState), err
+		func() (interface{}, error) { return c.immudbClient.CurrentState(c.callContext(ctx), e) })
+	if err != nil {
+		return nil, err
+	}
+	return currentState.(*schema.ImmutableState), nil
 }
 
 // VerifiableTXByID ...
-func (c *ImmudbClient) VerifiableTXByID(serverTX uint64, localTX uint64) (*schema.VerifiableTx, error) {
+func (c *ImmudbClient) VerifiableTXByID(ctx context.Context, serverTX uint64, localTX uint64) (*schema.VerifiableTx, error) {
 	if err := c.ensureConnected(false); err != nil {
 		return nil, err
 	}
 	verifiableTX, err := c.execute(
 		func() (interface{}, error) {
-			return c.immudbClient.VerifiableTxById(c.ctx, &schema.VerifiableTxRequest{
+			return c.immudbClient.VerifiableTxById(c.callContext(ctx), &schema.VerifiableTxRequest{
 				Tx:           serverTX,
 				ProveSinceTx: localTX,
 			})
 		})
-	return verifiableTX.(*schema.VerifiableTx), err
+	if err != nil {
+		return nil, err
+	}
+	return verifiableTX.(*schema.VerifiableTx), nil
 }
 
 // History ...
-func (c *ImmudbClient) History(key []byte) (*schema.Entries, error) {
+func (c *ImmudbClient) History(ctx context.Context, key []byte, offset uint64, limit uint64) ([]*schema.Entry, error) {
 	if err := c.ensureConnected(false); err != nil {
 		return nil, err
 	}
 	entries, err := c.execute(
 		func() (interface{}, error) {
-			return c.immudbClient.History(c.ctx, &schema.HistoryRequest{
+			return c.immudbClient.History(c.callContext(ctx), &schema.HistoryRequest{
 				Key:    key,
-				Offset: 0,
-				Limit:  database.MaxKeyScanLimit,
+				Offset: offset,
+				Limit:  int32(limit),
 			})
 		})
-	return entries.(*schema.Entries), err
+	if err != nil {
+		return nil, err
+	}
+	return entries.(*schema.Entries).GetEntries(), nil
+}
+
+// VerifiableGetAt fetches the value set for the key at the given tx, along with
+// the proofs of its inclusion in that tx and of that tx in the proveSinceTx one
+func (c *ImmudbClient) VerifiableGetAt(
+	ctx context.Context,
+	key []byte,
+	atTx uint64,
+	proveSinceTx uint64,
+) (*schema.VerifiableEntry, error) {
+	if err := c.ensureConnected(false); err != nil {
+		return nil, err
+	}
+	verifiableGetReq := &schema.VerifiableGetRequest{
+		KeyRequest:   &schema.KeyRequest{Key: key, AtTx: atTx},
+		ProveSinceTx: proveSinceTx,
+	}
+	verifiableEntry, err := c.execute(func() (interface{}, error) {
+		return c.immudbClient.VerifiableGet(c.callContext(ctx), verifiableGetReq)
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, fmt.Errorf("key %w: %v", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return verifiableEntry.(*schema.VerifiableEntry), nil
+}
+
+// TxScan fetches up to limit txs, in ascending order, starting with initialTX
+func (c *ImmudbClient) TxScan(ctx context.Context, initialTX uint64, limit uint32) ([]*schema.Tx, error) {
+	if err := c.ensureConnected(false); err != nil {
+		return nil, err
+	}
+	txList, err := c.execute(
+		func() (interface{}, error) {
+			return c.immudbClient.TxScan(c.callContext(ctx), &schema.TxScanRequest{
+				InitialTx: initialTX,
+				Limit:     limit,
+			})
+		})
+	if err != nil {
+		return nil, err
+	}
+	return txList.(*schema.TxList).GetTxs(), nil
 }
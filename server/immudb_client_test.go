@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// tlsTestImmuServer is an immudb server which only serves the calls made by the
+// client to connect and to ping it
+type tlsTestImmuServer struct {
+	schema.UnimplementedImmuServiceServer
+}
+
+func (s *tlsTestImmuServer) Login(context.Context, *schema.LoginRequest) (*schema.LoginResponse, error) {
+	return &schema.LoginResponse{Token: "token"}, nil
+}
+
+func (s *tlsTestImmuServer) UseDatabase(context.Context, *schema.Database) (*schema.UseDatabaseReply, error) {
+	return &schema.UseDatabaseReply{Token: "token"}, nil
+}
+
+func (s *tlsTestImmuServer) Health(ctx context.Context, _ *empty.Empty) (*schema.HealthResponse, error) {
+	// the state service of the client identifies the server by its UUID
+	grpc.SetHeader(ctx, metadata.Pairs("immudb-uuid", "test-server"))
+	return &schema.HealthResponse{Status: true}, nil
+}
+
+// serveImmudbTLS serves the test immudb server over TLS with the given config
+// and returns its address
+func serveImmudbTLS(t *testing.T, tlsConfig *tls.Config) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	schema.RegisterImmuServiceServer(grpcServer, &tlsTestImmuServer{})
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+	return listener.Addr().String()
+}
+
+func TestImmudbClientTLS(t *testing.T) {
+	gencerts := buildCommand(t, "./gencerts")
+	dir, otherDir := genTestCerts(t, gencerts), genTestCerts(t, gencerts)
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"))
+	if err != nil {
+		t.Fatalf("error loading server cert: %v", err)
+	}
+	clientCAs, err := loadCertPool(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatalf("error loading CA: %v", err)
+	}
+	tlsAddress := serveImmudbTLS(t, &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	mTLSAddress := serveImmudbTLS(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	})
+
+	for _, test := range []struct {
+		name    string
+		address string
+		config  ImmudbConfig
+		wantErr bool
+	}{
+		{
+			name:    "server TLS",
+			address: tlsAddress,
+			config:  ImmudbConfig{TLSCAFile: filepath.Join(dir, "ca.pem")},
+		},
+		{
+			name:    "server TLS with the server name",
+			address: tlsAddress,
+			config:  ImmudbConfig{TLSCAFile: filepath.Join(dir, "ca.pem"), TLSServerName: "localhost"},
+		},
+		{
+			name:    "server TLS with another CA",
+			address: tlsAddress,
+			config:  ImmudbConfig{TLSCAFile: filepath.Join(otherDir, "ca.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "mutual TLS",
+			address: mTLSAddress,
+			config: ImmudbConfig{
+				TLSCAFile:   filepath.Join(dir, "ca.pem"),
+				TLSCertFile: filepath.Join(dir, "client.pem"),
+				TLSKeyFile:  filepath.Join(dir, "client-key.pem"),
+			},
+		},
+		{
+			name:    "mutual TLS without a client cert",
+			address: mTLSAddress,
+			config:  ImmudbConfig{TLSCAFile: filepath.Join(dir, "ca.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "mutual TLS with a client cert of another CA",
+			address: mTLSAddress,
+			config: ImmudbConfig{
+				TLSCAFile:   filepath.Join(dir, "ca.pem"),
+				TLSCertFile: filepath.Join(otherDir, "client.pem"),
+				TLSKeyFile:  filepath.Join(otherDir, "client-key.pem"),
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.Address, config.DB, config.TLS = test.address, "defaultdb", true
+			config.LocalStateDir = t.TempDir()
+			client := &ImmudbClient{
+				// the rejected handshakes are retried until the dial times
+				// out: shorten it, and report the handshake error
+				Dial: func(address string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+					return grpc.Dial(address, append(opts,
+						grpc.WithTimeout(time.Second), grpc.WithReturnConnectionError())...)
+				},
+			}
+			client.Init(&config)
+			err := client.Connect()
+			if err == nil {
+				defer client.Disconnect()
+				err = client.Ping(context.Background())
+			}
+			if test.wantErr && err == nil {
+				t.Errorf("connecting: got no error")
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("error connecting: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// keyLocks serializes the read-check-write sequences of concurrent requests on
+// the same immudb keys: immudb has no conditional writes, so two requests could
+// otherwise both pass the checks on the values they read and both write
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock locks all given keys, always in the same order to avoid deadlocks, and
+// returns the func which unlocks them
+func (l *keyLocks) Lock(keys ...string) func() {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*keyLock)
+	}
+	locks := make([]*keyLock, 0, len(keys))
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		lock, ok := l.locks[key]
+		if !ok {
+			lock = &keyLock{}
+			l.locks[key] = lock
+		}
+		lock.refs++
+		locks = append(locks, lock)
+	}
+	l.mu.Unlock()
+
+	for _, lock := range locks {
+		lock.Lock()
+	}
+
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+		}
+		l.mu.Lock()
+		for i, key := range keys {
+			if i > 0 && key == keys[i-1] {
+				continue
+			}
+			lock := l.locks[key]
+			lock.refs--
+			if lock.refs == 0 {
+				delete(l.locks, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
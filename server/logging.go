@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// gRPC metadata key under which the request ID is sent to immudb
+	requestIDMetadataKey = "x-request-id"
+	requestIDMaxLen      = 128
+
+	redactedPII = "[REDACTED]"
+)
+
+// logger is the structured logger of the server; it is configured from the
+// config (see configureLogging) and redacts the PII in the logged fields
+var logger = newLogger()
+
+func newLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetFormatter(&logrus.JSONFormatter{})
+	l.AddHook(piiRedactionHook{})
+	return l
+}
+
+// configureLogging sets the format (json or text), the level and the output
+// (stderr, stdout or a file path) of the logger
+func configureLogging(format string, level string, output string) error {
+	switch format {
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unknown log format %q: it must be json or text", format)
+	}
+
+	logLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("unknown log level %q: %v", level, err)
+	}
+	logger.SetLevel(logLevel)
+
+	var out io.Writer
+	switch output {
+	case "stderr":
+		out = os.Stderr
+	case "stdout":
+		out = os.Stdout
+	default:
+		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return fmt.Errorf("error opening log file %s: %v", output, err)
+		}
+		out = file
+	}
+	logger.SetOutput(out)
+	return nil
+}
+
+type requestIDContextKey struct{}
+
+var requestIDRegexp = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+
+// withRequestID middleware: it assigns each request a correlation ID, the one
+// sent by the client in the X-Request-ID header (e.g. by a proxy) if valid, and
+// returns it in the same response header
+func withRequestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := validOrNewRequestID(r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), requestIDContextKey{}, requestID)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validOrNewRequestID returns the request ID sent by the client, if valid, else
+// a new one
+func validOrNewRequestID(requestID string) string {
+	if len(requestID) == 0 || len(requestID) > requestIDMaxLen || !requestIDRegexp.MatchString(requestID) {
+		var err error
+		if requestID, err = uuid(); err != nil {
+			logger.WithError(err).Error("error generating request ID")
+		}
+	}
+	return requestID
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// contextLogger returns the logger with the request ID of ctx (if any)
+func contextLogger(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logger)
+	if requestID := requestIDFromContext(ctx); len(requestID) > 0 {
+		entry = entry.WithField("request_id", requestID)
+	}
+	return entry
+}
+
+// requestLogger returns the logger with the request ID, method and path of r
+func requestLogger(r *http.Request) *logrus.Entry {
+	return contextLogger(r.Context()).WithFields(logrus.Fields{
+		"method": r.Method,
+		"path":   r.URL.Path,
+	})
+}
+
+var (
+	// the PII fields of the voters, as JSON (e.g. in a raw voter value or in an
+	// error about it), with their string values
+	piiJSONRegexp = regexp.MustCompile(`"(` +
+		strings.Join(piiJSONFields(reflect.TypeOf(RegisterVoterRequest{})), "|") +
+		`)"(\s*):(\s*)"(?:[^"\\]|\\.)*"`)
+	// the citizen IDs in the keys of the citizens (e.g. in an error about one)
+	citizenKeyRegexp = regexp.MustCompile(regexp.QuoteMeta(citizenPrefix) + `[^\s"]+`)
+)
+
+// piiRedactionHook redacts the fields tagged with `pii:"true"` (e.g. those of
+// RegisterVoterRequest) of the structs logged as fields, and the same fields of
+// the JSON and the citizen keys found in the message, the errors and the other
+// string and bytes fields (see redactPIIString), so that the logs can be
+// shipped anywhere without leaking the voters' identities
+type piiRedactionHook struct{}
+
+func (piiRedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (piiRedactionHook) Fire(entry *logrus.Entry) error {
+	entry.Message = redactPIIString(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case error:
+			if redacted := redactPIIString(v.Error()); redacted != v.Error() {
+				entry.Data[key] = errors.New(redacted)
+			}
+		case string:
+			entry.Data[key] = redactPIIString(v)
+		case []byte:
+			entry.Data[key] = redactPIIString(string(v))
+		case json.RawMessage:
+			entry.Data[key] = redactPIIString(string(v))
+		default:
+			entry.Data[key] = redactPII(value)
+		}
+	}
+	return nil
+}
+
+// redactPIIString redacts the values of the PII fields of the JSON in s and the
+// citizen IDs of the citizen keys
+func redactPIIString(s string) string {
+	s = piiJSONRegexp.ReplaceAllString(s, `"$1"$2:$3"`+redactedPII+`"`)
+	return citizenKeyRegexp.ReplaceAllString(s, citizenPrefix+redactedPII)
+}
+
+// redactPII returns the value as is if it has no PII fields, else a map of its
+// JSON representation with the PII fields redacted
+func redactPII(value interface{}) interface{} {
+	t := reflect.TypeOf(value)
+	if t == nil {
+		return value
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	piiFields := piiJSONFields(t)
+	if len(piiFields) == 0 {
+		return value
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return redactedPII
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(valueBytes, &fields); err != nil {
+		return redactedPII
+	}
+	for _, piiField := range piiFields {
+		if _, ok := fields[piiField]; ok {
+			fields[piiField] = redactedPII
+		}
+	}
+	return fields
+}
+
+// piiJSONFields returns the JSON names of the fields of the struct type tagged
+// with `pii:"true"`, including those of its embedded structs
+func piiJSONFields(t reflect.Type) []string {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			fields = append(fields, piiJSONFields(field.Type)...)
+			continue
+		}
+		if field.Tag.Get("pii") != "true" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(name) == 0 {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
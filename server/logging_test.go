@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogPIIRedaction(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	var logs bytes.Buffer
+	logger.SetOutput(&logs)
+	defer logger.SetOutput(ioutil.Discard)
+
+	pii := RegisterVoterRequest{
+		CitizenID: "CIT-987654",
+		Name:      "Zebulon Quixote",
+		Address:   "42 Wallaby Way",
+		Email:     "zq@example.org",
+		Precinct:  "north-1-a",
+	}
+	voterBytes, _ := json.Marshal(&Voter{RegisterVoterRequest: pii, RegistrationApproved: time.Now()})
+	const requestID = "test-request-1"
+	ctx := context.WithValue(context.Background(), requestIDContextKey{}, requestID)
+	contextLogger(ctx).WithField("request", &pii).Info("registering voter")
+	contextLogger(ctx).WithField("value", voterBytes).Warn("unexpected voter")
+	contextLogger(ctx).WithField("value", json.RawMessage(voterBytes)).Warn("unexpected voter")
+	contextLogger(ctx).WithError(fmt.Errorf("error persisting voter %s at key %s", voterBytes, citizenPrefix+pii.CitizenID)).
+		Errorf("error registering voter %s", voterBytes)
+
+	// and the registration itself, through the API
+	payload, _ := json.Marshal(&pii)
+	req, _ := http.NewRequest(http.MethodPost, httpServer.URL+apiV1Prefix+"/elections/"+electionID+"/voters",
+		bytes.NewReader(payload))
+	req.Header.Set(requestIDHeader, requestID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error registering voter: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(requestIDHeader) != requestID {
+		t.Fatalf("registering voter: got status %d, request ID %q, want %d and %q",
+			resp.StatusCode, resp.Header.Get(requestIDHeader), http.StatusOK, requestID)
+	}
+
+	output := logs.String()
+	for _, value := range []string{pii.CitizenID, pii.Name, pii.Address, pii.Email} {
+		if strings.Contains(output, value) {
+			t.Errorf("got %q in the logs:\n%s", value, output)
+		}
+	}
+	var lines int
+	scanner := bufio.NewScanner(&logs)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("error JSON-unmarshaling log line %s: %v", scanner.Text(), err)
+		}
+		if line["request_id"] != requestID {
+			t.Errorf("got log line %s, want request ID %s", scanner.Text(), requestID)
+		}
+		lines++
+	}
+	if lines < 5 || !strings.Contains(output, redactedPII) || !strings.Contains(output, pii.Precinct) {
+		t.Errorf("got logs:\n%s\nwant 5 lines or more, with the PII redacted and the rest kept", output)
+	}
+}
@@ -1,74 +1,234 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/signer"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const (
-	host      = "localhost"
-	port      = "8080"
-	adminUser = "admin"
-	adminPass = "admin"
-
 	// NikkiHaley candidate
 	NikkiHaley = 1
 	// KamalaHarris candidate
 	KamalaHarris = 2
 )
 
-var immudbClient = ImmudbClient{}
-
 func main() {
-	fmt.Println(
+	config, err := LoadConfig(os.Args[1:])
+	if errors.Is(err, pflag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if config.PrintConfig {
+		if err := config.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := configureLogging(config.LogFormat, config.LogLevel, config.LogOutput); err != nil {
+		log.Fatalf("error configuring logging: %v", err)
+	}
+
+	fmt.Print(
 		"    _                                       __  _\n" +
 			"   (_)___ ___  ____ ___  __  ___   ______  / /_(_)___  ____ _\n" +
 			"  / / __ `__ \\/ __ `__ \\/ / / / | / / __ \\/ __/ / __ \\/ __ `/\n" +
 			" / / / / / / / / / / / / /_/ /| |/ / /_/ / /_/ / / / / /_/ /\n" +
 			"/_/_/ /_/ /_/_/ /_/ /_/\\__,_/ |___/\\____/\\__/_/_/ /_/\\__, /\n" +
-			"e l e c t i o n s  a n y o n e  c a n  v e r i f y  \\____/\n")
+			"e l e c t i o n s  a n y o n e  c a n  v e r i f y  \\____/\n\n")
 	// fmt.Print("e l e c t i o n s   a n y o n e   c a n   v e r i f y\n\n\n")
 
-	// init immudb client
-	immudbClient.Init(&ImmudbConfig{
-		Address:       "localhost:3322",
-		DB:            "defaultdb",
-		User:          "immudb",
-		Password:      "immudb",
-		LocalStateDir: "",
-	})
-	if err := immudbClient.Connect(); err != nil {
-		log.Fatalf("error connecting to immudb: %v", err)
-	}
-	defer immudbClient.Disconnect()
+	if len(config.File) > 0 {
+		fmt.Println("using config file", config.File)
+	}
+	if config.Dev {
+		fmt.Println("WARNING: running in dev mode, the default credentials are allowed")
+	}
+
+	var store Store
+	var closeStore func() error
+	if config.Embedded {
+		// open embedded immudb database
+		embeddedStore, err := NewEmbeddedStore(config.DataDir, config.ImmudbDB, true)
+		if err != nil {
+			logger.Fatalf("error opening embedded immudb database: %v", err)
+		}
+		closeStore = embeddedStore.Close
+		fmt.Println("using embedded immudb database from", config.DataDir)
+		store = embeddedStore
+	} else {
+		// init immudb client
+		immudbClient := &ImmudbClient{}
+		immudbClient.Init(&ImmudbConfig{
+			Address:       config.ImmudbAddress,
+			DB:            config.ImmudbDB,
+			User:          config.ImmudbUser,
+			Password:      config.ImmudbPassword,
+			LocalStateDir: config.LocalStateDir,
+			TLS:           config.ImmudbTLS,
+			TLSCAFile:     config.ImmudbTLSCA,
+			TLSCertFile:   config.ImmudbTLSCert,
+			TLSKeyFile:    config.ImmudbTLSKey,
+			TLSServerName: config.ImmudbTLSServerName,
+		})
+		if err := immudbClient.Connect(); err != nil {
+			logger.Fatalf("error connecting to immudb: %v", err)
+		}
+		closeStore = immudbClient.Disconnect
+		store = immudbClient
+	}
 
 	// create immuvoting admin user
-	hashedPassword, err := HashAndSaltPassword("admin")
+	hashedPassword, err := HashAndSaltPassword(config.AdminPassword)
 	if err != nil {
-		log.Fatalf("error hashing and salting password: %v", err)
+		logger.Fatalf("error hashing and salting password: %v", err)
 	}
-	if err := immudbClient.Set(
-		[]byte("immuvoting:user:admin"), []byte(hashedPassword)); err != nil &&
+	if err := store.Set(context.Background(),
+		[]byte("immuvoting:user:"+config.AdminUser), []byte(hashedPassword)); err != nil &&
 		!errors.Is(err, ErrAlreadyExists) {
-		log.Fatalf("error creating admin user: %v", err)
+		logger.Fatalf("error creating admin user: %v", err)
+	}
+
+	// persist the election definition
+	if err := persistElection(context.Background(), store, &election); err != nil {
+		logger.Fatalf("error persisting election definition: %v", err)
+	}
+
+	var officials []Official
+	if len(config.OfficialsFile) > 0 {
+		if officials, err = LoadOfficials(config.OfficialsFile); err != nil {
+			logger.Fatalf("error loading officials: %v", err)
+		}
+		if config.CertificationQuorum > len(officials) {
+			logger.Fatalf("certification-quorum %d is more than the %d officials of %s",
+				config.CertificationQuorum, len(officials), config.OfficialsFile)
+		}
+	}
+	var stateSigner signer.Signer
+	if len(config.SigningKey) > 0 {
+		if stateSigner, err = signer.NewSigner(config.SigningKey); err != nil {
+			logger.Fatalf("error loading signing key from %s: %v", config.SigningKey, err)
+		}
+	}
+	server := NewServer(store, config.AdminUser, config.AdminPassword, officials, config.CertificationQuorum, stateSigner)
+	if err := server.LoadTally(context.Background()); err != nil {
+		logger.Fatalf("error loading tally: %v", err)
+	}
+	if err := server.LoadCertification(context.Background()); err != nil {
+		logger.Fatalf("error loading certification: %v", err)
+	}
+	// the background loops end when the server is drained, and must be done
+	// before the store is closed
+	var loops sync.WaitGroup
+	loops.Add(1)
+	go func() {
+		defer loops.Done()
+		server.CountTurnout(turnoutUpdateInterval)
+	}()
+	if config.TallyReconcileInterval > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			server.ReconcileTally(config.TallyReconcileInterval)
+		}()
+	}
+	httpServer := &http.Server{
+		Addr:    config.Addr(),
+		Handler: server.Handler(),
+	}
+	var grpcOpts []grpc.ServerOption
+	if len(config.TLSCert) > 0 {
+		certReloader, err := newCertReloader(config.TLSCert, config.TLSKey, config.TLSReloadInterval)
+		if err != nil {
+			logger.Fatalf("error loading TLS cert: %v", err)
+		}
+		httpServer.TLSConfig = &tls.Config{
+			GetCertificate: certReloader.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(httpServer.TLSConfig.Clone())))
+	}
+
+	// start gRPC server
+	var grpcServer *grpc.Server
+	if config.GRPCPort > 0 {
+		grpcListener, err := net.Listen("tcp", config.GRPCAddr())
+		if err != nil {
+			logger.Fatalf("error listening for gRPC: %v", err)
+		}
+		grpcServer = server.GRPCServer(grpcOpts...)
+		fmt.Println("gRPC listening on", config.GRPCAddr())
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				logger.Fatalf("error serving gRPC: %v", err)
+			}
+		}()
 	}
 
-	// setup HTTP handlers
-	http.HandleFunc("/register-voter", cors(registerVoterHandler))
-	http.HandleFunc("/vote", cors(voteHandler))
-	http.HandleFunc("/voter-status", cors(getVoterStatusHandler))
-	http.HandleFunc("/ballot", cors(getBallotHandler))
-	http.HandleFunc("/random-ballot", cors(getRandomBallotHandler))
-	http.HandleFunc("/state", cors(getStateHandler))
-	http.HandleFunc("/verifiable-tx", cors(getVerifiableTransactionHandler))
-	http.HandleFunc("/stats", cors(getStatsHandler))
-	// NOTE: to add a handler which requires auth, wrap the handler with corsAndBasicAuth(...)
-	fmt.Println("listening on port", port)
+	// shut down gracefully on SIGINT / SIGTERM: fail the readiness check, wait
+	// for the load balancers to notice, then stop accepting connections and
+	// wait for the in-flight requests (e.g. votes) to complete
+	shutdownDone := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		sig := <-signals
+		logger.WithField("signal", sig.String()).Info("shutting down")
+		server.Drain()
+		time.Sleep(config.ShutdownDelay)
+		ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+		if grpcServer != nil {
+			// the streams have been ended by Drain
+			grpcStopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(grpcStopped)
+			}()
+			select {
+			case <-grpcStopped:
+			case <-ctx.Done():
+				grpcServer.Stop()
+			}
+		}
+		if err := httpServer.Shutdown(ctx); err != nil {
+			logger.WithError(err).Error("error shutting down HTTP server")
+		}
+		close(shutdownDone)
+	}()
 
 	// start server
-	if err = http.ListenAndServe(host+":"+port, nil); err != nil {
-		log.Fatalf("error starting HTTP server: %v", err)
+	if httpServer.TLSConfig != nil {
+		fmt.Println("listening on", config.Addr(), "(HTTPS)")
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		fmt.Println("listening on", config.Addr())
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatalf("error starting HTTP server: %v", err)
+	}
+
+	<-shutdownDone
+	loops.Wait()
+	if err := closeStore(); err != nil {
+		logger.Fatalf("error closing immudb connection: %v", err)
 	}
+	logger.Info("shut down")
 }
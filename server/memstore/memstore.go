@@ -0,0 +1,548 @@
+// Package memstore is an in-memory immudb database, for tests: it commits txs
+// exactly as immudb does (entry hash trees, accumulative linear hashes and the
+// binary linking of the txs), so the proofs it returns are real ones and verify
+// with the immudb verification functions. It is deterministic: the same writes
+// always result in the same txs and hashes, as their timestamps are the ones of
+// a clock which only moves when a tx is committed.
+package memstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/ahtree"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+// Epoch is the timestamp of the first tx, unless set otherwise with SetTime
+var Epoch = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// maxKeyResolutionLimit is the max number of references resolved by a get, as
+// in immudb
+const maxKeyResolutionLimit = 1
+
+// version is the value of a key as set in a tx: the value is stored as immudb
+// stores it, i.e. prefixed with its type (plain value or reference)
+type version struct {
+	tx    uint64
+	value []byte
+}
+
+// Store is an in-memory immudb database; its methods have the same signatures
+// and semantics as those of the immuvoting Store, and the not found errors
+// wrap store.ErrKeyNotFound or store.ErrTxNotFound, as for the embedded immudb
+type Store struct {
+	mu     sync.Mutex
+	dbName string
+	// timestamp of the next tx
+	now time.Time
+	// txs[i] is the tx with ID i+1
+	txs []*store.Tx
+	aht *ahtree.AHtree
+	// versions of each (encoded) key, oldest first
+	keys map[string][]version
+	// encoded keys, sorted
+	sortedKeys []string
+}
+
+// New returns an empty database with the given name
+func New(dbName string) *Store {
+	aht, err := ahtree.OpenWith(&appendable{}, &appendable{}, &appendable{}, ahtree.DefaultOptions())
+	if err != nil {
+		// the in-memory appendables never fail
+		panic(fmt.Sprintf("error opening in-memory binary linking tree: %v", err))
+	}
+	return &Store{
+		dbName: dbName,
+		now:    Epoch,
+		aht:    aht,
+		keys:   make(map[string][]version),
+	}
+}
+
+// SetTime sets the timestamp of the next tx; each following tx is committed one
+// second after the previous one
+func (s *Store) SetTime(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = t
+}
+
+// Ping ...
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Database ...
+func (s *Store) Database() string {
+	return s.dbName
+}
+
+// Get returns the value of the key, as of the given tx (in which the key must
+// have been set) or the latest one if 0
+func (s *Store) Get(ctx context.Context, key []byte, txID uint64) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, err := s.getAt(database.EncodeKey(key), txID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return entry.GetValue(), nil
+}
+
+// GetLatest ...
+func (s *Store) GetLatest(ctx context.Context, key []byte) ([]byte, error) {
+	return s.Get(ctx, key, 0)
+}
+
+// GetLatestEntry ...
+func (s *Store) GetLatestEntry(ctx context.Context, key []byte) (*schema.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getAt(database.EncodeKey(key), 0, 0)
+}
+
+// VerifiedGet returns the entry as is, as the embedded immudb does
+func (s *Store) VerifiedGet(ctx context.Context, key []byte) (*schema.Entry, error) {
+	return s.GetLatestEntry(ctx, key)
+}
+
+// VerifiableGetAt returns the value of the key as of the given tx (the latest
+// one if 0), with the proof of its inclusion in its tx and the dual proof
+// between its tx and the proveSinceTx one
+func (s *Store) VerifiableGetAt(
+	ctx context.Context,
+	key []byte,
+	atTx uint64,
+	proveSinceTx uint64,
+) (*schema.VerifiableEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.getAt(database.EncodeKey(key), atTx, 0)
+	if err != nil {
+		return nil, err
+	}
+	vTxID, vKey := entry.GetTx(), entry.GetKey()
+	if entry.GetReferencedBy() != nil {
+		vTxID, vKey = entry.GetReferencedBy().GetTx(), entry.GetReferencedBy().GetKey()
+	}
+	tx, err := s.tx(vTxID)
+	if err != nil {
+		return nil, err
+	}
+	inclusionProof, err := tx.Proof(database.EncodeKey(vKey))
+	if err != nil {
+		return nil, err
+	}
+	verifiableTx, err := s.verifiableTx(vTxID, proveSinceTx)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.VerifiableEntry{
+		Entry:          entry,
+		VerifiableTx:   verifiableTx,
+		InclusionProof: schema.InclusionProofTo(inclusionProof),
+	}, nil
+}
+
+// Set ...
+func (s *Store) Set(ctx context.Context, key []byte, value []byte) error {
+	_, err := s.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: key, Value: value}}},
+	}})
+	return err
+}
+
+// ExecAll commits the key values and the (unbound) references in a single tx
+func (s *Store) ExecAll(ctx context.Context, ops *schema.ExecAllRequest) (uint64, error) {
+	if err := ops.Validate(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kvs := make([]*store.KV, 0, len(ops.GetOperations()))
+	setKeys := make(map[string]bool, len(ops.GetOperations()))
+	for _, op := range ops.GetOperations() {
+		switch x := op.GetOperation().(type) {
+		case *schema.Op_Kv:
+			if len(x.Kv.GetKey()) == 0 {
+				return 0, store.ErrIllegalArguments
+			}
+			kv := database.EncodeKV(x.Kv.GetKey(), x.Kv.GetValue())
+			setKeys[string(kv.Key)] = true
+			kvs = append(kvs, kv)
+		case *schema.Op_Ref:
+			ref := x.Ref
+			if len(ref.GetKey()) == 0 || len(ref.GetReferencedKey()) == 0 || ref.GetAtTx() > 0 || ref.GetBoundRef() {
+				return 0, store.ErrIllegalArguments
+			}
+			// the key must not exist or already be a reference, the referenced
+			// key must exist (possibly in this same tx) and not be a reference
+			if existing, err := s.getAt(database.EncodeKey(ref.GetKey()), 0, 0); err == nil &&
+				existing.GetReferencedBy() == nil {
+				return 0, database.ErrFinalKeyCannotBeConvertedIntoReference
+			}
+			refKey := database.EncodeKey(ref.GetReferencedKey())
+			if !setKeys[string(refKey)] {
+				referenced, err := s.getAt(refKey, 0, 0)
+				if err != nil {
+					return 0, err
+				}
+				if referenced.GetReferencedBy() != nil {
+					return 0, database.ErrReferencedKeyCannotBeAReference
+				}
+			}
+			kvs = append(kvs, database.EncodeReference(ref.GetKey(), ref.GetReferencedKey(), 0))
+		default:
+			return 0, fmt.Errorf("%w: operation %T is not supported", store.ErrIllegalArguments, x)
+		}
+	}
+	return s.commit(kvs)
+}
+
+// Scan returns up to limit entries with the given prefix, starting with the
+// seek key (inclusive), if any
+func (s *Store) Scan(
+	ctx context.Context,
+	prefix []byte,
+	limit uint64,
+	seekKey []byte,
+	desc bool,
+) ([]*schema.Entry, error) {
+	if limit > database.MaxKeyScanLimit {
+		return nil, database.ErrMaxKeyScanLimitExceeded
+	}
+	if limit == 0 {
+		limit = database.MaxKeyScanLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encPrefix := string(database.EncodeKey(prefix))
+	encSeekKey := string(database.EncodeKey(seekKey))
+	var entries []*schema.Entry
+	visit := func(key string) (bool, error) {
+		if len(seekKey) > 0 && ((!desc && key < encSeekKey) || (desc && key > encSeekKey)) {
+			return true, nil
+		}
+		versions := s.keys[key]
+		entry, err := s.getAt([]byte(key), versions[len(versions)-1].tx, 0)
+		if err != nil {
+			return false, err
+		}
+		entries = append(entries, entry)
+		return uint64(len(entries)) < limit, nil
+	}
+	start := sort.SearchStrings(s.sortedKeys, encPrefix)
+	end := start
+	for end < len(s.sortedKeys) && len(s.sortedKeys[end]) >= len(encPrefix) &&
+		s.sortedKeys[end][:len(encPrefix)] == encPrefix {
+		end++
+	}
+	for i := 0; i < end-start; i++ {
+		key := s.sortedKeys[start+i]
+		if desc {
+			key = s.sortedKeys[end-1-i]
+		}
+		more, err := visit(key)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// History returns up to limit values of the key, oldest first, skipping the
+// first offset ones; references are returned as is, without resolving them
+func (s *Store) History(ctx context.Context, key []byte, offset uint64, limit uint64) ([]*schema.Entry, error) {
+	if limit > database.MaxKeyScanLimit {
+		return nil, database.ErrMaxKeyScanLimitExceeded
+	}
+	if limit == 0 {
+		limit = database.MaxKeyScanLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, ok := s.keys[string(database.EncodeKey(key))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", store.ErrKeyNotFound, key)
+	}
+	entries := []*schema.Entry{}
+	for i := offset; i < uint64(len(versions)) && uint64(len(entries)) < limit; i++ {
+		entries = append(entries, &schema.Entry{
+			Key:   key,
+			Value: database.TrimPrefix(versions[i].value),
+			Tx:    versions[i].tx,
+		})
+	}
+	return entries, nil
+}
+
+// CurrentState ...
+func (s *Store) CurrentState(ctx context.Context) (*schema.ImmutableState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := &schema.ImmutableState{Db: s.dbName, TxHash: make([]byte, sha256.Size)}
+	alh := sha256.Sum256(nil)
+	if len(s.txs) > 0 {
+		lastTx := s.txs[len(s.txs)-1]
+		state.TxId, alh = lastTx.ID, lastTx.Alh
+	}
+	copy(state.TxHash, alh[:])
+	return state, nil
+}
+
+// VerifiableTXByID returns the server tx with the dual proof between it and
+// the local one
+func (s *Store) VerifiableTXByID(ctx context.Context, serverTX uint64, localTX uint64) (*schema.VerifiableTx, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.verifiableTx(serverTX, localTX)
+}
+
+// TxScan returns up to limit txs, in ascending order, starting with initialTX
+func (s *Store) TxScan(ctx context.Context, initialTX uint64, limit uint32) ([]*schema.Tx, error) {
+	if initialTX == 0 {
+		return nil, store.ErrIllegalArguments
+	}
+	if limit > database.MaxKeyScanLimit {
+		return nil, database.ErrMaxKeyScanLimitExceeded
+	}
+	if limit == 0 {
+		limit = database.MaxKeyScanLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txs := []*schema.Tx{}
+	for txID := initialTX; txID <= uint64(len(s.txs)) && len(txs) < int(limit); txID++ {
+		txs = append(txs, schema.TxTo(s.txs[txID-1]))
+	}
+	return txs, nil
+}
+
+// commit commits the key values in a new tx, linking it to the previous ones as
+// immudb does, and returns its ID
+func (s *Store) commit(kvs []*store.KV) (uint64, error) {
+	entries := make([]*store.TxEntry, len(kvs))
+	for i, kv := range kvs {
+		entries[i] = store.NewTxEntry(kv.Key, len(kv.Value), sha256.Sum256(kv.Value), 0)
+	}
+	tx := store.NewTxWithEntries(entries)
+	tx.ID = uint64(len(s.txs)) + 1
+	tx.Ts = s.now.Unix()
+	s.now = s.now.Add(time.Second)
+	// every previous tx is already linked, unlike in immudb, where the binary
+	// linking may lag behind
+	if blTxID, blRoot, err := s.aht.Root(); err == nil {
+		tx.BlTxID, tx.BlRoot = blTxID, blRoot
+	}
+	tx.PrevAlh = sha256.Sum256(nil)
+	if len(s.txs) > 0 {
+		tx.PrevAlh = s.txs[len(s.txs)-1].Alh
+	}
+	tx.BuildHashTree()
+	tx.CalcAlh()
+	if _, _, err := s.aht.Append(tx.Alh[:]); err != nil {
+		return 0, err
+	}
+	s.txs = append(s.txs, tx)
+
+	for _, kv := range kvs {
+		key := string(kv.Key)
+		if _, ok := s.keys[key]; !ok {
+			i := sort.SearchStrings(s.sortedKeys, key)
+			s.sortedKeys = append(s.sortedKeys, "")
+			copy(s.sortedKeys[i+1:], s.sortedKeys[i:])
+			s.sortedKeys[i] = key
+		}
+		s.keys[key] = append(s.keys[key], version{tx: tx.ID, value: kv.Value})
+	}
+	return tx.ID, nil
+}
+
+func (s *Store) tx(txID uint64) (*store.Tx, error) {
+	if txID == 0 || txID > uint64(len(s.txs)) {
+		return nil, fmt.Errorf("%w: %d", store.ErrTxNotFound, txID)
+	}
+	return s.txs[txID-1], nil
+}
+
+// getAt returns the entry of the (encoded) key as of the given tx, in which it
+// must have been set, or the latest one if 0, resolving references
+func (s *Store) getAt(key []byte, atTx uint64, resolved int) (*schema.Entry, error) {
+	versions := s.keys[string(key)]
+	var v *version
+	for i := len(versions) - 1; i >= 0; i-- {
+		if atTx == 0 || versions[i].tx == atTx {
+			v = &versions[i]
+			break
+		}
+	}
+	if v == nil {
+		return nil, fmt.Errorf("%w: %s", store.ErrKeyNotFound, database.TrimPrefix(key))
+	}
+
+	if v.value[0] == database.ReferenceValuePrefix {
+		if resolved == maxKeyResolutionLimit {
+			return nil, database.ErrMaxKeyResolutionLimitReached
+		}
+		refAtTx := binary.BigEndian.Uint64(v.value[1:])
+		entry, err := s.getAt(v.value[1+8:], refAtTx, resolved+1)
+		if err != nil {
+			return nil, err
+		}
+		entry.ReferencedBy = &schema.Reference{
+			Tx:   v.tx,
+			Key:  database.TrimPrefix(key),
+			AtTx: refAtTx,
+		}
+		return entry, nil
+	}
+	return &schema.Entry{
+		Key:   database.TrimPrefix(key),
+		Value: database.TrimPrefix(v.value),
+		Tx:    v.tx,
+	}, nil
+}
+
+// verifiableTx returns the tx with the dual proof between it and the root one,
+// in whichever order they are
+func (s *Store) verifiableTx(txID uint64, rootTxID uint64) (*schema.VerifiableTx, error) {
+	tx, err := s.tx(txID)
+	if err != nil {
+		return nil, err
+	}
+	rootTx := tx
+	if rootTxID > 0 {
+		if rootTx, err = s.tx(rootTxID); err != nil {
+			return nil, err
+		}
+	}
+	sourceTx, targetTx := rootTx, tx
+	if rootTxID > txID {
+		sourceTx, targetTx = tx, rootTx
+	}
+	dualProof, err := s.dualProof(sourceTx, targetTx)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.VerifiableTx{
+		Tx:        schema.TxTo(tx),
+		DualProof: schema.DualProofTo(dualProof),
+	}, nil
+}
+
+// dualProof is the immudb dual proof: the binary inclusion and consistency
+// proofs up to the tx the target tx is linked to, then the linear proof
+func (s *Store) dualProof(sourceTx, targetTx *store.Tx) (*store.DualProof, error) {
+	proof := &store.DualProof{
+		SourceTxMetadata: sourceTx.Metadata(),
+		TargetTxMetadata: targetTx.Metadata(),
+	}
+	var err error
+	if sourceTx.ID < targetTx.BlTxID {
+		if proof.InclusionProof, err = s.aht.InclusionProof(sourceTx.ID, targetTx.BlTxID); err != nil {
+			return nil, err
+		}
+	}
+	if sourceTx.BlTxID > 0 {
+		if proof.ConsistencyProof, err = s.aht.ConsistencyProof(sourceTx.BlTxID, targetTx.BlTxID); err != nil {
+			return nil, err
+		}
+	}
+	if targetTx.BlTxID > 0 {
+		proof.TargetBlTxAlh = s.txs[targetTx.BlTxID-1].Alh
+		if proof.LastInclusionProof, err = s.aht.InclusionProof(targetTx.BlTxID, targetTx.BlTxID); err != nil {
+			return nil, err
+		}
+	}
+
+	linearSourceTxID := sourceTx.ID
+	if targetTx.BlTxID > linearSourceTxID {
+		linearSourceTxID = targetTx.BlTxID
+	}
+	terms := [][sha256.Size]byte{s.txs[linearSourceTxID-1].Alh}
+	for txID := linearSourceTxID + 1; txID <= targetTx.ID; txID++ {
+		terms = append(terms, s.txs[txID-1].InnerHash)
+	}
+	proof.LinearProof = &store.LinearProof{
+		SourceTxID: linearSourceTxID,
+		TargetTxID: targetTx.ID,
+		Terms:      terms,
+	}
+	return proof, nil
+}
+
+// appendable is an in-memory immudb appendable, backing the binary linking tree
+type appendable struct {
+	data   []byte
+	offset int64
+}
+
+func (a *appendable) Metadata() []byte {
+	return nil
+}
+
+func (a *appendable) Size() (int64, error) {
+	return int64(len(a.data)), nil
+}
+
+func (a *appendable) Offset() int64 {
+	return a.offset
+}
+
+func (a *appendable) SetOffset(off int64) error {
+	a.data = a.data[:off]
+	a.offset = off
+	return nil
+}
+
+func (a *appendable) Append(bs []byte) (int64, int, error) {
+	off := a.offset
+	a.data = append(a.data, bs...)
+	a.offset += int64(len(bs))
+	return off, len(bs), nil
+}
+
+func (a *appendable) Flush() error {
+	return nil
+}
+
+func (a *appendable) Sync() error {
+	return nil
+}
+
+func (a *appendable) ReadAt(bs []byte, off int64) (int, error) {
+	if off >= int64(len(a.data)) {
+		return 0, io.EOF
+	}
+	n := copy(bs, a.data[off:])
+	if n < len(bs) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+func (a *appendable) Close() error {
+	return nil
+}
+
+func (a *appendable) Copy(dstPath string) error {
+	return errors.New("in-memory appendables can not be copied")
+}
@@ -0,0 +1,107 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/htree"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+func TestDualProofs(t *testing.T) {
+	ctx := context.Background()
+	s := New("defaultdb")
+	const txs = 20
+	for i := 1; i <= txs; i++ {
+		if err := s.Set(ctx, []byte(fmt.Sprintf("key-%d", i%7)), []byte(fmt.Sprintf("value-%d", i))); err != nil {
+			t.Fatalf("error setting tx %d: %v", i, err)
+		}
+	}
+	state, err := s.CurrentState(ctx)
+	if err != nil || state.GetTxId() != txs {
+		t.Fatalf("got state %+v, %v, want tx %d", state, err, txs)
+	}
+
+	alh := func(txID uint64) [32]byte {
+		vTx, err := s.VerifiableTXByID(ctx, txID, txID)
+		if err != nil {
+			t.Fatalf("error fetching tx %d: %v", txID, err)
+		}
+		return schema.TxFrom(vTx.GetTx()).Alh
+	}
+	for source := uint64(1); source <= txs; source++ {
+		for target := source; target <= txs; target++ {
+			vTx, err := s.VerifiableTXByID(ctx, target, source)
+			if err != nil {
+				t.Fatalf("error proving tx %d since tx %d: %v", target, source, err)
+			}
+			if !store.VerifyDualProof(schema.DualProofFrom(vTx.GetDualProof()), source, target, alh(source), alh(target)) {
+				t.Errorf("dual proof of tx %d since tx %d does not verify", target, source)
+			}
+		}
+	}
+
+	// the latest value of key-3 has been set in tx 17
+	verifiableEntry, err := s.VerifiableGetAt(ctx, []byte("key-3"), 0, 5)
+	if err != nil {
+		t.Fatalf("error fetching verifiable key: %v", err)
+	}
+	if string(verifiableEntry.GetEntry().GetValue()) != "value-17" || verifiableEntry.GetEntry().GetTx() != 17 {
+		t.Fatalf("got entry %+v, want value-17 at tx 17", verifiableEntry.GetEntry())
+	}
+	eh := schema.DigestFrom(verifiableEntry.GetVerifiableTx().GetTx().GetMetadata().GetEH())
+	digest := database.EncodeKV([]byte("key-3"), []byte("value-17")).Digest()
+	if !htree.VerifyInclusion(schema.InclusionProofFrom(verifiableEntry.GetInclusionProof()), digest, eh) {
+		t.Errorf("inclusion proof of key-3 does not verify")
+	}
+	if _, err := s.TxScan(ctx, txs+1, 0); err != nil {
+		t.Errorf("scanning txs after the last one: got %v, want no error", err)
+	}
+	if _, err := s.VerifiableTXByID(ctx, txs+1, 1); !errors.Is(err, store.ErrTxNotFound) {
+		t.Errorf("proving unknown tx: got %v, want %v", err, store.ErrTxNotFound)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	ctx := context.Background()
+	s := New("defaultdb")
+	if _, err := s.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{
+		{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: []byte("voter:1"), Value: []byte("v1")}}},
+		{Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{Key: []byte("citizen:a"), ReferencedKey: []byte("voter:1")}}},
+	}}); err != nil {
+		t.Fatalf("error setting key and reference: %v", err)
+	}
+	if err := s.Set(ctx, []byte("voter:1"), []byte("v2")); err != nil {
+		t.Fatalf("error updating key: %v", err)
+	}
+
+	entry, err := s.GetLatestEntry(ctx, []byte("citizen:a"))
+	if err != nil {
+		t.Fatalf("error getting reference: %v", err)
+	}
+	if string(entry.GetValue()) != "v2" || string(entry.GetKey()) != "voter:1" ||
+		entry.GetReferencedBy().GetTx() != 1 || string(entry.GetReferencedBy().GetKey()) != "citizen:a" {
+		t.Errorf("got entry %+v, want the latest value of voter:1, referenced by citizen:a", entry)
+	}
+
+	entries, err := s.Scan(ctx, []byte("citizen:"), 0, nil, false)
+	if err != nil || len(entries) != 1 || string(entries[0].GetValue()) != "v2" {
+		t.Errorf("got scanned entries %+v, %v, want the resolved reference", entries, err)
+	}
+	history, err := s.History(ctx, []byte("voter:1"), 0, 0)
+	if err != nil || len(history) != 2 || string(history[0].GetValue()) != "v1" || history[1].GetTx() != 2 {
+		t.Errorf("got history %+v, %v, want v1 then v2", history, err)
+	}
+	if _, err := s.Get(ctx, []byte("voter:1"), 3); !errors.Is(err, store.ErrKeyNotFound) {
+		t.Errorf("getting key at a tx which did not set it: got %v, want %v", err, store.ErrKeyNotFound)
+	}
+	if _, err := s.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{
+		{Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{Key: []byte("voter:1"), ReferencedKey: []byte("voter:2")}}},
+	}}); err == nil {
+		t.Errorf("referencing a missing key: got no error")
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "immuvoting"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+	grpcRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Latency of the gRPC calls (the duration of the streams) by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	immudbRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "immudb_request_duration_seconds",
+		Help:      "Latency of the immudb calls by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+	immudbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "immudb_errors_total",
+		Help:      "Failed immudb calls by operation (key not found is not an error).",
+	}, []string{"operation"})
+	immudbReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "immudb_reconnects_total",
+		Help:      "Reconnections to immudb after the session token expired, by result.",
+	}, []string{"result"})
+	registrations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "registrations_total",
+		Help:      "Voters registered by this instance.",
+	})
+	votesCast = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "votes_cast_total",
+		Help:      "Ballots cast through this instance.",
+	})
+	tallyReconciliations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "tally_reconciliations_total",
+		Help:      "Verifications of the tally against a full scan, by result (ok, mismatch, skipped or error).",
+	}, []string{"result"})
+)
+
+// metricsRegistry returns the registry of the metrics exposed on /metrics
+func (s *Server) metricsRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		grpcRequests,
+		grpcRequestDuration,
+		immudbRequestDuration,
+		immudbErrors,
+		immudbReconnects,
+		registrations,
+		votesCast,
+		tallyReconciliations,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "immudb_tx_id",
+			Help:      "ID of the current (latest) immudb tx; NaN if it can not be fetched.",
+		}, func() float64 {
+			state, err := s.store.CurrentState(context.Background())
+			if err != nil {
+				return math.NaN()
+			}
+			return float64(state.GetTxId())
+		}),
+	)
+	return registry
+}
+
+// instrument counts the requests of the route and measures their latency
+func instrument(route string, handler http.HandlerFunc) http.HandlerFunc {
+	labels := prometheus.Labels{"route": route}
+	return promhttp.InstrumentHandlerCounter(
+		httpRequests.MustCurryWith(labels),
+		promhttp.InstrumentHandlerDuration(
+			httpRequestDuration.MustCurryWith(labels),
+			handler)).ServeHTTP
+}
+
+// instrumentedStore measures the latency and counts (and logs) the errors of
+// the store calls, by operation
+type instrumentedStore struct {
+	Store
+}
+
+func observe(ctx context.Context, operation string, started time.Time, err error) {
+	immudbRequestDuration.WithLabelValues(operation).Observe(time.Since(started).Seconds())
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		immudbErrors.WithLabelValues(operation).Inc()
+		contextLogger(ctx).WithError(err).WithField("operation", operation).Warn("immudb call failed")
+	}
+}
+
+func (s instrumentedStore) Ping(ctx context.Context) (err error) {
+	defer func(started time.Time) { observe(ctx, "Ping", started, err) }(time.Now())
+	return s.Store.Ping(ctx)
+}
+
+func (s instrumentedStore) Get(ctx context.Context, key []byte, txID uint64) (value []byte, err error) {
+	defer func(started time.Time) { observe(ctx, "Get", started, err) }(time.Now())
+	return s.Store.Get(ctx, key, txID)
+}
+
+func (s instrumentedStore) GetLatest(ctx context.Context, key []byte) (value []byte, err error) {
+	defer func(started time.Time) { observe(ctx, "GetLatest", started, err) }(time.Now())
+	return s.Store.GetLatest(ctx, key)
+}
+
+func (s instrumentedStore) GetLatestEntry(ctx context.Context, key []byte) (entry *schema.Entry, err error) {
+	defer func(started time.Time) { observe(ctx, "GetLatestEntry", started, err) }(time.Now())
+	return s.Store.GetLatestEntry(ctx, key)
+}
+
+func (s instrumentedStore) VerifiedGet(ctx context.Context, key []byte) (entry *schema.Entry, err error) {
+	defer func(started time.Time) { observe(ctx, "VerifiedGet", started, err) }(time.Now())
+	return s.Store.VerifiedGet(ctx, key)
+}
+
+func (s instrumentedStore) VerifiableGetAt(
+	ctx context.Context,
+	key []byte,
+	atTx uint64,
+	proveSinceTx uint64,
+) (entry *schema.VerifiableEntry, err error) {
+	defer func(started time.Time) { observe(ctx, "VerifiableGetAt", started, err) }(time.Now())
+	return s.Store.VerifiableGetAt(ctx, key, atTx, proveSinceTx)
+}
+
+func (s instrumentedStore) Set(ctx context.Context, key []byte, value []byte) (err error) {
+	defer func(started time.Time) { observe(ctx, "Set", started, err) }(time.Now())
+	return s.Store.Set(ctx, key, value)
+}
+
+func (s instrumentedStore) ExecAll(ctx context.Context, ops *schema.ExecAllRequest) (txID uint64, err error) {
+	defer func(started time.Time) { observe(ctx, "ExecAll", started, err) }(time.Now())
+	return s.Store.ExecAll(ctx, ops)
+}
+
+func (s instrumentedStore) Scan(
+	ctx context.Context,
+	prefix []byte,
+	limit uint64,
+	seekKey []byte,
+	desc bool,
+) (entries []*schema.Entry, err error) {
+	defer func(started time.Time) { observe(ctx, "Scan", started, err) }(time.Now())
+	return s.Store.Scan(ctx, prefix, limit, seekKey, desc)
+}
+
+func (s instrumentedStore) History(ctx context.Context, key []byte, offset uint64, limit uint64) (entries []*schema.Entry, err error) {
+	defer func(started time.Time) { observe(ctx, "History", started, err) }(time.Now())
+	return s.Store.History(ctx, key, offset, limit)
+}
+
+func (s instrumentedStore) CurrentState(ctx context.Context) (state *schema.ImmutableState, err error) {
+	defer func(started time.Time) { observe(ctx, "CurrentState", started, err) }(time.Now())
+	return s.Store.CurrentState(ctx)
+}
+
+func (s instrumentedStore) VerifiableTXByID(
+	ctx context.Context,
+	serverTX uint64,
+	localTX uint64,
+) (vTX *schema.VerifiableTx, err error) {
+	defer func(started time.Time) { observe(ctx, "VerifiableTXByID", started, err) }(time.Now())
+	return s.Store.VerifiableTXByID(ctx, serverTX, localTX)
+}
+
+func (s instrumentedStore) TxScan(ctx context.Context, initialTX uint64, limit uint32) (txs []*schema.Tx, err error) {
+	defer func(started time.Time) { observe(ctx, "TxScan", started, err) }(time.Now())
+	return s.Store.TxScan(ctx, initialTX, limit)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// The NIST Election Results Reporting Common Data Format (NIST SP 1500-100,
+// version 2, JSON): the election is a general election with a single candidate
+// contest, reported at the precinct level, and its geographic units are the
+// reporting units (GpUnit), with the counts of each of them
+const (
+	cdfFormat       = "precinct-level"
+	cdfElectionType = "general"
+	cdfCountTotal   = "total"
+	cdfLanguage     = "en"
+	cdfIssuer       = "immuvoting"
+	// the type of the external identifiers of the election holding the immudb
+	// tx of the results and its hash
+	cdfTXIDType   = "immudb-tx-id"
+	cdfTXHashType = "immudb-tx-hash"
+)
+
+// reporting unit types of the levels of the units; the regions have none
+var cdfUnitTypes = map[string]string{
+	unitLevelDistrict: "district",
+	unitLevelPrecinct: "precinct",
+}
+
+type cdfElectionReport struct {
+	Type                string             `json:"@type"`
+	Election            []cdfElection      `json:"Election"`
+	Format              string             `json:"Format"`
+	GeneratedDate       string             `json:"GeneratedDate"`
+	GpUnit              []cdfReportingUnit `json:"GpUnit"`
+	Issuer              string             `json:"Issuer"`
+	IssuerAbbreviation  string             `json:"IssuerAbbreviation"`
+	SequenceStart       int                `json:"SequenceStart"`
+	SequenceEnd         int                `json:"SequenceEnd"`
+	Status              string             `json:"Status"`
+	VendorApplicationID string             `json:"VendorApplicationId"`
+}
+
+type cdfElection struct {
+	Type               string                  `json:"@type"`
+	BallotCounts       []cdfBallotCounts       `json:"BallotCounts"`
+	Candidate          []cdfCandidate          `json:"Candidate"`
+	Contest            []cdfCandidateContest   `json:"Contest"`
+	ElectionScopeID    string                  `json:"ElectionScopeId"`
+	EndDate            string                  `json:"EndDate"`
+	ExternalIdentifier []cdfExternalIdentifier `json:"ExternalIdentifier"`
+	Name               cdfText                 `json:"Name"`
+	StartDate          string                  `json:"StartDate"`
+	ElectionType       string                  `json:"Type"`
+}
+
+type cdfExternalIdentifier struct {
+	Type           string `json:"@type"`
+	OtherType      string `json:"OtherType"`
+	IdentifierType string `json:"Type"`
+	Value          string `json:"Value"`
+}
+
+type cdfReportingUnit struct {
+	ID                 string   `json:"@id"`
+	Type               string   `json:"@type"`
+	ComposingGpUnitIDs []string `json:"ComposingGpUnitIds,omitempty"`
+	Name               cdfText  `json:"Name"`
+	OtherType          string   `json:"OtherType,omitempty"`
+	UnitType           string   `json:"Type"`
+	VotersParticipated uint64   `json:"VotersParticipated"`
+	VotersRegistered   uint64   `json:"VotersRegistered"`
+}
+
+type cdfCandidate struct {
+	ID         string  `json:"@id"`
+	Type       string  `json:"@type"`
+	BallotName cdfText `json:"BallotName"`
+}
+
+type cdfCandidateContest struct {
+	ID                 string                  `json:"@id"`
+	Type               string                  `json:"@type"`
+	ContestSelection   []cdfCandidateSelection `json:"ContestSelection"`
+	ElectionDistrictID string                  `json:"ElectionDistrictId"`
+	Name               string                  `json:"Name"`
+	VotesAllowed       int                     `json:"VotesAllowed"`
+}
+
+type cdfCandidateSelection struct {
+	ID           string          `json:"@id"`
+	Type         string          `json:"@type"`
+	CandidateIDs []string        `json:"CandidateIds"`
+	VoteCounts   []cdfVoteCounts `json:"VoteCounts"`
+}
+
+type cdfVoteCounts struct {
+	Type          string `json:"@type"`
+	Count         uint64 `json:"Count"`
+	CountItemType string `json:"CountItemType"`
+	GpUnitID      string `json:"GpUnitId"`
+}
+
+type cdfBallotCounts struct {
+	Type          string `json:"@type"`
+	BallotsCast   uint64 `json:"BallotsCast"`
+	CountItemType string `json:"Type"`
+	GpUnitID      string `json:"GpUnitId"`
+}
+
+type cdfText struct {
+	Type string            `json:"@type"`
+	Text []cdfLanguageText `json:"Text"`
+}
+
+type cdfLanguageText struct {
+	Type     string `json:"@type"`
+	Content  string `json:"Content"`
+	Language string `json:"Language"`
+}
+
+func newCDFText(content string) cdfText {
+	return cdfText{
+		Type: "ElectionResults.InternationalizedText",
+		Text: []cdfLanguageText{{
+			Type:     "ElectionResults.LanguageString",
+			Content:  content,
+			Language: cdfLanguage,
+		}},
+	}
+}
+
+func cdfUnitID(id string) string {
+	return "gpu-" + id
+}
+
+func cdfCandidateID(id uint16) string {
+	return "cand-" + strconv.FormatUint(uint64(id), 10)
+}
+
+// nistCDFElectionReport converts the results to a NIST CDF election report
+func nistCDFElectionReport(export *ResultsExport) *cdfElectionReport {
+	date := export.Generated
+	if export.Closed != nil {
+		date = *export.Closed
+	}
+	scopeID := cdfUnitID(export.ElectionID)
+	report := cdfElectionReport{
+		Type:                "ElectionResults.ElectionReport",
+		Format:              cdfFormat,
+		GeneratedDate:       export.Generated.Format(time.RFC3339),
+		Issuer:              cdfIssuer,
+		IssuerAbbreviation:  cdfIssuer,
+		SequenceStart:       1,
+		SequenceEnd:         1,
+		Status:              export.Status,
+		VendorApplicationID: cdfIssuer,
+	}
+	electionReport := cdfElection{
+		Type:            "ElectionResults.Election",
+		ElectionScopeID: scopeID,
+		StartDate:       date.Format("2006-01-02"),
+		EndDate:         date.Format("2006-01-02"),
+		ExternalIdentifier: []cdfExternalIdentifier{
+			{
+				Type:           "ElectionResults.ExternalIdentifier",
+				IdentifierType: "other",
+				OtherType:      cdfTXIDType,
+				Value:          strconv.FormatUint(export.TXID, 10),
+			},
+			{
+				Type:           "ElectionResults.ExternalIdentifier",
+				IdentifierType: "other",
+				OtherType:      cdfTXHashType,
+				Value:          hex.EncodeToString(export.TXHash),
+			},
+		},
+		Name:         newCDFText(export.ElectionName),
+		ElectionType: cdfElectionType,
+	}
+	contest := cdfCandidateContest{
+		ID:                 "contest-" + export.ElectionID,
+		Type:               "ElectionResults.CandidateContest",
+		ElectionDistrictID: scopeID,
+		Name:               export.ElectionName,
+		VotesAllowed:       1,
+	}
+	selections := make(map[uint16]*cdfCandidateSelection, len(export.Candidates))
+	for _, candidate := range export.Candidates {
+		electionReport.Candidate = append(electionReport.Candidate, cdfCandidate{
+			ID:         cdfCandidateID(candidate.ID),
+			Type:       "ElectionResults.Candidate",
+			BallotName: newCDFText(candidate.Name),
+		})
+		contest.ContestSelection = append(contest.ContestSelection, cdfCandidateSelection{
+			ID:           "cs-" + strconv.FormatUint(uint64(candidate.ID), 10),
+			Type:         "ElectionResults.CandidateSelection",
+			CandidateIDs: []string{cdfCandidateID(candidate.ID)},
+		})
+	}
+	for i, candidate := range export.Candidates {
+		selections[candidate.ID] = &contest.ContestSelection[i]
+	}
+
+	// addCounts adds the vote and the ballot counts of the unit
+	addCounts := func(gpUnitID string, counts *Counts) {
+		for _, candidate := range export.Candidates {
+			selection := selections[candidate.ID]
+			selection.VoteCounts = append(selection.VoteCounts, cdfVoteCounts{
+				Type:          "ElectionResults.VoteCounts",
+				Count:         counts.Results[candidate.ID],
+				CountItemType: cdfCountTotal,
+				GpUnitID:      gpUnitID,
+			})
+		}
+		electionReport.BallotCounts = append(electionReport.BallotCounts, cdfBallotCounts{
+			Type:          "ElectionResults.BallotCounts",
+			BallotsCast:   counts.Ballots,
+			CountItemType: cdfCountTotal,
+			GpUnitID:      gpUnitID,
+		})
+	}
+
+	scope := cdfReportingUnit{
+		ID:                 scopeID,
+		Type:               "ElectionResults.ReportingUnit",
+		Name:               newCDFText(export.ElectionName),
+		UnitType:           "other",
+		OtherType:          "election",
+		VotersParticipated: export.Voted,
+		VotersRegistered:   export.Registered,
+	}
+	for _, region := range export.Units {
+		scope.ComposingGpUnitIDs = append(scope.ComposingGpUnitIDs, cdfUnitID(region.ID))
+	}
+	report.GpUnit = append(report.GpUnit, scope)
+	addCounts(scopeID, &export.Counts)
+
+	var addUnits func(units []UnitStats)
+	addUnits = func(units []UnitStats) {
+		for i := range units {
+			unit := &units[i]
+			reportingUnit := cdfReportingUnit{
+				ID:                 cdfUnitID(unit.ID),
+				Type:               "ElectionResults.ReportingUnit",
+				Name:               newCDFText(unit.Name),
+				UnitType:           cdfUnitTypes[unit.Level],
+				VotersParticipated: unit.Voted,
+				VotersRegistered:   unit.Registered,
+			}
+			if len(reportingUnit.UnitType) == 0 {
+				reportingUnit.UnitType, reportingUnit.OtherType = "other", unit.Level
+			}
+			for _, subUnit := range unit.Units {
+				reportingUnit.ComposingGpUnitIDs = append(reportingUnit.ComposingGpUnitIDs, cdfUnitID(subUnit.ID))
+			}
+			report.GpUnit = append(report.GpUnit, reportingUnit)
+			addCounts(reportingUnit.ID, &unit.Counts)
+			addUnits(unit.Units)
+		}
+	}
+	addUnits(export.Units)
+
+	electionReport.Contest = []cdfCandidateContest{contest}
+	report.Election = []cdfElection{electionReport}
+	return &report
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openAPIVersion = "3.0.3"
+
+var pathParamRegexp = regexp.MustCompile(`{([a-z_]+)}`)
+
+// openAPIDocument generates the OpenAPI 3 document of the routes, describing
+// their request and response bodies from the Go types (by reflection, the way
+// encoding/json marshals them)
+func openAPIDocument(routes []apiRoute) map[string]interface{} {
+	g := schemaGenerator{schemas: map[string]interface{}{}, types: map[string]reflect.Type{}}
+	errorResponse := map[string]interface{}{
+		"description": "Error (see the `code` for the cause)",
+		"content":     jsonContent(g.schema(reflect.TypeOf(ErrorResponse{}))),
+	}
+
+	paths := map[string]interface{}{}
+	for _, route := range routes {
+		operation := map[string]interface{}{
+			"summary":     route.summary,
+			"operationId": operationID(route),
+		}
+		if route.admin {
+			operation["tags"] = []string{"admin"}
+			operation["security"] = []map[string][]string{{"basicAuth": {}}}
+		} else {
+			operation["tags"] = []string{"public"}
+		}
+
+		var params []interface{}
+		for _, match := range pathParamRegexp.FindAllStringSubmatch(route.path, -1) {
+			param := apiPathParams[match[1]]
+			param.name, param.required = match[1], true
+			params = append(params, openAPIParam(param, "path"))
+		}
+		for _, param := range route.query {
+			params = append(params, openAPIParam(param, "query"))
+		}
+		if route.idempotent {
+			params = append(params, map[string]interface{}{
+				"name":        idempotencyHeader,
+				"in":          "header",
+				"description": "key of the request, so that its retries replay its response",
+				"schema":      map[string]interface{}{"type": "string", "maxLength": idempotencyMaxKeyLen},
+			})
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if route.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(g.schema(reflect.TypeOf(route.request))),
+			}
+		}
+		responses := map[string]interface{}{"default": errorResponse}
+		if len(route.events) > 0 {
+			var eventSchemas []interface{}
+			for _, e := range route.events {
+				eventSchemas = append(eventSchemas, g.schema(reflect.TypeOf(e)))
+			}
+			responses[strconv.Itoa(http.StatusOK)] = map[string]interface{}{
+				"description": "Stream of Server-Sent Events, whose data is one of these JSON objects",
+				"content": map[string]interface{}{
+					"text/event-stream": map[string]interface{}{
+						"schema": map[string]interface{}{"oneOf": eventSchemas},
+					},
+				},
+			}
+		} else if route.response != nil {
+			responses[strconv.Itoa(http.StatusOK)] = map[string]interface{}{
+				"description": "OK",
+				"content":     jsonContent(g.schema(reflect.TypeOf(route.response))),
+			}
+		} else {
+			responses[strconv.Itoa(http.StatusNoContent)] = map[string]interface{}{"description": "No Content"}
+		}
+		operation["responses"] = responses
+
+		routePath := apiV1Prefix + route.path
+		pathItem, ok := paths[routePath].(map[string]interface{})
+		if !ok {
+			pathItem = map[string]interface{}{}
+			paths[routePath] = pathItem
+		}
+		pathItem[strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":       "immuvoting API",
+			"version":     "1.0.0",
+			"description": "Voter registration, voting and auditing backed by immudb. Errors are described in the README.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"basicAuth": map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
+
+// operationID returns e.g. getElectionsElectionIdBallotsBallotId
+func operationID(route apiRoute) string {
+	id := strings.ToLower(route.method)
+	for _, part := range strings.FieldsFunc(route.path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '_'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+func openAPIParam(param apiParam, in string) map[string]interface{} {
+	paramSchema := map[string]interface{}{"type": "string"}
+	if param.integer {
+		paramSchema = map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}
+	}
+	return map[string]interface{}{
+		"name":        param.name,
+		"in":          in,
+		"description": param.description,
+		"required":    param.required,
+		"schema":      paramSchema,
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// schemaGenerator generates the JSON schemas of the Go types; named struct
+// types are added to the components of the document and referenced
+type schemaGenerator struct {
+	schemas map[string]interface{}
+	types   map[string]reflect.Type
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + g.component(t)}
+	}
+	// e.g. the interfaces of the protobuf oneofs: any value
+	return map[string]interface{}{}
+}
+
+// component adds the schema of the named struct type to the components, once,
+// and returns its name
+func (g *schemaGenerator) component(t reflect.Type) string {
+	name := t.Name()
+	if other, ok := g.types[name]; ok && other != t {
+		// e.g. a type named like one of the immudb schema types
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	if _, ok := g.types[name]; ok {
+		return name
+	}
+	g.types[name] = t
+	// set before generating the fields, for the recursive types
+	g.schemas[name] = map[string]interface{}{}
+	g.schemas[name] = g.structSchema(t)
+	return name
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	g.addFields(t, properties, &required)
+	structSchema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		structSchema["required"] = required
+	}
+	return structSchema
+}
+
+// addFields adds the fields of the struct as encoding/json marshals them, with
+// the fields of the embedded structs promoted
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagParts := strings.Split(tag, ",")
+		name := tagParts[0]
+		if field.Anonymous && len(name) == 0 {
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				g.addFields(fieldType, properties, required)
+				continue
+			}
+		}
+		if len(field.PkgPath) > 0 {
+			// unexported
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		omitEmpty := false
+		for _, option := range tagParts[1:] {
+			omitEmpty = omitEmpty || option == "omitempty"
+		}
+		if !omitEmpty {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// openAPIHandler serves the OpenAPI document
+func openAPIHandler(document map[string]interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(r, w, http.StatusOK, document)
+	}
+}
@@ -0,0 +1,396 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	schema "github.com/codenotary/immudb/pkg/api/schema"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+// ImmuvotingClient is the client API for Immuvoting service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImmuvotingClient interface {
+	// Registers a voter and issues their ballot
+	RegisterVoter(ctx context.Context, in *RegisterVoterRequest, opts ...grpc.CallOption) (*RegisterVoterResponse, error)
+	// Casts a ballot
+	Vote(ctx context.Context, in *VoteRequest, opts ...grpc.CallOption) (*VoteResponse, error)
+	// Returns the registration status of a voter
+	GetVoterStatus(ctx context.Context, in *GetVoterStatusRequest, opts ...grpc.CallOption) (*VoterStatus, error)
+	// Returns a ballot
+	GetBallot(ctx context.Context, in *GetBallotRequest, opts ...grpc.CallOption) (*Ballot, error)
+	// Returns the current state of the database
+	GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*State, error)
+	// Returns a tx with the proof of its consistency with a previously verified tx
+	GetVerifiableTx(ctx context.Context, in *GetVerifiableTxRequest, opts ...grpc.CallOption) (*schema.VerifiableTx, error)
+	// Returns the registration and voting stats
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error)
+	// Streams the current state, then each new state (checkpoint) as txs are
+	// committed; a slow client only gets the latest one
+	WatchCheckpoints(ctx context.Context, in *WatchCheckpointsRequest, opts ...grpc.CallOption) (Immuvoting_WatchCheckpointsClient, error)
+}
+
+type immuvotingClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImmuvotingClient(cc grpc.ClientConnInterface) ImmuvotingClient {
+	return &immuvotingClient{cc}
+}
+
+func (c *immuvotingClient) RegisterVoter(ctx context.Context, in *RegisterVoterRequest, opts ...grpc.CallOption) (*RegisterVoterResponse, error) {
+	out := new(RegisterVoterResponse)
+	err := c.cc.Invoke(ctx, "/immuvoting.v1.Immuvoting/RegisterVoter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *immuvotingClient) Vote(ctx context.Context, in *VoteRequest, opts ...grpc.CallOption) (*VoteResponse, error) {
+	out := new(VoteResponse)
+	err := c.cc.Invoke(ctx, "/immuvoting.v1.Immuvoting/Vote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *immuvotingClient) GetVoterStatus(ctx context.Context, in *GetVoterStatusRequest, opts ...grpc.CallOption) (*VoterStatus, error) {
+	out := new(VoterStatus)
+	err := c.cc.Invoke(ctx, "/immuvoting.v1.Immuvoting/GetVoterStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *immuvotingClient) GetBallot(ctx context.Context, in *GetBallotRequest, opts ...grpc.CallOption) (*Ballot, error) {
+	out := new(Ballot)
+	err := c.cc.Invoke(ctx, "/immuvoting.v1.Immuvoting/GetBallot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *immuvotingClient) GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*State, error) {
+	out := new(State)
+	err := c.cc.Invoke(ctx, "/immuvoting.v1.Immuvoting/GetState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *immuvotingClient) GetVerifiableTx(ctx context.Context, in *GetVerifiableTxRequest, opts ...grpc.CallOption) (*schema.VerifiableTx, error) {
+	out := new(schema.VerifiableTx)
+	err := c.cc.Invoke(ctx, "/immuvoting.v1.Immuvoting/GetVerifiableTx", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *immuvotingClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error) {
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, "/immuvoting.v1.Immuvoting/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *immuvotingClient) WatchCheckpoints(ctx context.Context, in *WatchCheckpointsRequest, opts ...grpc.CallOption) (Immuvoting_WatchCheckpointsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Immuvoting_serviceDesc.Streams[0], "/immuvoting.v1.Immuvoting/WatchCheckpoints", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &immuvotingWatchCheckpointsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Immuvoting_WatchCheckpointsClient interface {
+	Recv() (*State, error)
+	grpc.ClientStream
+}
+
+type immuvotingWatchCheckpointsClient struct {
+	grpc.ClientStream
+}
+
+func (x *immuvotingWatchCheckpointsClient) Recv() (*State, error) {
+	m := new(State)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ImmuvotingServer is the server API for Immuvoting service.
+// All implementations must embed UnimplementedImmuvotingServer
+// for forward compatibility
+type ImmuvotingServer interface {
+	// Registers a voter and issues their ballot
+	RegisterVoter(context.Context, *RegisterVoterRequest) (*RegisterVoterResponse, error)
+	// Casts a ballot
+	Vote(context.Context, *VoteRequest) (*VoteResponse, error)
+	// Returns the registration status of a voter
+	GetVoterStatus(context.Context, *GetVoterStatusRequest) (*VoterStatus, error)
+	// Returns a ballot
+	GetBallot(context.Context, *GetBallotRequest) (*Ballot, error)
+	// Returns the current state of the database
+	GetState(context.Context, *GetStateRequest) (*State, error)
+	// Returns a tx with the proof of its consistency with a previously verified tx
+	GetVerifiableTx(context.Context, *GetVerifiableTxRequest) (*schema.VerifiableTx, error)
+	// Returns the registration and voting stats
+	GetStats(context.Context, *GetStatsRequest) (*Stats, error)
+	// Streams the current state, then each new state (checkpoint) as txs are
+	// committed; a slow client only gets the latest one
+	WatchCheckpoints(*WatchCheckpointsRequest, Immuvoting_WatchCheckpointsServer) error
+	mustEmbedUnimplementedImmuvotingServer()
+}
+
+// UnimplementedImmuvotingServer must be embedded to have forward compatible implementations.
+type UnimplementedImmuvotingServer struct {
+}
+
+func (UnimplementedImmuvotingServer) RegisterVoter(context.Context, *RegisterVoterRequest) (*RegisterVoterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterVoter not implemented")
+}
+func (UnimplementedImmuvotingServer) Vote(context.Context, *VoteRequest) (*VoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Vote not implemented")
+}
+func (UnimplementedImmuvotingServer) GetVoterStatus(context.Context, *GetVoterStatusRequest) (*VoterStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVoterStatus not implemented")
+}
+func (UnimplementedImmuvotingServer) GetBallot(context.Context, *GetBallotRequest) (*Ballot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBallot not implemented")
+}
+func (UnimplementedImmuvotingServer) GetState(context.Context, *GetStateRequest) (*State, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
+func (UnimplementedImmuvotingServer) GetVerifiableTx(context.Context, *GetVerifiableTxRequest) (*schema.VerifiableTx, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVerifiableTx not implemented")
+}
+func (UnimplementedImmuvotingServer) GetStats(context.Context, *GetStatsRequest) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedImmuvotingServer) WatchCheckpoints(*WatchCheckpointsRequest, Immuvoting_WatchCheckpointsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCheckpoints not implemented")
+}
+func (UnimplementedImmuvotingServer) mustEmbedUnimplementedImmuvotingServer() {}
+
+// UnsafeImmuvotingServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImmuvotingServer will
+// result in compilation errors.
+type UnsafeImmuvotingServer interface {
+	mustEmbedUnimplementedImmuvotingServer()
+}
+
+func RegisterImmuvotingServer(s grpc.ServiceRegistrar, srv ImmuvotingServer) {
+	s.RegisterService(&_Immuvoting_serviceDesc, srv)
+}
+
+func _Immuvoting_RegisterVoter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterVoterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImmuvotingServer).RegisterVoter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immuvoting.v1.Immuvoting/RegisterVoter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImmuvotingServer).RegisterVoter(ctx, req.(*RegisterVoterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Immuvoting_Vote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImmuvotingServer).Vote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immuvoting.v1.Immuvoting/Vote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImmuvotingServer).Vote(ctx, req.(*VoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Immuvoting_GetVoterStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVoterStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImmuvotingServer).GetVoterStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immuvoting.v1.Immuvoting/GetVoterStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImmuvotingServer).GetVoterStatus(ctx, req.(*GetVoterStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Immuvoting_GetBallot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBallotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImmuvotingServer).GetBallot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immuvoting.v1.Immuvoting/GetBallot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImmuvotingServer).GetBallot(ctx, req.(*GetBallotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Immuvoting_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImmuvotingServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immuvoting.v1.Immuvoting/GetState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImmuvotingServer).GetState(ctx, req.(*GetStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Immuvoting_GetVerifiableTx_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVerifiableTxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImmuvotingServer).GetVerifiableTx(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immuvoting.v1.Immuvoting/GetVerifiableTx",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImmuvotingServer).GetVerifiableTx(ctx, req.(*GetVerifiableTxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Immuvoting_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImmuvotingServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immuvoting.v1.Immuvoting/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImmuvotingServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Immuvoting_WatchCheckpoints_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchCheckpointsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImmuvotingServer).WatchCheckpoints(m, &immuvotingWatchCheckpointsServer{stream})
+}
+
+type Immuvoting_WatchCheckpointsServer interface {
+	Send(*State) error
+	grpc.ServerStream
+}
+
+type immuvotingWatchCheckpointsServer struct {
+	grpc.ServerStream
+}
+
+func (x *immuvotingWatchCheckpointsServer) Send(m *State) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Immuvoting_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "immuvoting.v1.Immuvoting",
+	HandlerType: (*ImmuvotingServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterVoter",
+			Handler:    _Immuvoting_RegisterVoter_Handler,
+		},
+		{
+			MethodName: "Vote",
+			Handler:    _Immuvoting_Vote_Handler,
+		},
+		{
+			MethodName: "GetVoterStatus",
+			Handler:    _Immuvoting_GetVoterStatus_Handler,
+		},
+		{
+			MethodName: "GetBallot",
+			Handler:    _Immuvoting_GetBallot_Handler,
+		},
+		{
+			MethodName: "GetState",
+			Handler:    _Immuvoting_GetState_Handler,
+		},
+		{
+			MethodName: "GetVerifiableTx",
+			Handler:    _Immuvoting_GetVerifiableTx_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Immuvoting_GetStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchCheckpoints",
+			Handler:       _Immuvoting_WatchCheckpoints_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb/immuvoting.proto",
+}
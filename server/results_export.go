@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	resultsFormatJSON    = "json"
+	resultsFormatCSV     = "csv"
+	resultsFormatNISTCDF = "nist-cdf"
+
+	// statuses of the results, as in the NIST Election Results Reporting
+	// Common Data Format (ResultsStatus)
+	resultsStatusCertified          = "certified"
+	resultsStatusUnofficialComplete = "unofficial-complete"
+	resultsStatusUnofficialPartial  = "unofficial-partial"
+)
+
+// ResultsExport are the results handed to downstream reporting systems: the
+// tally, the turnout and the rollups of the units, with the tx they are as of
+// for provenance (the closing tx, once the election has been closed)
+type ResultsExport struct {
+	ElectionID   string `json:"election_id"`
+	ElectionName string `json:"election_name"`
+	// unofficial-partial until the election is closed, unofficial-complete until
+	// the quorum of the officials have signed the results and certified then
+	Status string `json:"status"`
+	TXID   uint64 `json:"tx_id"`
+	TXHash []byte `json:"tx_hash"`
+	// when the election has been closed, if it has
+	Closed     *time.Time  `json:"closed,omitempty"`
+	Generated  time.Time   `json:"generated"`
+	Candidates []Candidate `json:"candidates"`
+	Counts
+	Units []UnitStats `json:"units"`
+}
+
+// exportResults returns the results as of the closing tx, if the election has
+// been closed, or as of the latest write of the tally otherwise
+func (s *Server) exportResults(ctx context.Context) (*ResultsExport, error) {
+	s.tallyMu.Lock()
+	closedTX := s.closedTX
+	s.tallyMu.Unlock()
+
+	export := ResultsExport{
+		ElectionID:   electionID,
+		ElectionName: election.Name,
+		Status:       resultsStatusUnofficialPartial,
+		Generated:    time.Now().UTC(),
+		Candidates:   election.Candidates,
+	}
+	tally, tallyTX, err := s.tallyAsOf(ctx, closedTX, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if closedTX > 0 {
+		certification, err := s.certification(ctx)
+		if err != nil {
+			return nil, err
+		}
+		export.TXID = certification.Results.ClosingTXID
+		export.TXHash = certification.Results.ClosingTXHash
+		export.Closed = &certification.Results.Closed
+		export.Status = resultsStatusUnofficialComplete
+		if s.quorum > 0 && len(certification.Signatures) >= s.quorum {
+			export.Status = resultsStatusCertified
+		}
+	} else {
+		txs, err := s.store.TxScan(ctx, tallyTX, 1)
+		if err != nil || len(txs) == 0 {
+			return nil, internalError(err, fmt.Sprintf("error fetching tx %d", tallyTX))
+		}
+		export.TXID = tallyTX
+		export.TXHash = bulletinBoardTX(txs[0]).Alh
+	}
+	export.Counts = tally.Counts
+	export.Units = unitStats(election.Regions, 0, tally.Precincts)
+	return &export, nil
+}
+
+// writeResultsCSV writes one row for the election and one per unit, depth
+// first, with the votes of each candidate in a column; each row holds the tx of
+// the results and its hash
+func writeResultsCSV(w io.Writer, export *ResultsExport) error {
+	csvWriter := csv.NewWriter(w)
+	header := []string{"tx_id", "tx_hash", "status", "level", "unit_id", "unit_name",
+		"registered", "voted", "ballots"}
+	for _, candidate := range export.Candidates {
+		header = append(header, candidate.Name)
+	}
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+	writeRow := func(level string, id string, name string, counts *Counts) error {
+		row := []string{
+			strconv.FormatUint(export.TXID, 10),
+			hex.EncodeToString(export.TXHash),
+			export.Status,
+			level,
+			id,
+			name,
+			strconv.FormatUint(counts.Registered, 10),
+			strconv.FormatUint(counts.Voted, 10),
+			strconv.FormatUint(counts.Ballots, 10),
+		}
+		for _, candidate := range export.Candidates {
+			row = append(row, strconv.FormatUint(counts.Results[candidate.ID], 10))
+		}
+		return csvWriter.Write(row)
+	}
+	if err := writeRow("election", export.ElectionID, export.ElectionName, &export.Counts); err != nil {
+		return err
+	}
+	var writeUnits func(units []UnitStats) error
+	writeUnits = func(units []UnitStats) error {
+		for i := range units {
+			if err := writeRow(units[i].Level, units[i].ID, units[i].Name, &units[i].Counts); err != nil {
+				return err
+			}
+			if err := writeUnits(units[i].Units); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := writeUnits(export.Units); err != nil {
+		return err
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// getResultsExportHandler exports the results as JSON, CSV or NIST CDF JSON
+// (see nist_cdf.go), as an attachment
+func (s *Server) getResultsExportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = resultsFormatJSON
+	}
+	if format != resultsFormatJSON && format != resultsFormatCSV && format != resultsFormatNISTCDF {
+		writeErrorResponse(r, w, http.StatusBadRequest, nil, fmt.Sprintf(
+			"format query param must be %s, %s or %s", resultsFormatJSON, resultsFormatCSV, resultsFormatNISTCDF))
+		return
+	}
+
+	export, err := s.exportResults(r.Context())
+	if err != nil {
+		writeAPIError(r, w, err)
+		return
+	}
+
+	fileName := fmt.Sprintf("immuvoting-results-%d", export.TXID)
+	switch format {
+	case resultsFormatCSV:
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.csv\"", fileName))
+		w.Header().Set("Content-Type", "text/csv")
+		requestLogger(r).WithField("status", http.StatusOK).Info(http.StatusText(http.StatusOK))
+		if err := writeResultsCSV(w, export); err != nil {
+			requestLogger(r).WithError(err).Warn("error writing results CSV")
+		}
+	case resultsFormatNISTCDF:
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-nist-cdf.json\"", fileName))
+		writeJSONResponse(r, w, http.StatusOK, nistCDFElectionReport(export))
+	default:
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.json\"", fileName))
+		writeJSONResponse(r, w, http.StatusOK, export)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testResultsExport returns the results of an election of a region with a
+// district of two precincts, closed at tx 42
+func testResultsExport() *ResultsExport {
+	closed := time.Date(2024, time.November, 5, 20, 0, 0, 0, time.UTC)
+	precinctA := Counts{Registered: 3, Voted: 2, Ballots: 2, Results: map[uint16]uint64{NikkiHaley: 2}}
+	precinctB := Counts{Registered: 2, Voted: 1, Ballots: 1, Results: map[uint16]uint64{KamalaHarris: 1}}
+	total := Counts{Registered: 5, Voted: 3, Ballots: 3, Results: map[uint16]uint64{NikkiHaley: 2, KamalaHarris: 1}}
+	return &ResultsExport{
+		ElectionID:   electionID,
+		ElectionName: "test election",
+		Status:       resultsStatusCertified,
+		TXID:         42,
+		TXHash:       []byte{0xca, 0xfe},
+		Closed:       &closed,
+		Generated:    closed.Add(time.Hour),
+		Candidates:   []Candidate{{ID: NikkiHaley, Name: "Nikki Haley"}, {ID: KamalaHarris, Name: "Kamala Harris"}},
+		Counts:       total,
+		Units: []UnitStats{{ID: "north", Name: "North", Level: unitLevelRegion, Counts: total, Units: []UnitStats{
+			{ID: "north-1", Name: "North 1", Level: unitLevelDistrict, Counts: total, Units: []UnitStats{
+				{ID: "north-1-a", Name: "North 1A", Level: unitLevelPrecinct, Counts: precinctA},
+				{ID: "north-1-b", Name: "North 1B", Level: unitLevelPrecinct, Counts: precinctB},
+			}},
+		}}},
+	}
+}
+
+func TestResultsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResultsCSV(&buf, testResultsExport()); err != nil {
+		t.Fatalf("error writing results CSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("error reading results CSV: %v", err)
+	}
+	want := [][]string{
+		{"tx_id", "tx_hash", "status", "level", "unit_id", "unit_name", "registered", "voted", "ballots", "Nikki Haley", "Kamala Harris"},
+		{"42", "cafe", "certified", "election", electionID, "test election", "5", "3", "3", "2", "1"},
+		{"42", "cafe", "certified", "region", "north", "North", "5", "3", "3", "2", "1"},
+		{"42", "cafe", "certified", "district", "north-1", "North 1", "5", "3", "3", "2", "1"},
+		{"42", "cafe", "certified", "precinct", "north-1-a", "North 1A", "3", "2", "2", "2", "0"},
+		{"42", "cafe", "certified", "precinct", "north-1-b", "North 1B", "2", "1", "1", "0", "1"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got rows %q, want %q", rows, want)
+	}
+}
+
+func TestResultsNISTCDF(t *testing.T) {
+	export := testResultsExport()
+	reportBytes, err := json.Marshal(nistCDFElectionReport(export))
+	if err != nil {
+		t.Fatalf("error JSON-marshaling NIST CDF report: %v", err)
+	}
+	var report cdfElectionReport
+	if err := json.Unmarshal(reportBytes, &report); err != nil {
+		t.Fatalf("error JSON-unmarshaling NIST CDF report: %v", err)
+	}
+	if report.Status != export.Status || report.GeneratedDate != "2024-11-05T21:00:00Z" || len(report.Election) != 1 {
+		t.Fatalf("got report status %s, generated %s, %d elections, want %s, 2024-11-05T21:00:00Z and 1",
+			report.Status, report.GeneratedDate, len(report.Election), export.Status)
+	}
+	cdfElection := report.Election[0]
+	if cdfElection.StartDate != "2024-11-05" || len(cdfElection.ExternalIdentifier) != 2 ||
+		cdfElection.ExternalIdentifier[0].Value != strconv.FormatUint(export.TXID, 10) ||
+		cdfElection.ExternalIdentifier[1].Value != hex.EncodeToString(export.TXHash) {
+		t.Errorf("got election %s with identifiers %+v, want 2024-11-05 at tx 42 (cafe)",
+			cdfElection.StartDate, cdfElection.ExternalIdentifier)
+	}
+
+	// the counts of the units, read back from the report
+	counts := map[string]*Counts{}
+	for _, unit := range report.GpUnit {
+		counts[unit.ID] = &Counts{Registered: unit.VotersRegistered, Voted: unit.VotersParticipated,
+			Results: map[uint16]uint64{}}
+	}
+	for _, ballotCounts := range cdfElection.BallotCounts {
+		counts[ballotCounts.GpUnitID].Ballots = ballotCounts.BallotsCast
+	}
+	for i, selection := range cdfElection.Contest[0].ContestSelection {
+		for _, voteCounts := range selection.VoteCounts {
+			if voteCounts.Count > 0 {
+				counts[voteCounts.GpUnitID].Results[export.Candidates[i].ID] = voteCounts.Count
+			}
+		}
+	}
+	want := map[string]*Counts{cdfUnitID(electionID): &export.Counts}
+	var addUnits func(units []UnitStats)
+	addUnits = func(units []UnitStats) {
+		for i := range units {
+			want[cdfUnitID(units[i].ID)] = &units[i].Counts
+			addUnits(units[i].Units)
+		}
+	}
+	addUnits(export.Units)
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("got counts %+v, want %+v", counts, want)
+	}
+}
+
+func TestResultsCertificationQuorum(t *testing.T) {
+	store := newMemStore()
+	if err := persistElection(context.Background(), store, &election); err != nil {
+		t.Fatalf("error persisting election definition: %v", err)
+	}
+	var officials []Official
+	privateKeys := map[string]ed25519.PrivateKey{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("error generating key: %v", err)
+		}
+		officials = append(officials, Official{Name: name, PublicKey: publicKey})
+		privateKeys[name] = privateKey
+	}
+	server := NewServer(store, testAdminUser, testAdminPassword, officials, 2, nil)
+	if err := server.LoadTally(context.Background()); err != nil {
+		t.Fatalf("error loading tally: %v", err)
+	}
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	electionURL := httpServer.URL + apiV1Prefix + "/elections/" + electionID
+	adminURL := httpServer.URL + apiV1Prefix + "/admin/elections/" + electionID
+
+	var certification CertificationResponse
+	if status := doJSON(t, http.MethodPost, adminURL+"/certification", true, nil, &certification); status != http.StatusOK {
+		t.Fatalf("closing election: got status %d, want %d", status, http.StatusOK)
+	}
+	for i, wantStatus := range []string{resultsStatusUnofficialComplete, resultsStatusCertified} {
+		name := officials[i].Name
+		if status := doJSON(t, http.MethodPost, electionURL+"/certification/signatures", false, &SignCertificationRequest{
+			Official:  name,
+			Signature: ed25519.Sign(privateKeys[name], certification.Document),
+		}, nil); status != http.StatusOK {
+			t.Fatalf("signing as %s: got status %d, want %d", name, status, http.StatusOK)
+		}
+		var export ResultsExport
+		if status := doJSON(t, http.MethodGet, adminURL+"/results", true, nil, &export); status != http.StatusOK ||
+			export.Status != wantStatus {
+			t.Errorf("signed by %d of 3 officials, quorum 2: got status %d, results %s, want %s",
+				i+1, status, export.Status, wantStatus)
+		}
+	}
+}
@@ -0,0 +1,627 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+// The risk-limiting audit (RLA) compares a random sample of paper ballots with
+// their electronic counterparts. Once the election is closed, the ballot frame
+// and the reported results are committed in immudb; only then, in a later tx,
+// is the public seed of the sampling accepted, so that the frame can not be
+// chosen knowing the sample, and anyone can reproduce the sample and the risk
+// measure.
+const (
+	rlaKey                  = "immuvoting:rla"
+	rlaSeedKey              = "immuvoting:rla:seed"
+	rlaInterpretationPrefix = "immuvoting:rla:interpretation:"
+
+	rlaBallotPolling    = "ballot-polling"
+	rlaBallotComparison = "ballot-comparison"
+
+	rlaAwaitingSeed  = "awaiting_seed"
+	rlaInProgress    = "in_progress"
+	rlaConfirmed     = "confirmed"
+	rlaFullHandCount = "full_hand_count"
+
+	// error inflation factor of the Kaplan-Markov ballot-comparison audit
+	rlaGamma         = 1.03905
+	rlaMinSeedLength = 20
+	rlaDefaultCount  = 10
+)
+
+var (
+	errRLANotCommitted     = errors.New("no RLA has been committed yet")
+	errRLASeedNotCommitted = errors.New("no RLA seed has been committed yet")
+	errRLAFrameChanged     = errors.New("ballot frame has changed since the RLA was committed")
+)
+
+// RLACommitment is persisted before the seed: it fixes the ballot frame (the
+// sorted IDs of all ballots) and the reported results
+type RLACommitment struct {
+	Method        string            `json:"method"`
+	RiskLimit     float64           `json:"risk_limit"`
+	MaxSampleSize uint64            `json:"max_sample_size"`
+	FrameSize     uint64            `json:"frame_size"`
+	FrameDigest   []byte            `json:"frame_digest"`
+	Reported      map[uint16]uint64 `json:"reported"`
+	Committed     time.Time         `json:"committed"`
+}
+
+// RLASeed is persisted after the commitment, in a later tx: it fixes the
+// sample
+type RLASeed struct {
+	Seed string `json:"seed"`
+	// tx of the commitment which the seed follows
+	CommitmentTX uint64    `json:"commitment_tx"`
+	Committed    time.Time `json:"committed"`
+}
+
+// RLAInterpretation is the auditors' reading of a sampled paper ballot, next
+// to the electronic vote of the same ballot
+type RLAInterpretation struct {
+	Draw       uint64    `json:"draw"`
+	BallotID   string    `json:"ballot_id"`
+	Electronic uint16    `json:"electronic"`
+	Paper      uint16    `json:"paper"`
+	Auditor    string    `json:"auditor,omitempty"`
+	Recorded   time.Time `json:"recorded"`
+}
+
+// RLAStatus ...
+type RLAStatus struct {
+	Commitment    *RLACommitment `json:"commitment"`
+	Seed          *RLASeed       `json:"seed,omitempty"`
+	SampleSize    uint64         `json:"sample_size"`
+	Discrepancies uint64         `json:"discrepancies"`
+	RiskMeasure   float64        `json:"risk_measure"`
+	Status        string         `json:"status"`
+}
+
+// RLASampleDraw ...
+type RLASampleDraw struct {
+	Draw     uint64 `json:"draw"`
+	BallotID string `json:"ballot_id"`
+}
+
+// rlaDraw returns the frame index of the ballot picked by the given draw
+// (starting from 1): SHA-256(seed + "," + draw), as a big-endian integer,
+// modulo the frame size; draws are with replacement
+func rlaDraw(seed string, frameSize uint64, draw uint64) uint64 {
+	h := sha256.Sum256([]byte(seed + "," + strconv.FormatUint(draw, 10)))
+	n := new(big.Int).SetBytes(h[:])
+	return n.Mod(n, new(big.Int).SetUint64(frameSize)).Uint64()
+}
+
+// rlaFrame returns the IDs of all ballots, sorted, their digest and the
+// reported results
+func (s *Server) rlaFrame(ctx context.Context) ([]string, []byte, map[uint16]uint64, error) {
+	ballotEntries, err := scanAll(ctx, s.store, []byte(ballotPrefix))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error scanning ballots: %v", err)
+	}
+	candidates := make(map[uint16]bool, len(election.Candidates))
+	reported := make(map[uint16]uint64, len(election.Candidates))
+	for _, candidate := range election.Candidates {
+		candidates[candidate.ID] = true
+		reported[candidate.ID] = 0
+	}
+	ballotIDs := make([]string, 0, len(ballotEntries))
+	for _, ballotEntry := range ballotEntries {
+		ballotIDs = append(ballotIDs, strings.TrimPrefix(string(ballotEntry.GetKey()), ballotPrefix))
+		if vote := binary.BigEndian.Uint16(ballotEntry.GetValue()); candidates[vote] {
+			reported[vote]++
+		}
+	}
+	digest := sha256.Sum256([]byte(strings.Join(ballotIDs, "\n")))
+	return ballotIDs, digest[:], reported, nil
+}
+
+// loadRLA returns the committed RLA, its seed (nil if it has not been
+// committed yet) and its interpretations
+func (s *Server) loadRLA(ctx context.Context) (*RLACommitment, *RLASeed, []RLAInterpretation, error) {
+	commitmentBytes, err := s.store.Get(ctx, []byte(rlaKey), 0)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil, nil, errRLANotCommitted
+		}
+		return nil, nil, nil, fmt.Errorf("error fetching RLA commitment: %v", err)
+	}
+	var commitment RLACommitment
+	if err := json.Unmarshal(commitmentBytes, &commitment); err != nil {
+		return nil, nil, nil, fmt.Errorf("error JSON-unmarshaling RLA commitment: %v", err)
+	}
+	seedBytes, err := s.store.Get(ctx, []byte(rlaSeedKey), 0)
+	if errors.Is(err, ErrNotFound) {
+		return &commitment, nil, nil, nil
+	} else if err != nil {
+		return nil, nil, nil, fmt.Errorf("error fetching RLA seed: %v", err)
+	}
+	var seed RLASeed
+	if err := json.Unmarshal(seedBytes, &seed); err != nil {
+		return nil, nil, nil, fmt.Errorf("error JSON-unmarshaling RLA seed: %v", err)
+	}
+	interpretationEntries, err := scanAll(ctx, s.store, []byte(rlaInterpretationPrefix))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error scanning RLA interpretations: %v", err)
+	}
+	interpretations := make([]RLAInterpretation, 0, len(interpretationEntries))
+	for _, interpretationEntry := range interpretationEntries {
+		var interpretation RLAInterpretation
+		if err := json.Unmarshal(interpretationEntry.GetValue(), &interpretation); err != nil {
+			return nil, nil, nil, fmt.Errorf("error JSON-unmarshaling RLA interpretation %s: %v",
+				interpretationEntry.GetKey(), err)
+		}
+		interpretations = append(interpretations, interpretation)
+	}
+	return &commitment, &seed, interpretations, nil
+}
+
+// rlaContest returns the reported winner and losers; ok is false on a tie for
+// the first place, whose outcome can only be settled by a full hand count
+func rlaContest(reported map[uint16]uint64) (winner uint16, losers []uint16, ok bool) {
+	for _, candidate := range election.Candidates {
+		if winner == 0 || reported[candidate.ID] > reported[winner] {
+			winner = candidate.ID
+		}
+	}
+	for _, candidate := range election.Candidates {
+		if candidate.ID == winner {
+			continue
+		}
+		if reported[candidate.ID] == reported[winner] {
+			return 0, nil, false
+		}
+		losers = append(losers, candidate.ID)
+	}
+	return winner, losers, true
+}
+
+// rlaBallotPollingRisk is the BRAVO risk measure: the reciprocal of the
+// sequential probability ratio of each winner-loser pair, the largest of which
+// is the risk of confirming a wrong outcome
+func rlaBallotPollingRisk(
+	reported map[uint16]uint64, winner uint16, losers []uint16, interpretations []RLAInterpretation,
+) float64 {
+	risk := 0.
+	for _, loser := range losers {
+		share := float64(reported[winner]) / float64(reported[winner]+reported[loser])
+		t := 1.
+		for _, interpretation := range interpretations {
+			switch interpretation.Paper {
+			case winner:
+				t *= 2 * share
+			case loser:
+				t *= 2 * (1 - share)
+			}
+		}
+		risk = math.Max(risk, math.Min(1, 1/t))
+	}
+	return risk
+}
+
+// rlaBallotComparisonRisk is the Kaplan-Markov risk measure, based on the
+// overstatement of the smallest margin by each sampled electronic vote
+func rlaBallotComparisonRisk(
+	reported map[uint16]uint64, frameSize uint64,
+	winner uint16, losers []uint16, interpretations []RLAInterpretation,
+) float64 {
+	var runnerUp uint64
+	for _, loser := range losers {
+		if reported[loser] > runnerUp {
+			runnerUp = reported[loser]
+		}
+	}
+	dilutedMargin := float64(reported[winner]-runnerUp) / float64(frameSize)
+	u := 2 * rlaGamma / dilutedMargin
+	score := func(vote, loser uint16) int {
+		switch vote {
+		case winner:
+			return 1
+		case loser:
+			return -1
+		}
+		return 0
+	}
+	risk := 1.
+	for _, interpretation := range interpretations {
+		overstatement := math.MinInt32
+		for _, loser := range losers {
+			o := score(interpretation.Electronic, loser) - score(interpretation.Paper, loser)
+			if o > overstatement {
+				overstatement = o
+			}
+		}
+		risk *= 1 - 1/u
+		switch overstatement {
+		case 1:
+			risk /= 1 - 1/(2*rlaGamma)
+		case 2:
+			risk /= 1 - 1/rlaGamma
+		case -1:
+			risk /= 1 + 1/(2*rlaGamma)
+		case -2:
+			risk /= 1 + 1/rlaGamma
+		}
+	}
+	return math.Min(1, risk)
+}
+
+func rlaStatus(commitment *RLACommitment, seed *RLASeed, interpretations []RLAInterpretation) *RLAStatus {
+	status := RLAStatus{
+		Commitment:  commitment,
+		Seed:        seed,
+		SampleSize:  uint64(len(interpretations)),
+		RiskMeasure: 1,
+		Status:      rlaInProgress,
+	}
+	if seed == nil {
+		status.Status = rlaAwaitingSeed
+		return &status
+	}
+	for _, interpretation := range interpretations {
+		if interpretation.Paper != interpretation.Electronic {
+			status.Discrepancies++
+		}
+	}
+
+	winner, losers, ok := rlaContest(commitment.Reported)
+	if !ok {
+		status.Status = rlaFullHandCount
+		return &status
+	}
+	switch commitment.Method {
+	case rlaBallotPolling:
+		status.RiskMeasure = rlaBallotPollingRisk(
+			commitment.Reported, winner, losers, interpretations)
+	case rlaBallotComparison:
+		status.RiskMeasure = rlaBallotComparisonRisk(
+			commitment.Reported, commitment.FrameSize, winner, losers, interpretations)
+	}
+	switch {
+	case status.RiskMeasure <= commitment.RiskLimit:
+		status.Status = rlaConfirmed
+	case status.SampleSize >= commitment.MaxSampleSize:
+		status.Status = rlaFullHandCount
+	}
+	return &status
+}
+
+// CommitRLARequest ...
+type CommitRLARequest struct {
+	Method        string  `json:"method"`
+	RiskLimit     float64 `json:"risk_limit"`
+	MaxSampleSize uint64  `json:"max_sample_size"`
+}
+
+func (req *CommitRLARequest) validate() error {
+	var errs validationErrors
+	if req.Method != rlaBallotPolling && req.Method != rlaBallotComparison {
+		errs.add("method", fmt.Sprintf("method must be %s or %s", rlaBallotPolling, rlaBallotComparison))
+	}
+	if req.RiskLimit <= 0 || req.RiskLimit >= 1 {
+		errs.add("risk_limit", "risk limit must be between 0 and 1")
+	}
+	return errs.err()
+}
+
+func (s *Server) commitRLAHandler(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var payload CommitRLARequest
+	if err := decoder.Decode(&payload); err != nil {
+		writeAPIErrorResponse(r, w, http.StatusBadRequest, ErrCodeInvalidJSON, nil,
+			fmt.Sprintf("error parsing request body: %v", err))
+		return
+	}
+	if err := payload.validate(); err != nil {
+		writeValidationErrorResponse(r, w, err)
+		return
+	}
+
+	// the ballot frame is final only once the election has been closed
+	s.tallyMu.Lock()
+	closedTX := s.closedTX
+	s.tallyMu.Unlock()
+	if closedTX == 0 {
+		writeAPIErrorResponse(r, w, http.StatusConflict, ErrCodeElectionNotClosed, nil,
+			"the election must be closed before the RLA is committed")
+		return
+	}
+
+	unlock := s.locks.Lock(rlaKey)
+	defer unlock()
+	if _, err := s.store.Get(r.Context(), []byte(rlaKey), 0); err == nil {
+		writeAPIErrorResponse(r, w, http.StatusConflict, ErrCodeRLAAlreadyCommitted, nil,
+			"RLA has already been committed")
+		return
+	} else if !errors.Is(err, ErrNotFound) {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err, "error fetching RLA commitment")
+		return
+	}
+
+	ballotIDs, frameDigest, reported, err := s.rlaFrame(r.Context())
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err, "error building ballot frame")
+		return
+	}
+	if len(ballotIDs) == 0 {
+		writeErrorResponse(r, w, http.StatusConflict, nil, "there are no ballots to audit")
+		return
+	}
+	commitment := RLACommitment{
+		Method:        payload.Method,
+		RiskLimit:     payload.RiskLimit,
+		MaxSampleSize: payload.MaxSampleSize,
+		FrameSize:     uint64(len(ballotIDs)),
+		FrameDigest:   frameDigest,
+		Reported:      reported,
+		Committed:     time.Now(),
+	}
+	if commitment.MaxSampleSize == 0 || commitment.MaxSampleSize > commitment.FrameSize {
+		commitment.MaxSampleSize = commitment.FrameSize
+	}
+	commitmentBytes, err := json.Marshal(&commitment)
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error JSON-marshaling RLA commitment")
+		return
+	}
+	if err := s.store.Set(r.Context(), []byte(rlaKey), commitmentBytes); err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error persisting RLA commitment")
+		return
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, rlaStatus(&commitment, nil, nil))
+}
+
+// CommitRLASeedRequest ...
+type CommitRLASeedRequest struct {
+	Seed string `json:"seed"`
+}
+
+func (req *CommitRLASeedRequest) validate() error {
+	var errs validationErrors
+	if len(req.Seed) < rlaMinSeedLength {
+		errs.add("seed", fmt.Sprintf("seed must have at least %d characters", rlaMinSeedLength))
+	}
+	return errs.err()
+}
+
+func (s *Server) commitRLASeedHandler(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var payload CommitRLASeedRequest
+	if err := decoder.Decode(&payload); err != nil {
+		writeAPIErrorResponse(r, w, http.StatusBadRequest, ErrCodeInvalidJSON, nil,
+			fmt.Sprintf("error parsing request body: %v", err))
+		return
+	}
+	if err := payload.validate(); err != nil {
+		writeValidationErrorResponse(r, w, err)
+		return
+	}
+
+	unlock := s.locks.Lock(rlaKey)
+	defer unlock()
+	commitmentEntry, err := s.store.GetLatestEntry(r.Context(), []byte(rlaKey))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			err = errRLANotCommitted
+		}
+		writeRLAErrorResponse(r, w, err)
+		return
+	}
+	var commitment RLACommitment
+	if err := json.Unmarshal(commitmentEntry.GetValue(), &commitment); err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error JSON-unmarshaling RLA commitment")
+		return
+	}
+	if _, err := s.store.Get(r.Context(), []byte(rlaSeedKey), 0); err == nil {
+		writeAPIErrorResponse(r, w, http.StatusConflict, ErrCodeRLAAlreadyCommitted, nil,
+			"RLA seed has already been committed")
+		return
+	} else if !errors.Is(err, ErrNotFound) {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err, "error fetching RLA seed")
+		return
+	}
+
+	seed := RLASeed{
+		Seed:         payload.Seed,
+		CommitmentTX: commitmentEntry.GetTx(),
+		Committed:    time.Now(),
+	}
+	seedBytes, err := json.Marshal(&seed)
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error JSON-marshaling RLA seed")
+		return
+	}
+	if err := s.store.Set(r.Context(), []byte(rlaSeedKey), seedBytes); err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error persisting RLA seed")
+		return
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, rlaStatus(&commitment, &seed, nil))
+}
+
+func (s *Server) getRLAStatusHandler(w http.ResponseWriter, r *http.Request) {
+	commitment, seed, interpretations, err := s.loadRLA(r.Context())
+	if err != nil {
+		writeRLAErrorResponse(r, w, err)
+		return
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, rlaStatus(commitment, seed, interpretations))
+}
+
+// rlaCommittedFrame loads the committed RLA, whose seed must have been
+// committed, and the ballot frame, which must not have changed since the
+// commitment
+func (s *Server) rlaCommittedFrame(
+	ctx context.Context,
+) (*RLACommitment, *RLASeed, []RLAInterpretation, []string, error) {
+	commitment, seed, interpretations, err := s.loadRLA(ctx)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if seed == nil {
+		return nil, nil, nil, nil, errRLASeedNotCommitted
+	}
+	ballotIDs, frameDigest, _, err := s.rlaFrame(ctx)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if uint64(len(ballotIDs)) != commitment.FrameSize ||
+		!bytes.Equal(frameDigest, commitment.FrameDigest) {
+		return nil, nil, nil, nil, errRLAFrameChanged
+	}
+	return commitment, seed, interpretations, ballotIDs, nil
+}
+
+func writeRLAErrorResponse(r *http.Request, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errRLANotCommitted):
+		writeAPIErrorResponse(r, w, http.StatusNotFound, ErrCodeRLANotCommitted, nil, err.Error())
+	case errors.Is(err, errRLASeedNotCommitted):
+		writeAPIErrorResponse(r, w, http.StatusConflict, ErrCodeRLASeedNotCommitted, nil, err.Error())
+	case errors.Is(err, errRLAFrameChanged):
+		writeAPIErrorResponse(r, w, http.StatusConflict, ErrCodeRLAFrameChanged, nil, err.Error())
+	default:
+		writeErrorResponse(r, w, http.StatusInternalServerError, err, "error loading RLA")
+	}
+}
+
+func (s *Server) getRLASampleHandler(w http.ResponseWriter, r *http.Request) {
+	from := uint64(1)
+	if fromStr := r.URL.Query().Get("from"); len(fromStr) > 0 {
+		var err error
+		if from, err = strconv.ParseUint(fromStr, 10, 64); err != nil || from == 0 {
+			writeErrorResponse(r, w, http.StatusBadRequest, err,
+				"from query param is not a positive unsigned int")
+			return
+		}
+	}
+	count := uint64(rlaDefaultCount)
+	if countStr := r.URL.Query().Get("count"); len(countStr) > 0 {
+		var err error
+		if count, err = strconv.ParseUint(countStr, 10, 64); err != nil ||
+			count == 0 || count > database.MaxKeyScanLimit {
+			writeErrorResponse(r, w, http.StatusBadRequest, err, fmt.Sprintf(
+				"count query param must be between 1 and %d", database.MaxKeyScanLimit))
+			return
+		}
+	}
+
+	commitment, seed, _, ballotIDs, err := s.rlaCommittedFrame(r.Context())
+	if err != nil {
+		writeRLAErrorResponse(r, w, err)
+		return
+	}
+
+	draws := []RLASampleDraw{}
+	for draw := from; draw < from+count && draw <= commitment.MaxSampleSize; draw++ {
+		draws = append(draws, RLASampleDraw{
+			Draw:     draw,
+			BallotID: ballotIDs[rlaDraw(seed.Seed, commitment.FrameSize, draw)],
+		})
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, &draws)
+}
+
+// RecordRLAInterpretationRequest ...
+type RecordRLAInterpretationRequest struct {
+	Draw     uint64 `json:"draw"`
+	BallotID string `json:"ballot_id"`
+	Paper    uint16 `json:"paper"`
+	Auditor  string `json:"auditor"`
+}
+
+func (s *Server) recordRLAInterpretationHandler(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var payload RecordRLAInterpretationRequest
+	if err := decoder.Decode(&payload); err != nil {
+		writeAPIErrorResponse(r, w, http.StatusBadRequest, ErrCodeInvalidJSON, nil,
+			fmt.Sprintf("error parsing request body: %v", err))
+		return
+	}
+	validPaper := payload.Paper == 0
+	for _, candidate := range election.Candidates {
+		validPaper = validPaper || payload.Paper == candidate.ID
+	}
+	if !validPaper {
+		writeValidationErrorResponse(r, w, validationErrors{{Field: "paper",
+			Message: "paper must be a candidate ID or 0 if the paper ballot holds no valid vote"}})
+		return
+	}
+
+	// the draw expected next is only known under the lock
+	unlock := s.locks.Lock(rlaInterpretationPrefix)
+	defer unlock()
+	commitment, seed, interpretations, ballotIDs, err := s.rlaCommittedFrame(r.Context())
+	if err != nil {
+		writeRLAErrorResponse(r, w, err)
+		return
+	}
+	if rlaStatus(commitment, seed, interpretations).Status != rlaInProgress {
+		writeAPIErrorResponse(r, w, http.StatusConflict, ErrCodeRLAConcluded, nil,
+			"RLA has already been concluded")
+		return
+	}
+	// the risk measure is sequential: draws must be recorded in order
+	if expected := uint64(len(interpretations)) + 1; payload.Draw != expected {
+		writeErrorResponse(r, w, http.StatusConflict, nil,
+			fmt.Sprintf("expected interpretation of draw %d", expected))
+		return
+	}
+	ballotID := ballotIDs[rlaDraw(seed.Seed, commitment.FrameSize, payload.Draw)]
+	if payload.BallotID != ballotID {
+		writeErrorResponse(r, w, http.StatusBadRequest, nil,
+			fmt.Sprintf("draw %d has sampled ballot %s", payload.Draw, ballotID))
+		return
+	}
+
+	ballotBytes, err := s.store.Get(r.Context(), []byte(ballotPrefix+payload.BallotID), 0)
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err, "error fetching ballot")
+		return
+	}
+	interpretation := RLAInterpretation{
+		Draw:       payload.Draw,
+		BallotID:   payload.BallotID,
+		Electronic: binary.BigEndian.Uint16(ballotBytes),
+		Paper:      payload.Paper,
+		Auditor:    payload.Auditor,
+		Recorded:   time.Now(),
+	}
+	interpretationBytes, err := json.Marshal(&interpretation)
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error JSON-marshaling RLA interpretation")
+		return
+	}
+	interpretationKey := []byte(fmt.Sprintf("%s%010d", rlaInterpretationPrefix, payload.Draw))
+	if err := s.store.Set(r.Context(), interpretationKey, interpretationBytes); err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err,
+			"error persisting RLA interpretation")
+		return
+	}
+
+	writeJSONResponse(r, w, http.StatusOK,
+		rlaStatus(commitment, seed, append(interpretations, interpretation)))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowSetStore is a store which takes a while to set the keys with a prefix,
+// which widens the window of the races between reading and setting them
+type slowSetStore struct {
+	memStore
+	prefix string
+}
+
+// Set ...
+func (s slowSetStore) Set(ctx context.Context, key []byte, value []byte) error {
+	if strings.HasPrefix(string(key), s.prefix) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	return s.memStore.Set(ctx, key, value)
+}
+
+func TestRLA(t *testing.T) {
+	_, httpServer := newTestServerOn(t, slowSetStore{memStore: newMemStore(), prefix: rlaInterpretationPrefix})
+	electionURL := httpServer.URL + apiV1Prefix + "/elections/" + electionID
+	adminURL := httpServer.URL + apiV1Prefix + "/admin/elections/" + electionID
+	for citizenID, vote := range map[string]uint16{"alice": NikkiHaley, "bob": NikkiHaley, "carol": KamalaHarris} {
+		registered := registerTestVoter(t, httpServer.URL, citizenID, "north-1-a")
+		if status := doJSON(t, http.MethodPost, electionURL+"/votes", false,
+			&VoteRequest{RegisterVoterResponse: *registered, Vote: vote}, nil); status != http.StatusNoContent {
+			t.Fatalf("voting as %s: got status %d, want %d", citizenID, status, http.StatusNoContent)
+		}
+	}
+
+	commit := &CommitRLARequest{Method: rlaBallotPolling, RiskLimit: 0.05}
+	if status := doJSON(t, http.MethodPost, adminURL+"/rla", true, commit, nil); status != http.StatusConflict {
+		t.Fatalf("committing RLA before the election is closed: got status %d, want %d", status, http.StatusConflict)
+	}
+	if status := doJSON(t, http.MethodPost, adminURL+"/certification", true, nil, nil); status != http.StatusOK {
+		t.Fatalf("closing election: got status %d, want %d", status, http.StatusOK)
+	}
+
+	var rla RLAStatus
+	if status := doJSON(t, http.MethodPost, adminURL+"/rla", true, commit, &rla); status != http.StatusOK {
+		t.Fatalf("committing RLA: got status %d, want %d", status, http.StatusOK)
+	}
+	if rla.Status != rlaAwaitingSeed || rla.Seed != nil || rla.Commitment.FrameSize != 3 {
+		t.Fatalf("got RLA status %+v, want a frame of 3 ballots awaiting the seed", rla)
+	}
+	if status := doJSON(t, http.MethodGet, electionURL+"/rla/sample", false, nil, nil); status != http.StatusConflict {
+		t.Fatalf("sampling before the seed is committed: got status %d, want %d", status, http.StatusConflict)
+	}
+
+	seed := &CommitRLASeedRequest{Seed: "31415926535897932384"}
+	if status := doJSON(t, http.MethodPost, adminURL+"/rla/seed", true, seed, &rla); status != http.StatusOK {
+		t.Fatalf("committing RLA seed: got status %d, want %d", status, http.StatusOK)
+	}
+	if rla.Status != rlaInProgress || rla.Seed == nil || rla.Seed.Seed != seed.Seed || rla.Seed.CommitmentTX == 0 {
+		t.Fatalf("got RLA status %+v, want the seed following the commitment", rla)
+	}
+	if status := doJSON(t, http.MethodPost, adminURL+"/rla/seed", true, seed, nil); status != http.StatusConflict {
+		t.Fatalf("committing RLA seed twice: got status %d, want %d", status, http.StatusConflict)
+	}
+
+	var draws []RLASampleDraw
+	if status := doJSON(t, http.MethodGet, electionURL+"/rla/sample?count=1", false, nil, &draws); status != http.StatusOK ||
+		len(draws) != 1 || len(draws[0].BallotID) == 0 {
+		t.Fatalf("sampling: got status %d, draws %+v, want 1 draw", status, draws)
+	}
+
+	// concurrent interpretations of the same draw: only one is recorded
+	const concurrency = 10
+	var wg sync.WaitGroup
+	statuses := make(chan int, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- doJSON(t, http.MethodPost, adminURL+"/rla/interpretations", true, &RecordRLAInterpretationRequest{
+				Draw:     1,
+				BallotID: draws[0].BallotID,
+				Paper:    NikkiHaley,
+			}, nil)
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	var recorded int
+	for status := range statuses {
+		if status == http.StatusOK {
+			recorded++
+		} else if status != http.StatusConflict {
+			t.Errorf("recording interpretation: got status %d, want %d or %d", status, http.StatusOK, http.StatusConflict)
+		}
+	}
+	if status := doJSON(t, http.MethodGet, electionURL+"/rla", false, nil, &rla); recorded != 1 || status != http.StatusOK ||
+		rla.SampleSize != 1 {
+		t.Errorf("got %d interpretations recorded, RLA status %+v, want 1", recorded, rla)
+	}
+}
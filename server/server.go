@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/codenotary/immudb/pkg/signer"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server serves the immuvoting HTTP API from the given store
+type Server struct {
+	store         Store
+	adminUser     string
+	adminPassword string
+	// serializes the registrations of the same citizen and the votes on the
+	// same voter or ballot
+	locks keyLocks
+	// set when the server is shutting down (see Drain)
+	draining int32
+	// closed when the server is shutting down, to end the streams
+	drained   chan struct{}
+	drainOnce sync.Once
+	// pushes the new states to the streams
+	checkpoints *checkpointHub
+	// the tally, as persisted by the last write (see tally.go)
+	tallyMu sync.Mutex
+	tally   *Tally
+	tallyTX uint64
+	// the tx at which the election has been closed, if it has (see
+	// certification.go)
+	closedTX uint64
+	// the officials who can sign the results, and how many of them must sign
+	// for the results to be certified
+	officials []Official
+	quorum    int
+	// signs the states served by the server, if it has a signing key
+	stateSigner signer.Signer
+	// the turnout counted from the tx log so far (see turnout.go)
+	turnout *turnoutLog
+}
+
+// NewServer ...
+func NewServer(
+	store Store,
+	adminUser string,
+	adminPassword string,
+	officials []Official,
+	quorum int,
+	stateSigner signer.Signer,
+) *Server {
+	if quorum <= 0 {
+		quorum = len(officials)
+	}
+	s := &Server{
+		store:         instrumentedStore{Store: store},
+		adminUser:     adminUser,
+		adminPassword: adminPassword,
+		officials:     officials,
+		quorum:        quorum,
+		stateSigner:   stateSigner,
+		drained:       make(chan struct{}),
+		tally:         newTally(),
+		turnout:       newTurnoutLog(),
+	}
+	s.checkpoints = newCheckpointHub(s.store)
+	return s
+}
+
+// Handler returns the HTTP handlers of the API
+func (s *Server) Handler() http.Handler {
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	router.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	handle := func(route string, handler http.HandlerFunc, methods ...string) {
+		router.HandleFunc(route, instrument(route, handler)).Methods(methods...)
+	}
+
+	handle("/healthz", s.healthzHandler, http.MethodGet)
+	handle("/readyz", s.readyzHandler, http.MethodGet)
+	router.Handle("/metrics", promhttp.HandlerFor(s.metricsRegistry(), promhttp.HandlerOpts{}))
+
+	routes := s.apiV1Routes()
+	for _, route := range routes {
+		// the CORS middleware answers the preflight requests
+		handle(apiV1Prefix+route.path, s.apiV1Handler(route), route.method, http.MethodOptions)
+	}
+	handle(apiV1Prefix+"/openapi.json", cors(openAPIHandler(openAPIDocument(routes))),
+		http.MethodGet, http.MethodOptions)
+
+	// legacy (unversioned) routes, deprecated in favor of /api/v1
+	handle("/register-voter", chain(s.registerVoterHandler, cors, s.idempotent), http.MethodPost, http.MethodOptions)
+	handle("/vote", chain(s.voteHandler, cors, s.idempotent), http.MethodPost, http.MethodOptions)
+	handle("/voter-status", cors(s.getVoterStatusHandler), http.MethodGet, http.MethodOptions)
+	handle("/ballot", cors(s.getBallotHandler), http.MethodGet, http.MethodOptions)
+	handle("/random-ballot", cors(s.getRandomBallotHandler), http.MethodGet, http.MethodOptions)
+	handle("/state", cors(s.getStateHandler), http.MethodGet, http.MethodOptions)
+	handle("/verifiable-tx", cors(s.getVerifiableTransactionHandler), http.MethodGet, http.MethodOptions)
+	handle("/stats", cors(s.getStatsHandler), http.MethodGet, http.MethodOptions)
+	// NOTE: to add a handler, add an apiRoute to apiV1Routes (with admin: true
+	// if it requires auth), not a legacy route
+	return withRequestID(router)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/padurean/immuvoting/memstore"
+)
+
+const (
+	testAdminUser     = "admin"
+	testAdminPassword = "test-admin-password"
+)
+
+func TestMain(m *testing.M) {
+	// the tests check the responses, not the logs
+	logger.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+// memStore is the in-memory store of the tests: its not found errors are
+// mapped as those of the embedded store
+type memStore struct {
+	*memstore.Store
+}
+
+var _ Store = memStore{}
+
+func newMemStore() memStore {
+	return memStore{Store: memstore.New("defaultdb")}
+}
+
+// Get ...
+func (s memStore) Get(ctx context.Context, key []byte, txID uint64) ([]byte, error) {
+	value, err := s.Store.Get(ctx, key, txID)
+	return value, embeddedErr(err)
+}
+
+// GetLatest ...
+func (s memStore) GetLatest(ctx context.Context, key []byte) ([]byte, error) {
+	value, err := s.Store.GetLatest(ctx, key)
+	return value, embeddedErr(err)
+}
+
+// GetLatestEntry ...
+func (s memStore) GetLatestEntry(ctx context.Context, key []byte) (*schema.Entry, error) {
+	entry, err := s.Store.GetLatestEntry(ctx, key)
+	return entry, embeddedErr(err)
+}
+
+// VerifiedGet ...
+func (s memStore) VerifiedGet(ctx context.Context, key []byte) (*schema.Entry, error) {
+	entry, err := s.Store.VerifiedGet(ctx, key)
+	return entry, embeddedErr(err)
+}
+
+// VerifiableGetAt ...
+func (s memStore) VerifiableGetAt(
+	ctx context.Context,
+	key []byte,
+	atTx uint64,
+	proveSinceTx uint64,
+) (*schema.VerifiableEntry, error) {
+	verifiableEntry, err := s.Store.VerifiableGetAt(ctx, key, atTx, proveSinceTx)
+	return verifiableEntry, embeddedErr(err)
+}
+
+// History ...
+func (s memStore) History(ctx context.Context, key []byte, offset uint64, limit uint64) ([]*schema.Entry, error) {
+	entries, err := s.Store.History(ctx, key, offset, limit)
+	return entries, embeddedErr(err)
+}
+
+// newTestServer returns a server of the default election on an in-memory
+// store, along with the HTTP server of its API
+func newTestServer(t testing.TB) (*Server, *httptest.Server) {
+	t.Helper()
+	return newTestServerOn(t, newMemStore())
+}
+
+// newTestServerOn returns a server of the default election on the given store,
+// along with the HTTP server of its API
+func newTestServerOn(t testing.TB, store Store) (*Server, *httptest.Server) {
+	t.Helper()
+	if err := persistElection(context.Background(), store, &election); err != nil {
+		t.Fatalf("error persisting election definition: %v", err)
+	}
+	server := NewServer(store, testAdminUser, testAdminPassword, nil, 0, nil)
+	if err := server.LoadTally(context.Background()); err != nil {
+		t.Fatalf("error loading tally: %v", err)
+	}
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(func() {
+		server.Drain()
+		httpServer.Close()
+	})
+	return server, httpServer
+}
+
+// buildCommand builds the command of the package (e.g. ./verifier) into a temp
+// dir and returns its path
+func buildCommand(t *testing.T, pkg string) string {
+	t.Helper()
+	command := filepath.Join(t.TempDir(), filepath.Base(pkg))
+	if out, err := exec.Command("go", "build", "-o", command, pkg).CombinedOutput(); err != nil {
+		t.Fatalf("error building %s: %v: %s", pkg, err, out)
+	}
+	return command
+}
+
+// doJSON sends the request with the JSON of the payload, if any, and decodes
+// the JSON response into out, if any; it returns the response status
+func doJSON(t *testing.T, method string, url string, admin bool, payload interface{}, out interface{}) int {
+	t.Helper()
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			t.Fatalf("error JSON-marshaling request payload: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		t.Fatalf("error creating request %s %s: %v", method, url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if admin {
+		req.SetBasicAuth(testAdminUser, testAdminPassword)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error executing request %s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("error JSON-unmarshaling response of %s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// registerTestVoter registers a voter of the given precinct via the API
+func registerTestVoter(t *testing.T, baseURL string, citizenID string, precinct string) *RegisterVoterResponse {
+	t.Helper()
+	var registered RegisterVoterResponse
+	if status := doJSON(t, http.MethodPost, baseURL+apiV1Prefix+"/elections/"+electionID+"/voters", false,
+		&RegisterVoterRequest{
+			CitizenID: citizenID,
+			Name:      "Voter " + citizenID,
+			Address:   "1 Main Street",
+			Email:     citizenID + "@example.com",
+			Precinct:  precinct,
+		}, &registered); status != http.StatusOK {
+		t.Fatalf("registering citizen %s: got status %d, want %d", citizenID, status, http.StatusOK)
+	}
+	return &registered
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/signer"
+)
+
+// signState signs the state as immudb does, so that it can be verified against
+// the public key of the server, which the verifiers pin; without a signer, the
+// state is left as is, i.e. signed by immudb if it runs with a signing key
+func signState(stateSigner signer.Signer, state *schema.ImmutableState) error {
+	if stateSigner == nil {
+		return nil
+	}
+	signature, publicKey, err := stateSigner.Sign(state.ToBytes())
+	if err != nil {
+		return fmt.Errorf("error signing state at tx %d: %v", state.GetTxId(), err)
+	}
+	state.Signature = &schema.Signature{Signature: signature, PublicKey: publicKey}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+// Store is the storage used by the server: it is implemented by the immudb
+// client (ImmudbClient) and by the embedded immudb database (EmbeddedStore)
+type Store interface {
+	// Ping checks that the database is reachable and healthy
+	Ping(ctx context.Context) error
+	// Database returns the name of the immudb database
+	Database() string
+	Get(ctx context.Context, key []byte, txID uint64) ([]byte, error)
+	GetLatest(ctx context.Context, key []byte) ([]byte, error)
+	GetLatestEntry(ctx context.Context, key []byte) (*schema.Entry, error)
+	VerifiedGet(ctx context.Context, key []byte) (*schema.Entry, error)
+	VerifiableGetAt(ctx context.Context, key []byte, atTx uint64, proveSinceTx uint64) (*schema.VerifiableEntry, error)
+	Set(ctx context.Context, key []byte, value []byte) error
+	ExecAll(ctx context.Context, ops *schema.ExecAllRequest) (uint64, error)
+	Scan(ctx context.Context, prefix []byte, limit uint64, seekKey []byte, desc bool) ([]*schema.Entry, error)
+	// History returns up to limit values of the key, oldest first, skipping the
+	// first offset ones
+	History(ctx context.Context, key []byte, offset uint64, limit uint64) ([]*schema.Entry, error)
+	CurrentState(ctx context.Context) (*schema.ImmutableState, error)
+	VerifiableTXByID(ctx context.Context, serverTX uint64, localTX uint64) (*schema.VerifiableTx, error)
+	TxScan(ctx context.Context, initialTX uint64, limit uint32) ([]*schema.Tx, error)
+}
+
+// errInvalidCursor is returned for a cursor which has not been returned by
+// scanPage
+var errInvalidCursor = errors.New("cursor is invalid")
+
+var (
+	_ Store = (*ImmudbClient)(nil)
+	_ Store = (*EmbeddedStore)(nil)
+)
+
+// scanAll returns all entries with the given prefix, in key order, fetching
+// them page by page (a single scan returns at most database.MaxKeyScanLimit)
+func scanAll(ctx context.Context, store Store, prefix []byte) ([]*schema.Entry, error) {
+	var all []*schema.Entry
+	err := scanEach(ctx, store, prefix, func(entry *schema.Entry) error {
+		all = append(all, entry)
+		return nil
+	})
+	return all, err
+}
+
+// scanEach calls fn with each entry with the given prefix, in key order,
+// fetching them page by page, until fn returns an error
+func scanEach(ctx context.Context, store Store, prefix []byte, fn func(*schema.Entry) error) error {
+	var seekKey []byte
+	for {
+		entries, err := store.Scan(ctx, prefix, database.MaxKeyScanLimit, seekKey, false)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		if len(entries) < database.MaxKeyScanLimit {
+			return nil
+		}
+		seekKey = keyAfter(entries[len(entries)-1].GetKey())
+	}
+}
+
+// keyAfter returns the smallest key after the given one
+func keyAfter(key []byte) []byte {
+	return append(append([]byte{}, key...), 0)
+}
+
+// scanPage returns a page of up to limit entries with the given prefix, in key
+// order, starting after the cursor (from the start if empty), along with the
+// cursor of the next page (empty if this is the last one)
+func scanPage(ctx context.Context, store Store, prefix []byte, cursor string, limit uint64) ([]*schema.Entry, string, error) {
+	var seekKey []byte
+	if len(cursor) > 0 {
+		// the cursor is the last key of the previous page, without the prefix:
+		// opaque to the clients, which must pass it back as is
+		lastKey, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", errInvalidCursor
+		}
+		seekKey = keyAfter(append(append([]byte{}, prefix...), lastKey...))
+	}
+	entries, err := store.Scan(ctx, prefix, limit, seekKey, false)
+	if err != nil {
+		return nil, "", err
+	}
+	var nextCursor string
+	if uint64(len(entries)) == limit {
+		lastKey := bytes.TrimPrefix(entries[len(entries)-1].GetKey(), prefix)
+		nextCursor = base64.RawURLEncoding.EncodeToString(lastKey)
+	}
+	return entries, nextCursor, nil
+}
+
+// historyAll returns all values of the key, oldest first, fetching them page by
+// page (a single call returns at most database.MaxKeyScanLimit)
+func historyAll(ctx context.Context, store Store, key []byte) ([]*schema.Entry, error) {
+	var all []*schema.Entry
+	for {
+		entries, err := store.History(ctx, key, uint64(len(all)), database.MaxKeyScanLimit)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, entries...)
+		if len(entries) < database.MaxKeyScanLimit {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+// setTestKeys sets the keys prefix00000, prefix00001 etc., nbKeys in all, with
+// their index as value, a hundred per tx
+func setTestKeys(t *testing.T, store Store, prefix string, nbKeys int) {
+	t.Helper()
+	var ops []*schema.Op
+	for i := 0; i < nbKeys; i++ {
+		ops = append(ops, &schema.Op{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{
+			Key:   []byte(fmt.Sprintf("%s%05d", prefix, i)),
+			Value: []byte(fmt.Sprint(i)),
+		}}})
+		if len(ops) == 100 || i == nbKeys-1 {
+			if _, err := store.ExecAll(context.Background(), &schema.ExecAllRequest{Operations: ops}); err != nil {
+				t.Fatalf("error setting keys: %v", err)
+			}
+			ops = nil
+		}
+	}
+}
+
+// checkEachKeyOnce checks that the entries are the keys set by setTestKeys, in
+// order, each exactly once
+func checkEachKeyOnce(t *testing.T, entries []*schema.Entry, prefix string, nbKeys int) {
+	t.Helper()
+	if len(entries) != nbKeys {
+		t.Errorf("got %d entries, want %d", len(entries), nbKeys)
+	}
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		key := string(entry.GetKey())
+		if seen[key] {
+			t.Errorf("got key %s more than once", key)
+		}
+		seen[key] = true
+		if want := fmt.Sprintf("%s%05d", prefix, i); key != want {
+			t.Errorf("got key %s at %d, want %s", key, i, want)
+		}
+	}
+}
+
+func TestScanAcrossPages(t *testing.T) {
+	store := newMemStore()
+	ctx := context.Background()
+	// more keys than a single scan returns, surrounded by keys of other
+	// prefixes
+	nbKeys := 2*database.MaxKeyScanLimit + 5
+	setTestKeys(t, store, "a:", 10)
+	setTestKeys(t, store, "b:", nbKeys)
+	setTestKeys(t, store, "c:", 10)
+
+	all, err := scanAll(ctx, store, []byte("b:"))
+	if err != nil {
+		t.Fatalf("error scanning all keys: %v", err)
+	}
+	checkEachKeyOnce(t, all, "b:", nbKeys)
+
+	// a small limit, with the cursor of each page passed back
+	var paged []*schema.Entry
+	var nbPages int
+	for cursor := ""; nbPages == 0 || len(cursor) > 0; nbPages++ {
+		var entries []*schema.Entry
+		entries, cursor, err = scanPage(ctx, store, []byte("b:"), cursor, 7)
+		if err != nil {
+			t.Fatalf("error scanning page %d: %v", nbPages, err)
+		}
+		if len(entries) > 7 {
+			t.Fatalf("got %d entries in page %d, want at most 7", len(entries), nbPages)
+		}
+		paged = append(paged, entries...)
+	}
+	checkEachKeyOnce(t, paged, "b:", nbKeys)
+	if want := (nbKeys + 6) / 7; nbPages != want {
+		t.Errorf("got %d pages, want %d", nbPages, want)
+	}
+
+	if _, _, err := scanPage(ctx, store, []byte("b:"), "not base64!", 7); !errors.Is(err, errInvalidCursor) {
+		t.Errorf("scanning with an invalid cursor: got %v, want %v", err, errInvalidCursor)
+	}
+}
+
+func TestHistoryAcrossPages(t *testing.T) {
+	store := newMemStore()
+	ctx := context.Background()
+	key := []byte("key")
+	nbValues := database.MaxKeyScanLimit + 3
+	for i := 0; i < nbValues; i++ {
+		if err := store.Set(ctx, key, []byte(fmt.Sprint(i))); err != nil {
+			t.Fatalf("error setting value %d: %v", i, err)
+		}
+	}
+
+	history, err := historyAll(ctx, store, key)
+	if err != nil {
+		t.Fatalf("error fetching history: %v", err)
+	}
+	if len(history) != nbValues {
+		t.Fatalf("got %d values, want %d", len(history), nbValues)
+	}
+	for i, entry := range history {
+		if want := fmt.Sprint(i); string(entry.GetValue()) != want {
+			t.Errorf("got value %s at %d, want %s", entry.GetValue(), i, want)
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// tallyKey holds the tally, updated in the same tx as each registration, vote
+// and revocation, so that it is never out of sync with the voters and the
+// ballots and is covered by the same proofs
+const tallyKey = "immuvoting:tally"
+
+// Counts are the registration and voting stats and the results; a revoked
+// voter is neither registered nor voted, but a ballot already cast can not be
+// changed: it is still counted, and also counted as orphaned until adjudicated
+type Counts struct {
+	Registered uint64            `json:"registered"`
+	Voted      uint64            `json:"voted"`
+	Ballots    uint64            `json:"ballots"`
+	Orphaned   uint64            `json:"orphaned"`
+	Results    map[uint16]uint64 `json:"results"`
+}
+
+func newCounts() *Counts {
+	return &Counts{Results: map[uint16]uint64{}}
+}
+
+// add adds the other counts to these ones
+func (c *Counts) add(other *Counts) {
+	c.Registered += other.Registered
+	c.Voted += other.Voted
+	c.Ballots += other.Ballots
+	c.Orphaned += other.Orphaned
+	for vote, count := range other.Results {
+		c.Results[vote] += count
+	}
+}
+
+// Tally is the persisted tally: the counts of the whole election and those of
+// each precinct (the voters and the ballots registered before the precincts
+// were introduced are only counted in the former)
+type Tally struct {
+	Counts
+	Precincts map[string]*Counts `json:"precincts"`
+}
+
+func newTally() *Tally {
+	return &Tally{Counts: *newCounts(), Precincts: map[string]*Counts{}}
+}
+
+// copy returns a deep copy of the tally
+func (t *Tally) copy() *Tally {
+	tallyCopy := newTally()
+	tallyCopy.add(&t.Counts)
+	for precinct, counts := range t.Precincts {
+		tallyCopy.Precincts[precinct] = newCounts()
+		tallyCopy.Precincts[precinct].add(counts)
+	}
+	return tallyCopy
+}
+
+// count applies the change to the counts of the election and, if any, of the
+// precinct
+func (t *Tally) count(precinct string, change func(*Counts)) {
+	change(&t.Counts)
+	if len(precinct) == 0 {
+		return
+	}
+	counts, ok := t.Precincts[precinct]
+	if !ok {
+		counts = newCounts()
+		t.Precincts[precinct] = counts
+	}
+	change(counts)
+}
+
+// LoadTally loads the tally persisted in immudb; if there is none yet (e.g. the
+// database predates it), it computes it from the voters and the ballots and
+// persists it
+func (s *Server) LoadTally(ctx context.Context) error {
+	s.tallyMu.Lock()
+	defer s.tallyMu.Unlock()
+
+	entry, err := s.store.GetLatestEntry(ctx, []byte(tallyKey))
+	if err == nil {
+		tally, err := unmarshalTally(entry.GetValue())
+		if err != nil {
+			return err
+		}
+		s.tally, s.tallyTX = tally, entry.GetTx()
+		return nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("error fetching tally: %v", err)
+	}
+
+	tally, err := s.scanTally(ctx)
+	if err != nil {
+		return err
+	}
+	txID, err := s.store.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{tallyOp(tally)}})
+	if err != nil {
+		return fmt.Errorf("error persisting tally: %v", err)
+	}
+	s.tally, s.tallyTX = tally, txID
+	logger.WithField("tx_id", txID).Info("tally computed from the voters and the ballots")
+	return nil
+}
+
+func unmarshalTally(tallyBytes []byte) (*Tally, error) {
+	tally := newTally()
+	if err := json.Unmarshal(tallyBytes, tally); err != nil {
+		return nil, fmt.Errorf("error JSON-unmarshaling tally: %v", err)
+	}
+	if tally.Results == nil {
+		tally.Results = map[uint16]uint64{}
+	}
+	if tally.Precincts == nil {
+		// persisted before the precincts were introduced
+		tally.Precincts = map[string]*Counts{}
+	}
+	return tally, nil
+}
+
+// tallyOp returns the op which persists the tally
+func tallyOp(tally *Tally) *schema.Op {
+	// the tally always marshals
+	tallyBytes, _ := json.Marshal(tally)
+	return &schema.Op{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: []byte(tallyKey), Value: tallyBytes}}}
+}
+
+// execAllAndTally executes the ops together with the update of the tally made
+// by change, in a single tx; once the election has been closed, it fails with
+// errElectionClosed.
+//
+// The writes of the tally are serialized, the immudb round trip included: each
+// tx persists the whole tally, which must start from the one of the previous
+// tx, and immudb (as of v0.9) has no conditional writes, which would let the
+// writes race and retry the ones which lost. Hence all the registrations and
+// the votes are written one at a time, at most one per round trip to immudb
+// (which commits them one at a time anyway): see BenchmarkExecAllAndTally for
+// the throughput at a given latency
+func (s *Server) execAllAndTally(ctx context.Context, ops []*schema.Op, change func(*Tally)) error {
+	s.tallyMu.Lock()
+	defer s.tallyMu.Unlock()
+
+	if s.closedTX > 0 {
+		return fmt.Errorf("%w at tx %d", errElectionClosed, s.closedTX)
+	}
+	tally := s.tally.copy()
+	change(tally)
+	txID, err := s.store.ExecAll(ctx, &schema.ExecAllRequest{Operations: append(ops, tallyOp(tally))})
+	if err != nil {
+		return err
+	}
+	s.tally, s.tallyTX = tally, txID
+	return nil
+}
+
+// scanTally computes the tally from all the voters and the ballots
+func (s *Server) scanTally(ctx context.Context) (*Tally, error) {
+	tally := newTally()
+
+	if err := scanEach(ctx, s.store, []byte(voterPrefix), func(voterEntry *schema.Entry) error {
+		var voter Voter
+		if err := json.Unmarshal(voterEntry.GetValue(), &voter); err != nil {
+			contextLogger(ctx).WithError(err).WithField("key", string(voterEntry.GetKey())).
+				Error("error JSON-unmarshaling voter")
+			return nil
+		}
+		tally.count(voter.Precinct, func(counts *Counts) {
+			switch {
+			case !voter.Revoked.IsZero() && !voter.Voted.IsZero():
+				counts.Orphaned++
+			case !voter.Revoked.IsZero():
+			case !voter.Voted.IsZero():
+				counts.Registered++
+				counts.Voted++
+			default:
+				counts.Registered++
+			}
+		})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("error scanning voters: %v", err)
+	}
+
+	ballotPrecincts := map[string]string{}
+	if err := scanEach(ctx, s.store, []byte(ballotPrecinctPrefix), func(entry *schema.Entry) error {
+		ballotID := strings.TrimPrefix(string(entry.GetKey()), ballotPrecinctPrefix)
+		ballotPrecincts[ballotID] = string(entry.GetValue())
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("error scanning ballot precincts: %v", err)
+	}
+
+	if err := scanEach(ctx, s.store, []byte(ballotPrefix), func(ballotEntry *schema.Entry) error {
+		vote := binary.BigEndian.Uint16(ballotEntry.GetValue())
+		switch vote {
+		case KamalaHarris, NikkiHaley:
+			ballotID := strings.TrimPrefix(string(ballotEntry.GetKey()), ballotPrefix)
+			tally.count(ballotPrecincts[ballotID], func(counts *Counts) {
+				counts.Results[vote]++
+				counts.Ballots++
+			})
+		case 0:
+			// nothing to do: this ballot has not been cast yet
+		default:
+			contextLogger(ctx).WithFields(logrus.Fields{
+				"key":  string(ballotEntry.GetKey()),
+				"vote": vote,
+			}).Error("ballot has invalid vote")
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("error scanning ballots: %v", err)
+	}
+
+	return tally, nil
+}
+
+// ReconcileTally verifies the tally against a full scan of the voters and the
+// ballots, every interval, until the server is drained
+func (s *Server) ReconcileTally(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.drained:
+			return
+		case <-ticker.C:
+		}
+		result, err := s.reconcileTally(context.Background())
+		if err != nil {
+			logger.WithError(err).Warn("error reconciling tally")
+			result = "error"
+		}
+		tallyReconciliations.WithLabelValues(result).Inc()
+	}
+}
+
+// reconcileTally compares the tally with a full scan, without blocking the
+// writes while scanning: if the tally has been written in the meantime the scan
+// may be stale, so the reconciliation is skipped until the next interval;
+// a wrong tally is logged and replaced with the scanned one, unless the
+// election has been closed: the tally is then frozen at the closing tx, like
+// all the other writes (see execAllAndTally)
+func (s *Server) reconcileTally(ctx context.Context) (string, error) {
+	s.tallyMu.Lock()
+	tallyTX, closedTX := s.tallyTX, s.closedTX
+	s.tallyMu.Unlock()
+	if closedTX > 0 {
+		return "skipped", nil
+	}
+
+	// the scans do not wait for the index, the get does
+	if _, err := s.store.GetLatestEntry(ctx, []byte(tallyKey)); err != nil {
+		return "", fmt.Errorf("error fetching tally: %v", err)
+	}
+	scanned, err := s.scanTally(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	s.tallyMu.Lock()
+	defer s.tallyMu.Unlock()
+	if s.tallyTX != tallyTX || s.closedTX > 0 {
+		return "skipped", nil
+	}
+	if reflect.DeepEqual(scanned, s.tally) {
+		return "ok", nil
+	}
+	logger.WithFields(logrus.Fields{
+		"tally":   s.tally,
+		"scanned": scanned,
+		"tx_id":   tallyTX,
+	}).Error("tally does not match the voters and the ballots: replacing it")
+	txID, err := s.store.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{tallyOp(scanned)}})
+	if err != nil {
+		return "", fmt.Errorf("error persisting reconciled tally: %v", err)
+	}
+	s.tally, s.tallyTX = scanned, txID
+	return "mismatch", nil
+}
+
+// UnitStats are the stats of a geographic unit, with those of its units
+type UnitStats struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	Counts
+	Units []UnitStats `json:"units,omitempty"`
+}
+
+// unitStats rolls the counts of the precincts up to the units and their
+// parents
+func unitStats(units []Unit, depth int, precincts map[string]*Counts) []UnitStats {
+	stats := make([]UnitStats, 0, len(units))
+	for _, unit := range units {
+		us := UnitStats{ID: unit.ID, Name: unit.Name, Level: unitLevels[depth], Counts: *newCounts()}
+		if depth == len(unitLevels)-1 {
+			if counts, ok := precincts[unit.ID]; ok {
+				us.add(counts)
+			}
+		} else {
+			us.Units = unitStats(unit.Units, depth+1, precincts)
+			for i := range us.Units {
+				us.add(&us.Units[i].Counts)
+			}
+		}
+		stats = append(stats, us)
+	}
+	return stats
+}
+
+// findUnitStats returns the stats of the unit with the given ID, if any
+func findUnitStats(stats []UnitStats, id string) *UnitStats {
+	for i := range stats {
+		if stats[i].ID == id {
+			return &stats[i]
+		}
+		if found := findUnitStats(stats[i].Units, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// txAt returns the ID of the latest tx committed at or before the time, if any
+// (0 otherwise), searching the txs up to the given one
+func (s *Server) txAt(ctx context.Context, t time.Time, latestTX uint64) (uint64, error) {
+	var searchErr error
+	// the txs are committed in time order
+	nbTXs := sort.Search(int(latestTX), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		txs, err := s.store.TxScan(ctx, uint64(i)+1, 1)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return len(txs) == 0 || txs[0].GetMetadata().GetTs() > t.Unix()
+	})
+	if searchErr != nil {
+		return 0, fmt.Errorf("error searching tx at %s: %v", t.Format(time.RFC3339), searchErr)
+	}
+	return uint64(nbTXs), nil
+}
+
+// tallyAsOf returns the tally as of the given tx (or the latest one committed at
+// the given time), that is its latest version written at or before that tx,
+// with the tx in which it has been written; the current one if neither is given
+func (s *Server) tallyAsOf(ctx context.Context, asOfTX uint64, asOfTime time.Time) (*Tally, uint64, error) {
+	s.tallyMu.Lock()
+	tally, tallyTX := s.tally.copy(), s.tallyTX
+	s.tallyMu.Unlock()
+	if asOfTX == 0 && asOfTime.IsZero() {
+		return tally, tallyTX, nil
+	}
+
+	state, err := s.store.CurrentState(ctx)
+	if err != nil {
+		return nil, 0, internalError(err, "error fetching current state")
+	}
+	if !asOfTime.IsZero() {
+		if asOfTX, err = s.txAt(ctx, asOfTime, state.GetTxId()); err != nil {
+			return nil, 0, internalError(err, "error searching tx")
+		}
+	} else if asOfTX > state.GetTxId() {
+		return nil, 0, newAPIError(http.StatusBadRequest, ErrCodeBadRequest, nil,
+			fmt.Sprintf("tx %d has not been committed yet", asOfTX))
+	}
+	if asOfTX >= tallyTX {
+		return tally, tallyTX, nil
+	}
+
+	// the history does not wait for the index, the get does
+	if _, err := s.store.GetLatestEntry(ctx, []byte(tallyKey)); err != nil {
+		return nil, 0, internalError(err, "error fetching tally")
+	}
+	var searchErr error
+	// after tells whether the version of the tally at the offset of its history
+	// has been written after the tx (or does not exist)
+	after := func(offset int) bool {
+		if searchErr != nil {
+			return true
+		}
+		entries, err := s.store.History(ctx, []byte(tallyKey), uint64(offset), 1)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return len(entries) == 0 || entries[0].GetTx() > asOfTX
+	}
+	nbVersions := 1
+	for !after(nbVersions) {
+		nbVersions *= 2
+	}
+	offset := sort.Search(nbVersions, after)
+	if searchErr != nil {
+		return nil, 0, internalError(searchErr, "error searching tally history")
+	}
+	if offset == 0 {
+		return nil, 0, newAPIError(http.StatusNotFound, ErrCodeTallyNotFound, nil,
+			fmt.Sprintf("no tally as of tx %d", asOfTX))
+	}
+	entries, err := s.store.History(ctx, []byte(tallyKey), uint64(offset-1), 1)
+	if err != nil || len(entries) == 0 {
+		return nil, 0, internalError(err, "error fetching tally history")
+	}
+	if tally, err = unmarshalTally(entries[0].GetValue()); err != nil {
+		return nil, 0, internalError(err, "error reading tally history")
+	}
+	return tally, entries[0].GetTx(), nil
+}
+
+// stats returns the stats of the election, or of the requested unit, with the
+// rollups of the units below, from the tally: in constant time, whatever the
+// number of voters (as of a past tx, in the number of versions of the tally)
+func (s *Server) stats(ctx context.Context, req *GetStatsRequest) (*GetStatsResponse, error) {
+	if req.AsOfTX > 0 && !req.AsOfTime.IsZero() {
+		return nil, newAPIError(http.StatusBadRequest, ErrCodeBadRequest, nil,
+			"as of tx and as of time are mutually exclusive")
+	}
+	tally, tallyTX, err := s.tallyAsOf(ctx, req.AsOfTX, req.AsOfTime)
+	if err != nil {
+		return nil, err
+	}
+
+	regions := unitStats(election.Regions, 0, tally.Precincts)
+	if len(req.Unit) == 0 {
+		return &GetStatsResponse{TXID: tallyTX, Counts: tally.Counts, Units: regions}, nil
+	}
+	found := findUnitStats(regions, req.Unit)
+	if found == nil {
+		return nil, newAPIError(http.StatusNotFound, ErrCodeUnitNotFound, nil,
+			fmt.Sprintf("no such unit %s", req.Unit))
+	}
+	return &GetStatsResponse{TXID: tallyTX, Unit: found.ID, Counts: found.Counts, Units: found.Units}, nil
+}
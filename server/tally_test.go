@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+func TestReconcileTally(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	ctx := context.Background()
+	voter := registerTestVoter(t, httpServer.URL, "alice", "north-1-a")
+	if err := server.vote(ctx, &VoteRequest{RegisterVoterResponse: *voter, Vote: NikkiHaley}); err != nil {
+		t.Fatalf("error voting: %v", err)
+	}
+	reconcile := func(want string) {
+		t.Helper()
+		if result, err := server.reconcileTally(ctx); err != nil || result != want {
+			t.Fatalf("reconciling tally: got %q, error %v, want %q", result, err, want)
+		}
+	}
+	// corrupt replaces the tally in memory with a wrong one
+	corrupt := func() *Tally {
+		server.tallyMu.Lock()
+		defer server.tallyMu.Unlock()
+		server.tally = server.tally.copy()
+		server.tally.Ballots++
+		return server.tally
+	}
+	reconcile("ok")
+
+	corrupt()
+	reconcile("mismatch")
+	scanned, err := server.scanTally(ctx)
+	if err != nil {
+		t.Fatalf("error scanning tally: %v", err)
+	}
+	if !reflect.DeepEqual(scanned, server.tally) {
+		t.Errorf("got reconciled tally %+v, want %+v", server.tally.Counts, scanned.Counts)
+	}
+
+	// once the election has been closed, the tally is not written anymore
+	if status := doJSON(t, http.MethodPost,
+		httpServer.URL+apiV1Prefix+"/admin/elections/"+electionID+"/certification",
+		true, nil, nil); status != http.StatusOK {
+		t.Fatalf("closing election: got status %d, want %d", status, http.StatusOK)
+	}
+	state, err := server.store.CurrentState(ctx)
+	if err != nil {
+		t.Fatalf("error fetching current state: %v", err)
+	}
+	corrupted := corrupt()
+	reconcile("skipped")
+	after, err := server.store.CurrentState(ctx)
+	if err != nil {
+		t.Fatalf("error fetching current state: %v", err)
+	}
+	if after.GetTxId() != state.GetTxId() || server.tally != corrupted {
+		t.Errorf("reconciling after the close: got tx %d, want no tx after tx %d and the tally unchanged",
+			after.GetTxId(), state.GetTxId())
+	}
+}
+
+// latencyStore is a store whose commits take the given latency, e.g. the round
+// trip to immudb
+type latencyStore struct {
+	memStore
+	latency time.Duration
+}
+
+// ExecAll ...
+func (s latencyStore) ExecAll(ctx context.Context, ops *schema.ExecAllRequest) (uint64, error) {
+	time.Sleep(s.latency)
+	return s.memStore.ExecAll(ctx, ops)
+}
+
+// BenchmarkExecAllAndTally registers voters concurrently: since the writes of
+// the tally are serialized, the time per registration is at least the latency
+// of a commit, whatever the concurrency
+func BenchmarkExecAllAndTally(b *testing.B) {
+	for _, latency := range []time.Duration{0, time.Millisecond} {
+		b.Run(fmt.Sprintf("latency %s", latency), func(b *testing.B) {
+			server, _ := newTestServerOn(b, latencyStore{memStore: newMemStore(), latency: latency})
+			ctx := context.Background()
+			var nbRegistered uint64
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					citizenID := strconv.FormatUint(atomic.AddUint64(&nbRegistered, 1), 10)
+					if _, err := server.registerVoter(ctx, &RegisterVoterRequest{
+						CitizenID: citizenID,
+						Name:      "Voter " + citizenID,
+						Address:   "1 Main Street",
+						Email:     citizenID + "@example.com",
+						Precinct:  "north-1-a",
+					}); err != nil {
+						b.Errorf("error registering citizen %s: %v", citizenID, err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// checkRollups checks that the counts of each unit are the sums of the ones of
+// the units below, down to the precincts
+func checkRollups(t *testing.T, units []UnitStats) {
+	t.Helper()
+	for _, unit := range units {
+		if len(unit.Units) == 0 {
+			if unit.Level != unitLevelPrecinct {
+				t.Errorf("unit %s of level %s has no units", unit.ID, unit.Level)
+			}
+			continue
+		}
+		sum := Counts{Results: map[uint16]uint64{}}
+		for _, below := range unit.Units {
+			sum.Registered += below.Registered
+			sum.Voted += below.Voted
+			sum.Ballots += below.Ballots
+			sum.Orphaned += below.Orphaned
+			for vote, count := range below.Results {
+				sum.Results[vote] += count
+			}
+		}
+		if !reflect.DeepEqual(unit.Counts, sum) {
+			t.Errorf("got counts %+v of unit %s, want the sum of its units %+v", unit.Counts, unit.ID, sum)
+		}
+		checkRollups(t, unit.Units)
+	}
+}
+
+func TestStatsByUnit(t *testing.T) {
+	_, httpServer := newTestServer(t)
+	electionURL := httpServer.URL + apiV1Prefix + "/elections/" + electionID
+	for _, registration := range []struct {
+		citizenID string
+		precinct  string
+		vote      uint16
+	}{
+		{"alice", "north-1-a", NikkiHaley},
+		{"bob", "north-1-a", 0},
+		{"carol", "north-1-b", KamalaHarris},
+		{"dave", "north-2-a", 0},
+		{"erin", "south-1-b", NikkiHaley},
+	} {
+		voter := registerTestVoter(t, httpServer.URL, registration.citizenID, registration.precinct)
+		if registration.vote == 0 {
+			continue
+		}
+		if status := doJSON(t, http.MethodPost, electionURL+"/votes", false,
+			&VoteRequest{RegisterVoterResponse: *voter, Vote: registration.vote}, nil); status != http.StatusNoContent {
+			t.Fatalf("voting as %s: got status %d, want %d", registration.citizenID, status, http.StatusNoContent)
+		}
+	}
+
+	var all GetStatsResponse
+	if status := doJSON(t, http.MethodGet, electionURL+"/stats", false, nil, &all); status != http.StatusOK {
+		t.Fatalf("fetching stats: got status %d, want %d", status, http.StatusOK)
+	}
+	checkRollups(t, []UnitStats{{ID: "election", Counts: all.Counts, Units: all.Units}})
+
+	for _, want := range []struct {
+		unit       string
+		level      string
+		registered uint64
+		voted      uint64
+		results    map[uint16]uint64
+		units      []string
+	}{
+		{"north", unitLevelRegion, 4, 2,
+			map[uint16]uint64{NikkiHaley: 1, KamalaHarris: 1}, []string{"north-1", "north-2"}},
+		{"north-1", unitLevelDistrict, 3, 2,
+			map[uint16]uint64{NikkiHaley: 1, KamalaHarris: 1}, []string{"north-1-a", "north-1-b"}},
+		{"north-1-a", unitLevelPrecinct, 2, 1, map[uint16]uint64{NikkiHaley: 1}, nil},
+		{"south", unitLevelRegion, 1, 1, map[uint16]uint64{NikkiHaley: 1}, []string{"south-1"}},
+		{"south-1-a", unitLevelPrecinct, 0, 0, map[uint16]uint64{}, nil},
+	} {
+		var stats GetStatsResponse
+		if status := doJSON(t, http.MethodGet, electionURL+"/stats?unit="+want.unit, false, nil, &stats); status != http.StatusOK {
+			t.Fatalf("fetching stats of %s: got status %d, want %d", want.unit, status, http.StatusOK)
+		}
+		var units []string
+		for _, unit := range stats.Units {
+			units = append(units, unit.ID)
+			if unit.Level == want.level {
+				t.Errorf("got unit %s of level %s below %s, want a lower level", unit.ID, unit.Level, want.unit)
+			}
+		}
+		results := stats.Results
+		if results == nil {
+			results = map[uint16]uint64{}
+		}
+		if stats.Unit != want.unit || stats.Registered != want.registered || stats.Voted != want.voted ||
+			stats.Ballots != want.voted || !reflect.DeepEqual(results, want.results) || !reflect.DeepEqual(units, want.units) {
+			t.Errorf("got stats of %s %+v with units %v, want %d registered, %d voted, results %v and units %v",
+				want.unit, stats.Counts, units, want.registered, want.voted, want.results, want.units)
+		}
+		checkRollups(t, []UnitStats{{ID: stats.Unit, Level: want.level, Counts: stats.Counts, Units: stats.Units}})
+	}
+
+	resp, err := http.Get(electionURL + "/stats?unit=nowhere")
+	if err != nil {
+		t.Fatalf("error fetching stats of unknown unit: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading stats of unknown unit: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound || errorCode(t, body) != ErrCodeUnitNotFound {
+		t.Errorf("fetching stats of unknown unit: got status %d, body %s, want %d %s",
+			resp.StatusCode, body, http.StatusNotFound, ErrCodeUnitNotFound)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// genTestCerts writes the CA, server and client certs and keys of a new local
+// CA to a temp dir, with gencerts, and returns the dir
+func genTestCerts(t *testing.T, gencerts string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if out, err := exec.Command(gencerts, "-dir", dir).CombinedOutput(); err != nil {
+		t.Fatalf("error generating certs: %v: %s", err, out)
+	}
+	return dir
+}
+
+// certDER returns the DER of the cert in the given PEM file
+func certDER(t *testing.T, certFile string) []byte {
+	t.Helper()
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("error reading cert %s: %v", certFile, err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		t.Fatalf("%s is not a PEM file", certFile)
+	}
+	return block.Bytes
+}
+
+// copyFile copies the file and sets its mod time
+func copyFile(t *testing.T, from string, to string, modTime time.Time) {
+	t.Helper()
+	fileBytes, err := ioutil.ReadFile(from)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", from, err)
+	}
+	if err := ioutil.WriteFile(to, fileBytes, 0600); err != nil {
+		t.Fatalf("error writing %s: %v", to, err)
+	}
+	if err := os.Chtimes(to, modTime, modTime); err != nil {
+		t.Fatalf("error setting mod time of %s: %v", to, err)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	gencerts := buildCommand(t, "./gencerts")
+	dir, rotatedDir := genTestCerts(t, gencerts), genTestCerts(t, gencerts)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+
+	if _, err := newCertReloader(certFile, filepath.Join(dir, "missing-key.pem"), 0); err == nil {
+		t.Errorf("loading a missing key: got no error")
+	}
+	if _, err := newCertReloader(certFile, filepath.Join(dir, "client-key.pem"), 0); err == nil {
+		t.Errorf("loading the key of another cert: got no error")
+	}
+	reloader, err := newCertReloader(certFile, keyFile, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("error loading cert: %v", err)
+	}
+
+	// the TLS config of the HTTP API (see main)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: reloader.GetCertificate})
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	httpServer := &http.Server{
+		Handler:  http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+	}
+	go httpServer.Serve(listener)
+	defer httpServer.Close()
+	// get connects to the server, trusting only the CA of the given dir
+	get := func(caDir string) error {
+		pool, err := loadCertPool(filepath.Join(caDir, "ca.pem"))
+		if err != nil {
+			t.Fatalf("error loading CA: %v", err)
+		}
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool},
+			DisableKeepAlives: true,
+		}}
+		resp, err := client.Get("https://" + listener.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(dir); err != nil {
+		t.Fatalf("connecting with the CA of the cert: %v", err)
+	}
+
+	// a half-written rotation (the cert, but not the key yet) keeps the
+	// previous cert
+	previous := certDER(t, certFile)
+	previousDir := t.TempDir()
+	copyFile(t, certFile, filepath.Join(previousDir, "server.pem"), time.Now())
+	copyFile(t, keyFile, filepath.Join(previousDir, "server-key.pem"), time.Now())
+	modTime := time.Now().Add(time.Minute)
+	copyFile(t, filepath.Join(rotatedDir, "server.pem"), certFile, modTime)
+	if cert, _ := reloader.GetCertificate(nil); !bytes.Equal(cert.Certificate[0], previous) {
+		t.Fatalf("half-written rotation: got another cert, want the previous one")
+	}
+	if err := get(dir); err != nil {
+		t.Errorf("connecting with the CA of the previous cert during the rotation: %v", err)
+	}
+
+	copyFile(t, filepath.Join(rotatedDir, "server-key.pem"), keyFile, modTime.Add(time.Minute))
+	if cert, _ := reloader.GetCertificate(nil); !bytes.Equal(cert.Certificate[0], certDER(t, certFile)) {
+		t.Fatalf("rotation: got the previous cert, want the new one")
+	}
+	if err := get(rotatedDir); err != nil {
+		t.Errorf("connecting with the CA of the rotated cert: %v", err)
+	}
+	if err := get(dir); err == nil {
+		t.Errorf("connecting with the CA of the previous cert: got no error")
+	}
+
+	// without an interval, the cert is never reloaded
+	fixed, err := newCertReloader(certFile, keyFile, 0)
+	if err != nil {
+		t.Fatalf("error loading cert: %v", err)
+	}
+	copyFile(t, filepath.Join(previousDir, "server.pem"), certFile, modTime.Add(2*time.Minute))
+	copyFile(t, filepath.Join(previousDir, "server-key.pem"), keyFile, modTime.Add(2*time.Minute))
+	if cert, _ := fixed.GetCertificate(nil); bytes.Equal(cert.Certificate[0], previous) {
+		t.Errorf("rotation without reloading: got the rotated cert reloaded")
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	turnoutDefaultInterval = time.Hour
+	// the turnout is counted by minute, then rolled up to the requested interval
+	turnoutResolution  = time.Minute
+	turnoutMaxInterval = 24 * time.Hour
+	turnoutMaxBuckets  = 10000
+	// how often the new txs are counted
+	turnoutUpdateInterval = 5 * time.Second
+)
+
+// TurnoutBucket is the turnout of an interval: the registrations (minus the
+// revocations) and the ballots cast by the txs committed within it
+type TurnoutBucket struct {
+	Start      time.Time `json:"start"`
+	Registered int64     `json:"registered"`
+	Ballots    uint64    `json:"ballots"`
+}
+
+// TurnoutResponse ...
+type TurnoutResponse struct {
+	Interval string `json:"interval"`
+	// ID of the latest tx counted
+	TXID    uint64          `json:"tx_id"`
+	Buckets []TurnoutBucket `json:"buckets"`
+}
+
+// turnoutLog is the turnout by minute (unix time / 60), counted from the tx log
+// in the background (see CountTurnout): it is extended with the txs committed
+// since the previous update, so each tx is read only once
+type turnoutLog struct {
+	mu      sync.Mutex
+	txID    uint64
+	minutes map[int64]*TurnoutBucket
+}
+
+func newTurnoutLog() *turnoutLog {
+	return &turnoutLog{minutes: map[int64]*TurnoutBucket{}}
+}
+
+// CountTurnout counts the new txs in the turnout log, every interval, until the
+// server is drained
+func (s *Server) CountTurnout(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.updateTurnout(context.Background()); err != nil {
+			logger.WithError(err).Warn("error counting turnout")
+		}
+		select {
+		case <-s.drained:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateTurnout counts the txs committed since the latest one counted; the
+// txs are read without holding the lock of the log, which is only held to
+// merge the counts of each batch, so the reads of the log never wait for the
+// store (it must not be called concurrently)
+func (s *Server) updateTurnout(ctx context.Context) error {
+	l := s.turnout
+	state, err := s.store.CurrentState(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching current state: %v", err)
+	}
+	l.mu.Lock()
+	txID := l.txID
+	l.mu.Unlock()
+	for txID < state.GetTxId() {
+		txs, err := s.store.TxScan(ctx, txID+1, bulletinBoardDefaultLimit)
+		if err != nil {
+			return fmt.Errorf("error scanning txs after tx %d: %v", txID, err)
+		}
+		if len(txs) == 0 {
+			return nil
+		}
+		minutes := map[int64]*TurnoutBucket{}
+		for _, tx := range txs {
+			tally, err := s.txTally(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if !tally.empty() {
+				minute := tx.GetMetadata().GetTs() / int64(turnoutResolution.Seconds())
+				bucket, ok := minutes[minute]
+				if !ok {
+					bucket = &TurnoutBucket{}
+					minutes[minute] = bucket
+				}
+				bucket.Registered += tally.Registered
+				bucket.Ballots += tally.Ballots
+			}
+		}
+		txID = txs[len(txs)-1].GetMetadata().GetId()
+
+		l.mu.Lock()
+		for minute, counts := range minutes {
+			bucket, ok := l.minutes[minute]
+			if !ok {
+				bucket = &TurnoutBucket{}
+				l.minutes[minute] = bucket
+			}
+			bucket.Registered += counts.Registered
+			bucket.Ballots += counts.Ballots
+		}
+		l.txID = txID
+		l.mu.Unlock()
+	}
+	return nil
+}
+
+// buckets rolls the minutes up to the interval, from the from time (rounded
+// down to the interval) to the to one (rounded up), by default from the first
+// minute with a registration or a ballot to the latest one; the intervals with
+// none are returned too, so that the series has no gaps
+func (l *turnoutLog) buckets(interval time.Duration, from, to time.Time) ([]TurnoutBucket, error) {
+	minuteSecs := int64(turnoutResolution.Seconds())
+	intervalSecs := int64(interval.Seconds())
+	minutes := make([]int64, 0, len(l.minutes))
+	for minute := range l.minutes {
+		minutes = append(minutes, minute)
+	}
+	sort.Slice(minutes, func(i, j int) bool { return minutes[i] < minutes[j] })
+
+	var start, end int64
+	if from.IsZero() {
+		if len(minutes) == 0 {
+			return []TurnoutBucket{}, nil
+		}
+		start = minutes[0] * minuteSecs
+	} else {
+		start = from.Unix()
+	}
+	start -= start % intervalSecs
+	if to.IsZero() {
+		if len(minutes) == 0 {
+			return []TurnoutBucket{}, nil
+		}
+		end = minutes[len(minutes)-1]*minuteSecs + 1
+	} else {
+		end = to.Unix()
+	}
+	if rest := end % intervalSecs; rest > 0 {
+		end += intervalSecs - rest
+	}
+	if end <= start {
+		return []TurnoutBucket{}, nil
+	}
+	if (end-start)/intervalSecs > turnoutMaxBuckets {
+		return nil, fmt.Errorf("more than %d buckets", turnoutMaxBuckets)
+	}
+
+	buckets := make([]TurnoutBucket, 0, (end-start)/intervalSecs)
+	for bucketStart := start; bucketStart < end; bucketStart += intervalSecs {
+		buckets = append(buckets, TurnoutBucket{Start: time.Unix(bucketStart, 0).UTC()})
+	}
+	for _, minute := range minutes {
+		minuteStart := minute * minuteSecs
+		if minuteStart < start || minuteStart >= end {
+			continue
+		}
+		bucket := &buckets[(minuteStart-start)/intervalSecs]
+		bucket.Registered += l.minutes[minute].Registered
+		bucket.Ballots += l.minutes[minute].Ballots
+	}
+	return buckets, nil
+}
+
+// getTurnoutHandler returns the registrations and the ballots cast by interval
+// (an hour by default), as counted from the tx log so far
+func (s *Server) getTurnoutHandler(w http.ResponseWriter, r *http.Request) {
+	interval := turnoutDefaultInterval
+	if intervalStr := r.URL.Query().Get("interval"); len(intervalStr) > 0 {
+		var err error
+		if interval, err = time.ParseDuration(intervalStr); err != nil ||
+			interval < turnoutResolution || interval > turnoutMaxInterval || interval%turnoutResolution != 0 {
+			writeErrorResponse(r, w, http.StatusBadRequest, err, fmt.Sprintf(
+				"interval query param must be a number of minutes between %s and %s (e.g. 15m or 1h)",
+				turnoutResolution, turnoutMaxInterval))
+			return
+		}
+	}
+	var from, to time.Time
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		if tStr := r.URL.Query().Get(name); len(tStr) > 0 {
+			var err error
+			if *t, err = time.Parse(time.RFC3339, tStr); err != nil {
+				writeErrorResponse(r, w, http.StatusBadRequest, err,
+					fmt.Sprintf("%s query param is not an RFC 3339 time", name))
+				return
+			}
+		}
+	}
+
+	s.turnout.mu.Lock()
+	defer s.turnout.mu.Unlock()
+	buckets, err := s.turnout.buckets(interval, from, to)
+	if err != nil {
+		writeErrorResponse(r, w, http.StatusBadRequest, err,
+			"too many buckets: narrow the from and to query params or widen the interval")
+		return
+	}
+	writeJSONResponse(r, w, http.StatusOK, &TurnoutResponse{
+		Interval: interval.String(),
+		TXID:     s.turnout.txID,
+		Buckets:  buckets,
+	})
+}
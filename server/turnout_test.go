@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestTurnout(t *testing.T) {
+	server, httpServer := newTestServer(t)
+	ctx := context.Background()
+	turnoutURL := httpServer.URL + apiV1Prefix + "/elections/" + electionID + "/turnout?interval=24h"
+	// turnout returns the total registrations and ballots of the buckets
+	turnout := func() (int64, uint64) {
+		t.Helper()
+		var response TurnoutResponse
+		if status := doJSON(t, http.MethodGet, turnoutURL, false, nil, &response); status != http.StatusOK {
+			t.Fatalf("getting turnout: got status %d, want %d", status, http.StatusOK)
+		}
+		var registered int64
+		var ballots uint64
+		for _, bucket := range response.Buckets {
+			registered, ballots = registered+bucket.Registered, ballots+bucket.Ballots
+		}
+		return registered, ballots
+	}
+
+	for _, citizenID := range []string{"alice", "bob"} {
+		registerTestVoter(t, httpServer.URL, citizenID, "north-1-a")
+	}
+	voter := registerTestVoter(t, httpServer.URL, "carol", "south-1-b")
+	if err := server.vote(ctx, &VoteRequest{RegisterVoterResponse: *voter, Vote: NikkiHaley}); err != nil {
+		t.Fatalf("error voting: %v", err)
+	}
+	// the handler only reads what has been counted in the background
+	if registered, ballots := turnout(); registered != 0 || ballots != 0 {
+		t.Errorf("before counting: got %d registered and %d ballots, want none", registered, ballots)
+	}
+	if err := server.updateTurnout(ctx); err != nil {
+		t.Fatalf("error counting turnout: %v", err)
+	}
+	if registered, ballots := turnout(); registered != 3 || ballots != 1 {
+		t.Errorf("got %d registered and %d ballots, want 3 and 1", registered, ballots)
+	}
+
+	// the next update only counts the new txs
+	registerTestVoter(t, httpServer.URL, "dave", "south-1-b")
+	if err := server.updateTurnout(ctx); err != nil {
+		t.Fatalf("error counting turnout: %v", err)
+	}
+	if registered, ballots := turnout(); registered != 4 || ballots != 1 {
+		t.Errorf("got %d registered and %d ballots, want 4 and 1", registered, ballots)
+	}
+}
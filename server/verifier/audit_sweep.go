@@ -0,0 +1,232 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/htree"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+var errInvalidSweep = errors.New("INVALID AUDIT SWEEP")
+
+// AuditSweepHistoryEntry mirrors the ballot history entry published by the server
+type AuditSweepHistoryEntry struct {
+	TX             uint64                 `json:"tx"`
+	Vote           uint16                 `json:"vote"`
+	InclusionProof *schema.InclusionProof `json:"inclusion_proof"`
+	DualProof      *schema.DualProof      `json:"dual_proof"`
+}
+
+// AuditSweepBallot ...
+type AuditSweepBallot struct {
+	BallotID string                   `json:"ballot_id"`
+	Vote     uint16                   `json:"vote"`
+	History  []AuditSweepHistoryEntry `json:"history"`
+}
+
+// AuditSweepPage ...
+type AuditSweepPage struct {
+	StateTX    uint64             `json:"state_tx"`
+	Ballots    []AuditSweepBallot `json:"ballots"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// SweepReport sums up the outcome of a sweep of all ballots
+type SweepReport struct {
+	StateTX    uint64
+	Swept      uint64
+	Registered uint64
+	Cast       uint64
+	NotCast    uint64
+	Results    map[uint16]uint64
+	// ballots which have been cast more than once or changed after being cast
+	Anomalies []string
+}
+
+// VerifySweepBallot verifies every value the ballot has had against the
+// verified state: the inclusion of the value in the tx it was set in and the
+// dual proof linking that tx to the state; it returns an anomaly description
+// if the ballot has been cast more than once or changed after being cast
+func VerifySweepBallot(state *State, ballot *AuditSweepBallot) (string, error) {
+	if len(ballot.History) == 0 {
+		return "", fmt.Errorf("%w: ballot %s has no history", errInvalidSweep, ballot.BallotID)
+	}
+	stateAlh := schema.DigestFrom(state.TXHash)
+	ballotKey := []byte(ballotPrefix + ballot.BallotID)
+
+	var prevTX uint64
+	var cast bool
+	for _, entry := range ballot.History {
+		if entry.TX <= prevTX || entry.TX > state.TXID {
+			return "", fmt.Errorf("%w: history of ballot %s is out of order at tx %d",
+				errInvalidSweep, ballot.BallotID, entry.TX)
+		}
+		prevTX = entry.TX
+
+		dualProof := schema.DualProofFrom(entry.DualProof)
+		if dualProof.SourceTxMetadata == nil || dualProof.SourceTxMetadata.ID != entry.TX {
+			return "", fmt.Errorf("%w: proof of ballot %s at tx %d has no valid source tx",
+				errInvalidSweep, ballot.BallotID, entry.TX)
+		}
+		if !store.VerifyDualProof(
+			dualProof, entry.TX, state.TXID, dualProof.SourceTxMetadata.Alh(), stateAlh) {
+			return "", fmt.Errorf("%w: tx %d of ballot %s is not consistent with state tx %d",
+				errInvalidSweep, entry.TX, ballot.BallotID, state.TXID)
+		}
+		value := make([]byte, 2)
+		binary.BigEndian.PutUint16(value, entry.Vote)
+		digest := database.EncodeKV(ballotKey, value).Digest()
+		if !htree.VerifyInclusion(
+			schema.InclusionProofFrom(entry.InclusionProof), digest, dualProof.SourceTxMetadata.Eh) {
+			return "", fmt.Errorf("%w: vote %d of ballot %s is not included in tx %d",
+				errInvalidSweep, entry.Vote, ballot.BallotID, entry.TX)
+		}
+		if cast {
+			return fmt.Sprintf("ballot %s changed to %d at tx %d after being cast",
+				ballot.BallotID, entry.Vote, entry.TX), nil
+		}
+		cast = entry.Vote != 0
+	}
+	if last := ballot.History[len(ballot.History)-1]; last.Vote != ballot.Vote {
+		return "", fmt.Errorf("%w: ballot %s has vote %d but its last value is %d",
+			errInvalidSweep, ballot.BallotID, ballot.Vote, last.Vote)
+	}
+	return "", nil
+}
+
+type ballotSweeper struct {
+	client    *http.Client
+	serverURL string
+	limit     uint
+}
+
+// sweep pages through all ballots as of the verified state and verifies each
+func (s *ballotSweeper) sweep(state *State) (*SweepReport, error) {
+	report := SweepReport{
+		StateTX: state.TXID,
+		Results: make(map[uint16]uint64),
+	}
+	seen := make(map[string]bool)
+	cursor := ""
+	for {
+		pageURL := fmt.Sprintf("%s/api/v1/elections/default/audit-sweep?state_tx=%d&limit=%d&cursor=%s",
+			s.serverURL, state.TXID, s.limit, url.QueryEscape(cursor))
+		req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"error creating new HTTP GET %s request to fetch ballots: %v", pageURL, err)
+		}
+		var page AuditSweepPage
+		if _, err := httpDo(s.client, req, &page, ""); err != nil {
+			return nil, err
+		}
+		if page.StateTX != state.TXID {
+			return nil, fmt.Errorf("%w: requested ballots as of tx %d, got tx %d",
+				errInvalidSweep, state.TXID, page.StateTX)
+		}
+		for i := range page.Ballots {
+			ballot := &page.Ballots[i]
+			if seen[ballot.BallotID] {
+				return nil, fmt.Errorf("%w: ballot %s is listed twice", errInvalidSweep, ballot.BallotID)
+			}
+			seen[ballot.BallotID] = true
+			anomaly, err := VerifySweepBallot(state, ballot)
+			if err != nil {
+				return nil, err
+			}
+			if len(anomaly) > 0 {
+				report.Anomalies = append(report.Anomalies, anomaly)
+			}
+			report.Swept++
+			if ballot.Vote == 0 {
+				report.NotCast++
+			} else {
+				report.Cast++
+				report.Results[ballot.Vote]++
+			}
+		}
+		if len(page.NextCursor) == 0 {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	statsURL := s.serverURL + "/stats"
+	req, err := http.NewRequest(http.MethodGet, statsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error creating new HTTP GET %s request to fetch stats: %v", statsURL, err)
+	}
+	var stats struct {
+		Registered uint64 `json:"registered"`
+	}
+	if _, err := httpDo(s.client, req, &stats, ""); err != nil {
+		return nil, err
+	}
+	report.Registered = stats.Registered
+	return &report, nil
+}
+
+func sweepCmd(args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	serverURL := fs.String("server", "http://localhost:8080", "immuvoting server URL")
+	stateFile := fs.String("state", "immuvoting-state.json", "file in which the verified state is stored")
+	limit := fs.Uint("limit", 100, "number of ballots to fetch per page")
+	publicKeyFile := fs.String("pubkey", "", "public key (PEM) of the server signing key, as published for the election")
+	fs.Parse(args)
+
+	publicKey, err := readPublicKey(*publicKeyFile)
+	if err != nil {
+		return err
+	}
+
+	v := &cliVerifier{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		serverURL: strings.TrimSuffix(*serverURL, "/"),
+		publicKey: publicKey,
+		stateFile: *stateFile,
+	}
+	state, err := v.verifyServerState()
+	if err != nil {
+		return err
+	}
+
+	s := &ballotSweeper{client: v.client, serverURL: v.serverURL, limit: *limit}
+	report, err := s.sweep(state)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("swept %d ballots as of tx %d: %d cast, %d not cast",
+		report.Swept, report.StateTX, report.Cast, report.NotCast)
+	for vote, count := range report.Results {
+		log.Printf("  vote %d: %d", vote, count)
+	}
+	// every registered voter gets exactly one ballot: voters registered after
+	// the verified state are not swept
+	if report.Registered > 0 {
+		log.Printf("coverage: %d of %d registered voters (%.2f%%)", report.Swept, report.Registered,
+			100*float64(report.Swept)/float64(report.Registered))
+	}
+	if len(report.Anomalies) > 0 {
+		for _, anomaly := range report.Anomalies {
+			log.Print(anomaly)
+		}
+		return fmt.Errorf("%w: %d ballots cast more than once or changed after being cast",
+			errInvalidSweep, len(report.Anomalies))
+	}
+	return nil
+}
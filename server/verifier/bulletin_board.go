@@ -0,0 +1,261 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/htree"
+	"github.com/codenotary/immudb/embedded/store"
+)
+
+var errInvalidFeed = errors.New("INVALID BULLETIN BOARD FEED")
+
+// BulletinBoardEntry mirrors the bulletin board entry published by the server:
+// the voter and the citizen entries have no digest
+type BulletinBoardEntry struct {
+	KeyType   string `json:"key_type"`
+	ValueHash []byte `json:"value_hash,omitempty"`
+	Digest    []byte `json:"digest,omitempty"`
+}
+
+// BulletinBoardTX mirrors the bulletin board tx published by the server
+type BulletinBoardTX struct {
+	TXID    uint64               `json:"tx_id"`
+	Ts      int64                `json:"ts"`
+	PrevAlh []byte               `json:"prev_alh"`
+	EH      []byte               `json:"eh"`
+	BlTXID  uint64               `json:"bl_tx_id"`
+	BlRoot  []byte               `json:"bl_root"`
+	Alh     []byte               `json:"alh"`
+	Entries []BulletinBoardEntry `json:"entries"`
+}
+
+// BulletinBoardPage ...
+type BulletinBoardPage struct {
+	TXs    []BulletinBoardTX `json:"txs"`
+	NextTX uint64            `json:"next_tx"`
+}
+
+// VerifyBulletinBoardTX checks that the tx follows the previous one (nil for
+// the 1st tx) and that its accumulated hash (Alh) is genuine; the entries hash
+// (EH) is recomputed from the digests of the entries if they have all been
+// published, otherwise it is only bound by the Alh, which the proof of the head
+// of the mirror against the server state covers
+func VerifyBulletinBoardTX(prev *State, tx *BulletinBoardTX) error {
+	prevTXID := uint64(0)
+	prevAlh := sha256.Sum256(nil)
+	if prev != nil {
+		prevTXID = prev.TXID
+		copy(prevAlh[:], prev.TXHash)
+	}
+	if tx.TXID != prevTXID+1 {
+		return fmt.Errorf("%w: expected tx %d, got tx %d", errInvalidFeed, prevTXID+1, tx.TXID)
+	}
+	if !bytes.Equal(tx.PrevAlh, prevAlh[:]) {
+		return fmt.Errorf("%w: tx %d is not linked to tx %d", errInvalidFeed, tx.TXID, prevTXID)
+	}
+	if len(tx.Entries) == 0 || len(tx.EH) != sha256.Size ||
+		len(tx.BlRoot) != sha256.Size || len(tx.Alh) != sha256.Size {
+		return fmt.Errorf("%w: tx %d is incomplete", errInvalidFeed, tx.TXID)
+	}
+
+	digests := make([][sha256.Size]byte, 0, len(tx.Entries))
+	for i, entry := range tx.Entries {
+		if len(entry.Digest) == 0 {
+			continue
+		}
+		if len(entry.Digest) != sha256.Size {
+			return fmt.Errorf("%w: entry %d of tx %d has no valid digest", errInvalidFeed, i, tx.TXID)
+		}
+		digests = append(digests, [sha256.Size]byte{})
+		copy(digests[len(digests)-1][:], entry.Digest)
+	}
+	if len(digests) == len(tx.Entries) {
+		hTree, err := htree.New(len(digests))
+		if err != nil {
+			return err
+		}
+		if err := hTree.BuildWith(digests); err != nil {
+			return err
+		}
+		root, err := hTree.Root()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(root[:], tx.EH) {
+			return fmt.Errorf("%w: entries of tx %d do not match its entries hash", errInvalidFeed, tx.TXID)
+		}
+	}
+
+	md := store.TxMetadata{
+		ID:       tx.TXID,
+		Ts:       tx.Ts,
+		NEntries: len(tx.Entries),
+		BlTxID:   tx.BlTXID,
+	}
+	copy(md.PrevAlh[:], tx.PrevAlh)
+	copy(md.Eh[:], tx.EH)
+	copy(md.BlRoot[:], tx.BlRoot)
+	alh := md.Alh()
+	if !bytes.Equal(alh[:], tx.Alh) {
+		return fmt.Errorf("%w: accumulated hash of tx %d does not match", errInvalidFeed, tx.TXID)
+	}
+	return nil
+}
+
+type bulletinBoardMirror struct {
+	client    *http.Client
+	serverURL string
+	file      string
+	limit     uint
+	last      *State
+}
+
+// load reads the head (last tx) of the local mirror, if any
+func (m *bulletinBoardMirror) load() error {
+	f, err := os.Open(m.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error opening mirror file %s: %v", m.file, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	var lastLine []byte
+	for scanner.Scan() {
+		lastLine = append(lastLine[:0], scanner.Bytes()...)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading mirror file %s: %v", m.file, err)
+	}
+	if len(lastLine) == 0 {
+		return nil
+	}
+	var tx BulletinBoardTX
+	if err := json.Unmarshal(lastLine, &tx); err != nil {
+		return fmt.Errorf("error JSON-unmarshaling last tx from mirror file %s: %v", m.file, err)
+	}
+	m.last = &State{TXID: tx.TXID, TXHash: tx.Alh}
+	return nil
+}
+
+// sync fetches, verifies and appends to the local mirror all txs published
+// after its head, then proves that the new head belongs to the server history
+func (m *bulletinBoardMirror) sync() error {
+	f, err := os.OpenFile(m.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening mirror file %s: %v", m.file, err)
+	}
+	defer f.Close()
+
+	var nbMirrored int
+	for {
+		sinceTX := uint64(1)
+		if m.last != nil {
+			sinceTX = m.last.TXID + 1
+		}
+		pageURL := fmt.Sprintf(
+			"%s/api/v1/elections/default/bulletin-board?since_tx=%d&limit=%d", m.serverURL, sinceTX, m.limit)
+		req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+		if err != nil {
+			return fmt.Errorf(
+				"error creating new HTTP GET %s request to fetch bulletin board: %v", pageURL, err)
+		}
+		var page BulletinBoardPage
+		if _, err := httpDo(m.client, req, &page, ""); err != nil {
+			return err
+		}
+		if len(page.TXs) == 0 {
+			break
+		}
+		for i := range page.TXs {
+			tx := &page.TXs[i]
+			if err := VerifyBulletinBoardTX(m.last, tx); err != nil {
+				return err
+			}
+			txBytes, err := json.Marshal(tx)
+			if err != nil {
+				return fmt.Errorf("error JSON-marshaling tx %d: %v", tx.TXID, err)
+			}
+			if _, err := f.Write(append(txBytes, '\n')); err != nil {
+				return fmt.Errorf("error appending tx %d to mirror file %s: %v", tx.TXID, m.file, err)
+			}
+			m.last = &State{TXID: tx.TXID, TXHash: tx.Alh}
+			nbMirrored++
+		}
+	}
+	if m.last == nil {
+		log.Print("bulletin board is empty")
+		return nil
+	}
+
+	serverState, err := FetchServerState(m.client, m.serverURL)
+	if err != nil {
+		return err
+	}
+	if m.last.TXID == serverState.TXID {
+		if !m.last.Equals(serverState) {
+			return fmt.Errorf(
+				"%w: mirrored tx %d does not match the server state", errInvalidFeed, m.last.TXID)
+		}
+	} else {
+		_, verified, err := ProveConsistency(m.client, m.serverURL, m.last, serverState)
+		if err != nil {
+			return fmt.Errorf("error proving mirrored tx %d against server tx %d: %v",
+				m.last.TXID, serverState.TXID, err)
+		}
+		if !verified {
+			return fmt.Errorf("%w: mirrored tx %d is not consistent with server tx %d",
+				errInvalidFeed, m.last.TXID, serverState.TXID)
+		}
+	}
+	log.Printf("mirrored %d new txs, head tx %d verified against server tx %d",
+		nbMirrored, m.last.TXID, serverState.TXID)
+	return nil
+}
+
+func mirrorCmd(args []string) error {
+	fs := flag.NewFlagSet("mirror", flag.ExitOnError)
+	serverURL := fs.String("server", "http://localhost:8080", "immuvoting server URL")
+	file := fs.String("file", "bulletin-board.jsonl", "file in which the verified txs are mirrored")
+	limit := fs.Uint("limit", 100, "number of txs to fetch per page")
+	interval := fs.Duration("interval", 0, "repeat the sync at this interval (0 runs it once)")
+	fs.Parse(args)
+
+	m := &bulletinBoardMirror{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		serverURL: strings.TrimSuffix(*serverURL, "/"),
+		file:      *file,
+		limit:     *limit,
+	}
+	if err := m.load(); err != nil {
+		return err
+	}
+	for {
+		if err := m.sync(); err != nil {
+			if *interval == 0 || errors.Is(err, errInvalidFeed) {
+				return err
+			}
+			log.Print(err)
+		}
+		if *interval == 0 {
+			return nil
+		}
+		time.Sleep(*interval)
+	}
+}
@@ -0,0 +1,246 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/codenotary/immudb/embedded/htree"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+const (
+	electionKey  = "immuvoting:election"
+	ballotPrefix = "immuvoting:ballot:"
+)
+
+var errInvalidBundle = errors.New("INVALID AUDIT BUNDLE")
+
+// AuditBundle mirrors the audit bundle exported by the server
+type AuditBundle struct {
+	State       AuditBundleState             `json:"state"`
+	Election    AuditBundleEntry             `json:"election"`
+	VoterRoll   []AuditBundleEntry           `json:"voter_roll"`
+	Ballots     []AuditBundleEntry           `json:"ballots"`
+	Checkpoints map[uint64]*schema.DualProof `json:"checkpoints"`
+}
+
+// AuditBundleState ...
+type AuditBundleState struct {
+	DB        string            `json:"db"`
+	TXID      uint64            `json:"tx_id"`
+	TXHash    []byte            `json:"tx_hash"`
+	Signature *schema.Signature `json:"signature,omitempty"`
+}
+
+// AuditBundleEntry ...
+type AuditBundleEntry struct {
+	Key            []byte                 `json:"key,omitempty"`
+	Value          []byte                 `json:"value,omitempty"`
+	Digest         []byte                 `json:"digest,omitempty"`
+	TX             uint64                 `json:"tx"`
+	InclusionProof *schema.InclusionProof `json:"inclusion_proof"`
+}
+
+// Election ...
+type Election struct {
+	Name       string `json:"name"`
+	Candidates []struct {
+		ID   uint16 `json:"id"`
+		Name string `json:"name"`
+	} `json:"candidates"`
+}
+
+// Tally is the result reproduced from a verified audit bundle
+type Tally struct {
+	Election   Election          `json:"election"`
+	TXID       uint64            `json:"tx_id"`
+	Registered uint64            `json:"registered"`
+	Ballots    uint64            `json:"ballots"`
+	Results    map[uint16]uint64 `json:"results"`
+	Invalid    uint64            `json:"invalid"`
+}
+
+// VerifyAuditBundle verifies the signature of the bundle state against the
+// pinned public key of the server and all proofs in the bundle against its
+// state, then reproduces the tally; it needs no connection to the server
+func VerifyAuditBundle(bundle *AuditBundle, publicKey *ecdsa.PublicKey) (*Tally, error) {
+	if len(bundle.State.TXHash) != sha256.Size {
+		return nil, fmt.Errorf("%w: state tx hash is missing or invalid", errInvalidBundle)
+	}
+	stateAlh := schema.DigestFrom(bundle.State.TXHash)
+
+	// the proofs only link the entries to the state: without the signature,
+	// anyone could forge a whole consistent bundle
+	if err := verifyStateSignature(&bundle.State, publicKey); err != nil {
+		return nil, err
+	}
+
+	// every checkpoint must link its tx to the bundle state
+	for txID, checkpoint := range bundle.Checkpoints {
+		dualProof := schema.DualProofFrom(checkpoint)
+		if dualProof.SourceTxMetadata == nil {
+			return nil, fmt.Errorf("%w: checkpoint of tx %d has no source tx", errInvalidBundle, txID)
+		}
+		if !store.VerifyDualProof(
+			dualProof, txID, bundle.State.TXID,
+			dualProof.SourceTxMetadata.Alh(), stateAlh) {
+			return nil, fmt.Errorf(
+				"%w: checkpoint of tx %d is not consistent with state tx %d",
+				errInvalidBundle, txID, bundle.State.TXID)
+		}
+	}
+
+	verifyEntry := func(what string, entry *AuditBundleEntry) error {
+		checkpoint, ok := bundle.Checkpoints[entry.TX]
+		if !ok {
+			return fmt.Errorf("%w: no checkpoint for tx %d of %s", errInvalidBundle, entry.TX, what)
+		}
+		digest := schema.DigestFrom(entry.Digest)
+		if len(entry.Digest) == 0 {
+			digest = database.EncodeKV(entry.Key, entry.Value).Digest()
+		}
+		eh := schema.DigestFrom(checkpoint.GetSourceTxMetadata().GetEH())
+		if !htree.VerifyInclusion(schema.InclusionProofFrom(entry.InclusionProof), digest, eh) {
+			return fmt.Errorf("%w: %s is not included in tx %d", errInvalidBundle, what, entry.TX)
+		}
+		return nil
+	}
+
+	tally := Tally{
+		TXID:       bundle.State.TXID,
+		Registered: uint64(len(bundle.VoterRoll)),
+		Results:    make(map[uint16]uint64),
+	}
+
+	if string(bundle.Election.Key) != electionKey {
+		return nil, fmt.Errorf("%w: election definition has key %s", errInvalidBundle, bundle.Election.Key)
+	}
+	if err := verifyEntry("election definition", &bundle.Election); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bundle.Election.Value, &tally.Election); err != nil {
+		return nil, fmt.Errorf("%w: error JSON-unmarshaling election definition: %v", errInvalidBundle, err)
+	}
+	candidates := make(map[uint16]bool, len(tally.Election.Candidates))
+	for _, candidate := range tally.Election.Candidates {
+		candidates[candidate.ID] = true
+	}
+
+	for i := range bundle.VoterRoll {
+		if len(bundle.VoterRoll[i].Digest) != sha256.Size {
+			return nil, fmt.Errorf("%w: voter roll entry %d has no valid digest", errInvalidBundle, i)
+		}
+		if err := verifyEntry(fmt.Sprintf("voter roll entry %d", i), &bundle.VoterRoll[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	seenBallots := make(map[string]bool, len(bundle.Ballots))
+	for i := range bundle.Ballots {
+		ballot := &bundle.Ballots[i]
+		ballotKey := string(ballot.Key)
+		if !strings.HasPrefix(ballotKey, ballotPrefix) || len(ballot.Value) != 2 {
+			return nil, fmt.Errorf("%w: entry %s is not a ballot", errInvalidBundle, ballotKey)
+		}
+		if seenBallots[ballotKey] {
+			return nil, fmt.Errorf("%w: ballot %s is exported twice", errInvalidBundle, ballotKey)
+		}
+		seenBallots[ballotKey] = true
+		if err := verifyEntry("ballot "+ballotKey, ballot); err != nil {
+			return nil, err
+		}
+		vote := binary.BigEndian.Uint16(ballot.Value)
+		switch {
+		case vote == 0:
+			// not cast
+		case candidates[vote]:
+			tally.Results[vote]++
+			tally.Ballots++
+		default:
+			tally.Invalid++
+		}
+	}
+
+	return &tally, nil
+}
+
+// verifyStateSignature verifies that the bundle state has been signed by the
+// server (see State.VerifySignature)
+func verifyStateSignature(state *AuditBundleState, publicKey *ecdsa.PublicKey) error {
+	signedState := State{DB: state.DB, TXID: state.TXID, TXHash: state.TXHash, Signature: state.Signature}
+	if err := signedState.VerifySignature(publicKey); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidBundle, err)
+	}
+	return nil
+}
+
+// readPublicKey reads the public key (PKIX PEM) of the server signing key
+func readPublicKey(file string) (*ecdsa.PublicKey, error) {
+	if len(file) == 0 {
+		return nil, errors.New("-pubkey flag is missing: the public key of the server is required to verify its signatures")
+	}
+	publicKeyPEM, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading public key of the server from %s: %v", file, err)
+	}
+	publicKey, err := ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error reading public key of the server from %s: %v", file, err)
+	}
+	return publicKey, nil
+}
+
+func verifyBundleCmd(args []string) error {
+	fs := flag.NewFlagSet("verify-bundle", flag.ExitOnError)
+	bundleFile := fs.String("bundle", "", "audit bundle file exported by the server")
+	publicKeyFile := fs.String("pubkey", "", "public key (PEM) of the server signing key, as published for the election")
+	fs.Parse(args)
+
+	if len(*bundleFile) == 0 {
+		return errors.New("-bundle flag is missing")
+	}
+	publicKey, err := readPublicKey(*publicKeyFile)
+	if err != nil {
+		return err
+	}
+	bundleBytes, err := ioutil.ReadFile(*bundleFile)
+	if err != nil {
+		return fmt.Errorf("error reading audit bundle from %s: %v", *bundleFile, err)
+	}
+	var bundle AuditBundle
+	if err := json.Unmarshal(bundleBytes, &bundle); err != nil {
+		return fmt.Errorf("%w: error JSON-unmarshaling %s: %v", errInvalidBundle, *bundleFile, err)
+	}
+
+	tally, err := VerifyAuditBundle(&bundle, publicKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("audit bundle %s verified: %d checkpoints, all proofs OK\n",
+		*bundleFile, len(bundle.Checkpoints))
+	fmt.Printf("state: tx %d, hash %x\n", bundle.State.TXID, bundle.State.TXHash)
+	fmt.Printf("state signed by the server (public key %x)\n", bundle.State.Signature.GetPublicKey())
+	fmt.Printf("election: %s\n", tally.Election.Name)
+	fmt.Printf("registered voters: %d\n", tally.Registered)
+	fmt.Printf("ballots cast: %d\n", tally.Ballots)
+	for _, candidate := range tally.Election.Candidates {
+		fmt.Printf("  %s: %d\n", candidate.Name, tally.Results[candidate.ID])
+	}
+	if tally.Invalid > 0 {
+		fmt.Printf("invalid ballots: %d\n", tally.Invalid)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+	"github.com/codenotary/immudb/pkg/signer"
+	"github.com/padurean/immuvoting/memstore"
+)
+
+const testElection = `{"name":"Test election","candidates":[{"id":1,"name":"A"},{"id":2,"name":"B"}]}`
+
+// testServerKey is the signing key of the server of the tests
+var testServerKey = newTestKey()
+
+func newTestKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// signTestState signs the state as the server does
+func signTestState(key *ecdsa.PrivateKey, db string, txID uint64, txHash []byte) *schema.Signature {
+	state := schema.ImmutableState{Db: db, TxId: txID, TxHash: txHash}
+	signature, publicKey, err := signer.NewSignerFromPKey(rand.Reader, key).Sign(state.ToBytes())
+	if err != nil {
+		panic(err)
+	}
+	return &schema.Signature{Signature: signature, PublicKey: publicKey}
+}
+
+// newTestBundle builds the audit bundle of an election with two voters, whose
+// ballots have been cast for candidate 1 and not at all, as the server does,
+// signed with the test key of the server
+func newTestBundle(t *testing.T) *AuditBundle {
+	t.Helper()
+	ctx := context.Background()
+	db := memstore.New("defaultdb")
+	ballot := func(vote uint16) []byte {
+		value := make([]byte, 2)
+		binary.BigEndian.PutUint16(value, vote)
+		return value
+	}
+	for _, kv := range []struct{ key, value string }{
+		{electionKey, testElection},
+		{"immuvoting:voter:1", `{"name":"Alice"}`},
+		{ballotPrefix + "1", string(ballot(0))},
+		{"immuvoting:voter:2", `{"name":"Bob"}`},
+		{ballotPrefix + "2", string(ballot(0))},
+		{ballotPrefix + "1", string(ballot(1))},
+	} {
+		if err := db.Set(ctx, []byte(kv.key), []byte(kv.value)); err != nil {
+			t.Fatalf("error setting key %s: %v", kv.key, err)
+		}
+	}
+	state, err := db.CurrentState(ctx)
+	if err != nil {
+		t.Fatalf("error fetching current state: %v", err)
+	}
+
+	bundle := &AuditBundle{
+		State: AuditBundleState{
+			DB:        db.Database(),
+			TXID:      state.GetTxId(),
+			TXHash:    state.GetTxHash(),
+			Signature: signTestState(testServerKey, db.Database(), state.GetTxId(), state.GetTxHash()),
+		},
+		Checkpoints: make(map[uint64]*schema.DualProof),
+	}
+	entry := func(key string, digestOnly bool) AuditBundleEntry {
+		verifiableEntry, err := db.VerifiableGetAt(ctx, []byte(key), 0, state.GetTxId())
+		if err != nil {
+			t.Fatalf("error fetching verifiable key %s: %v", key, err)
+		}
+		txID := verifiableEntry.GetEntry().GetTx()
+		bundle.Checkpoints[txID] = verifiableEntry.GetVerifiableTx().GetDualProof()
+		entry := AuditBundleEntry{TX: txID, InclusionProof: verifiableEntry.GetInclusionProof()}
+		if digestOnly {
+			digest := database.EncodeKV([]byte(key), verifiableEntry.GetEntry().GetValue()).Digest()
+			entry.Digest = digest[:]
+		} else {
+			entry.Key = []byte(key)
+			entry.Value = verifiableEntry.GetEntry().GetValue()
+		}
+		return entry
+	}
+	bundle.Election = entry(electionKey, false)
+	bundle.VoterRoll = []AuditBundleEntry{entry("immuvoting:voter:1", true), entry("immuvoting:voter:2", true)}
+	bundle.Ballots = []AuditBundleEntry{entry(ballotPrefix+"1", false), entry(ballotPrefix+"2", false)}
+	return bundle
+}
+
+func TestVerifyAuditBundle(t *testing.T) {
+	tally, err := VerifyAuditBundle(newTestBundle(t), &testServerKey.PublicKey)
+	if err != nil {
+		t.Fatalf("error verifying audit bundle: %v", err)
+	}
+	if tally.Election.Name != "Test election" || tally.Registered != 2 || tally.Ballots != 1 ||
+		tally.Results[1] != 1 || tally.Results[2] != 0 || tally.Invalid != 0 {
+		t.Errorf("got tally %+v, want 2 registered and 1 ballot for candidate 1", tally)
+	}
+}
+
+func TestVerifyAuditBundleTampered(t *testing.T) {
+	for name, tamper := range map[string]func(*AuditBundle){
+		"ballot value": func(bundle *AuditBundle) {
+			bundle.Ballots[1].Value = []byte{0, 2}
+		},
+		"voter digest": func(bundle *AuditBundle) {
+			bundle.VoterRoll[0].Digest[0] ^= 1
+		},
+		"state hash": func(bundle *AuditBundle) {
+			bundle.State.TXHash[0] ^= 1
+		},
+		"state tx": func(bundle *AuditBundle) {
+			bundle.State.TXID--
+		},
+		"duplicate ballot": func(bundle *AuditBundle) {
+			bundle.Ballots = append(bundle.Ballots, bundle.Ballots[0])
+		},
+		"missing checkpoint": func(bundle *AuditBundle) {
+			delete(bundle.Checkpoints, bundle.Ballots[0].TX)
+		},
+	} {
+		bundle := newTestBundle(t)
+		tamper(bundle)
+		if _, err := VerifyAuditBundle(bundle, &testServerKey.PublicKey); !errors.Is(err, errInvalidBundle) {
+			t.Errorf("verifying audit bundle with tampered %s: got %v, want %v", name, err, errInvalidBundle)
+		}
+	}
+}
+
+func TestVerifyAuditBundleSignature(t *testing.T) {
+	bundle := newTestBundle(t)
+	if _, err := VerifyAuditBundle(bundle, nil); !errors.Is(err, errInvalidBundle) {
+		t.Errorf("verifying audit bundle without the public key of the server: got %v, want %v", err, errInvalidBundle)
+	}
+
+	// a bundle signed with any other key, which comes with the signature
+	otherKey := newTestKey()
+	bundle.State.Signature = signTestState(otherKey, bundle.State.DB, bundle.State.TXID, bundle.State.TXHash)
+	if _, err := VerifyAuditBundle(bundle, &testServerKey.PublicKey); !errors.Is(err, errInvalidBundle) {
+		t.Errorf("verifying audit bundle signed with another key: got %v, want %v", err, errInvalidBundle)
+	}
+	bundle.State.Signature.PublicKey = elliptic.Marshal(elliptic.P256(), testServerKey.X, testServerKey.Y)
+	if _, err := VerifyAuditBundle(bundle, &testServerKey.PublicKey); !errors.Is(err, errInvalidBundle) {
+		t.Errorf("verifying audit bundle signed with another key, claiming the server one: got %v, want %v",
+			err, errInvalidBundle)
+	}
+
+	bundle.State.Signature = nil
+	if _, err := VerifyAuditBundle(bundle, &testServerKey.PublicKey); !errors.Is(err, errInvalidBundle) {
+		t.Errorf("verifying unsigned audit bundle: got %v, want %v", err, errInvalidBundle)
+	}
+}
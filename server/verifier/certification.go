@@ -0,0 +1,281 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+var errInvalidCertification = errors.New("INVALID CERTIFICATION")
+
+// Official mirrors the officials file of the server
+type Official struct {
+	Name      string `json:"name"`
+	PublicKey []byte `json:"public_key"`
+}
+
+// ResultsDocument mirrors the results document of the server
+type ResultsDocument struct {
+	ElectionID    string    `json:"election_id"`
+	ElectionHash  []byte    `json:"election_hash"`
+	ClosingTXID   uint64    `json:"closing_tx_id"`
+	ClosingTXHash []byte    `json:"closing_tx_hash"`
+	Closed        time.Time `json:"closed"`
+	Contests      []struct {
+		Name    string `json:"name"`
+		Ballots uint64 `json:"ballots"`
+		Totals  []struct {
+			CandidateID uint16 `json:"candidate_id"`
+			Name        string `json:"name"`
+			Votes       uint64 `json:"votes"`
+		} `json:"totals"`
+	} `json:"contests"`
+	Turnout struct {
+		Registered uint64 `json:"registered"`
+		Voted      uint64 `json:"voted"`
+		Ballots    uint64 `json:"ballots"`
+	} `json:"turnout"`
+}
+
+// Certification mirrors the certification returned by the server: the document
+// is verified as signed, the decoded results it comes with are ignored
+type Certification struct {
+	Document   []byte `json:"document"`
+	Signatures []struct {
+		Official  string `json:"official"`
+		PublicKey []byte `json:"public_key"`
+		Signature []byte `json:"signature"`
+	} `json:"signatures"`
+}
+
+// VerifyCertification verifies that the results document has been signed by at
+// least quorum of the distinct officials and that its totals (and its turnout)
+// match the ballots of the audit bundle exported at its closing tx, signed by
+// the server (see VerifyAuditBundle); it returns the document and the names of
+// the officials who signed it
+func VerifyCertification(
+	certification *Certification,
+	officials []Official,
+	quorum int,
+	bundle *AuditBundle,
+	publicKey *ecdsa.PublicKey,
+) (*ResultsDocument, []string, error) {
+	var document ResultsDocument
+	if err := json.Unmarshal(certification.Document, &document); err != nil {
+		return nil, nil, fmt.Errorf("%w: error JSON-unmarshaling results document: %v", errInvalidCertification, err)
+	}
+
+	// an official listed twice would count twice towards the quorum
+	names := make(map[string]bool, len(officials))
+	for i, official := range officials {
+		if len(official.Name) == 0 || names[official.Name] {
+			return nil, nil, fmt.Errorf("%w: official %d has no name or a duplicate one", errInvalidCertification, i)
+		}
+		names[official.Name] = true
+	}
+
+	var signers []string
+	for _, official := range officials {
+		for _, signature := range certification.Signatures {
+			if signature.Official == official.Name &&
+				ed25519.Verify(official.PublicKey, certification.Document, signature.Signature) {
+				signers = append(signers, official.Name)
+				break
+			}
+		}
+	}
+	if len(signers) < quorum {
+		return nil, nil, fmt.Errorf("%w: signed by %d officials, %d required",
+			errInvalidCertification, len(signers), quorum)
+	}
+
+	tally, err := VerifyAuditBundle(bundle, publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bundle.State.TXID != document.ClosingTXID || !bytes.Equal(bundle.State.TXHash, document.ClosingTXHash) {
+		return nil, nil, fmt.Errorf("%w: audit bundle is at tx %d (hash %x), the election has been closed at tx %d (hash %x)",
+			errInvalidCertification, bundle.State.TXID, bundle.State.TXHash, document.ClosingTXID, document.ClosingTXHash)
+	}
+	if electionHash := sha256.Sum256(bundle.Election.Value); !bytes.Equal(electionHash[:], document.ElectionHash) {
+		return nil, nil, fmt.Errorf("%w: election definition hash does not match the audit bundle", errInvalidCertification)
+	}
+	if len(document.Contests) != 1 {
+		return nil, nil, fmt.Errorf("%w: results document has %d contests, the election has 1",
+			errInvalidCertification, len(document.Contests))
+	}
+	contest := document.Contests[0]
+	if contest.Ballots != tally.Ballots || document.Turnout.Ballots != tally.Ballots {
+		return nil, nil, fmt.Errorf("%w: results document has %d ballots cast, the audit bundle %d",
+			errInvalidCertification, contest.Ballots, tally.Ballots)
+	}
+	if len(contest.Totals) != len(tally.Election.Candidates) {
+		return nil, nil, fmt.Errorf("%w: results document has %d totals, the election %d candidates",
+			errInvalidCertification, len(contest.Totals), len(tally.Election.Candidates))
+	}
+	for _, total := range contest.Totals {
+		if total.Votes != tally.Results[total.CandidateID] {
+			return nil, nil, fmt.Errorf("%w: results document has %d votes for %s, the audit bundle %d",
+				errInvalidCertification, total.Votes, total.Name, tally.Results[total.CandidateID])
+		}
+	}
+	// the voter roll also holds the revoked registrations, whose values are
+	// not exported: it bounds the registered voters
+	if document.Turnout.Registered > tally.Registered {
+		return nil, nil, fmt.Errorf("%w: results document has %d registered voters, the voter roll %d",
+			errInvalidCertification, document.Turnout.Registered, tally.Registered)
+	}
+	// each voter who voted has cast a ballot, while the ballots of the revoked
+	// voters are still counted: the ballots cast bound the voters who voted
+	if document.Turnout.Voted > tally.Ballots || document.Turnout.Voted > document.Turnout.Registered {
+		return nil, nil, fmt.Errorf("%w: results document has %d voters who voted, for %d ballots cast and %d registered voters",
+			errInvalidCertification, document.Turnout.Voted, tally.Ballots, document.Turnout.Registered)
+	}
+	return &document, signers, nil
+}
+
+func readJSON(file string, what string, v interface{}) error {
+	jsonBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading %s from %s: %v", what, file, err)
+	}
+	if err := json.Unmarshal(jsonBytes, v); err != nil {
+		return fmt.Errorf("error JSON-unmarshaling %s from %s: %v", what, file, err)
+	}
+	return nil
+}
+
+func verifyCertificationCmd(args []string) error {
+	fs := flag.NewFlagSet("verify-certification", flag.ExitOnError)
+	certificationFile := fs.String("certification", "", "certification file returned by the server")
+	bundleFile := fs.String("bundle", "", "audit bundle file exported by the server at the closing tx")
+	officialsFile := fs.String("officials", "", "officials file (names and public keys) of the election")
+	quorum := fs.Int("quorum", 0, "number of officials who must have signed (default: all of them, as the --certification-quorum of the server)")
+	publicKeyFile := fs.String("pubkey", "", "public key (PEM) of the server signing key, as published for the election")
+	fs.Parse(args)
+
+	if len(*certificationFile) == 0 || len(*bundleFile) == 0 || len(*officialsFile) == 0 {
+		return errors.New("-certification, -bundle and -officials flags are required")
+	}
+	publicKey, err := readPublicKey(*publicKeyFile)
+	if err != nil {
+		return err
+	}
+	var certification Certification
+	if err := readJSON(*certificationFile, "certification", &certification); err != nil {
+		return err
+	}
+	var officials []Official
+	if err := readJSON(*officialsFile, "officials", &officials); err != nil {
+		return err
+	}
+	var bundle AuditBundle
+	if err := readJSON(*bundleFile, "audit bundle", &bundle); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidBundle, err)
+	}
+	if *quorum <= 0 {
+		*quorum = len(officials)
+	}
+
+	document, signers, err := VerifyCertification(&certification, officials, *quorum, &bundle, publicKey)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("results certified: signed by %d of %d officials (%v), totals match the audit bundle\n",
+		len(signers), len(officials), signers)
+	fmt.Printf("closed at tx %d (hash %x) on %s\n",
+		document.ClosingTXID, document.ClosingTXHash, document.Closed.Format(time.RFC3339))
+	fmt.Printf("registered voters: %d\n", document.Turnout.Registered)
+	fmt.Printf("voters who voted: %d\n", document.Turnout.Voted)
+	fmt.Printf("ballots cast: %d\n", document.Turnout.Ballots)
+	for _, total := range document.Contests[0].Totals {
+		fmt.Printf("  %s: %d\n", total.Name, total.Votes)
+	}
+	return nil
+}
+
+func genOfficialKeyCmd(args []string) error {
+	fs := flag.NewFlagSet("gen-official-key", flag.ExitOnError)
+	name := fs.String("name", "", "name of the official")
+	keyFile := fs.String("key", "official-key.pem", "file in which the private key (PKCS #8 PEM) is written")
+	fs.Parse(args)
+
+	if len(*name) == 0 {
+		return errors.New("-name flag is missing")
+	}
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating key: %v", err)
+	}
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("error marshaling private key: %v", err)
+	}
+	f, err := os.OpenFile(*keyFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating private key file: %v", err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}); err != nil {
+		return fmt.Errorf("error writing private key to %s: %v", *keyFile, err)
+	}
+
+	// the entry of the official in the officials file
+	officialBytes, _ := json.Marshal(&Official{Name: *name, PublicKey: publicKey})
+	fmt.Println(string(officialBytes))
+	return nil
+}
+
+func signCertificationCmd(args []string) error {
+	fs := flag.NewFlagSet("sign-certification", flag.ExitOnError)
+	certificationFile := fs.String("certification", "", "certification file returned by the server")
+	name := fs.String("name", "", "name of the official")
+	keyFile := fs.String("key", "official-key.pem", "private key (PKCS #8 PEM) of the official")
+	fs.Parse(args)
+
+	if len(*certificationFile) == 0 || len(*name) == 0 {
+		return errors.New("-certification and -name flags are required")
+	}
+	var certification Certification
+	if err := readJSON(*certificationFile, "certification", &certification); err != nil {
+		return err
+	}
+	keyPEM, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		return fmt.Errorf("error reading private key from %s: %v", *keyFile, err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return fmt.Errorf("%s is not a PEM file", *keyFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("error parsing private key from %s: %v", *keyFile, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return fmt.Errorf("%s is not an Ed25519 private key", *keyFile)
+	}
+
+	// the body of the signature request
+	signatureBytes, _ := json.Marshal(map[string]interface{}{
+		"official":  *name,
+		"signature": ed25519.Sign(privateKey, certification.Document),
+	})
+	fmt.Println(string(signatureBytes))
+	return nil
+}
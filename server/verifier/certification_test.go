@@ -0,0 +1,122 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestCertification returns the certification of the results of the test
+// bundle (see newTestBundle), with the given turnout, signed by the given
+// officials, as the server returns it
+func newTestCertification(
+	t *testing.T,
+	bundle *AuditBundle,
+	registered uint64,
+	voted uint64,
+	keys map[string]ed25519.PrivateKey,
+) *Certification {
+	t.Helper()
+	electionHash := sha256.Sum256(bundle.Election.Value)
+	document, err := json.Marshal(map[string]interface{}{
+		"election_id":     "default",
+		"election_hash":   electionHash[:],
+		"closing_tx_id":   bundle.State.TXID,
+		"closing_tx_hash": bundle.State.TXHash,
+		"closed":          time.Now().UTC(),
+		"contests": []interface{}{map[string]interface{}{
+			"name":    "Test election",
+			"ballots": 1,
+			"totals": []interface{}{
+				map[string]interface{}{"candidate_id": 1, "name": "A", "votes": 1},
+				map[string]interface{}{"candidate_id": 2, "name": "B", "votes": 0},
+			},
+		}},
+		"turnout": map[string]interface{}{"registered": registered, "voted": voted, "ballots": 1},
+	})
+	if err != nil {
+		t.Fatalf("error JSON-marshaling results document: %v", err)
+	}
+	var signatures []interface{}
+	for name, key := range keys {
+		signatures = append(signatures, map[string]interface{}{
+			"official":   name,
+			"public_key": key.Public(),
+			"signature":  ed25519.Sign(key, document),
+		})
+	}
+	certificationBytes, err := json.Marshal(map[string]interface{}{
+		"document":   document,
+		"signatures": signatures,
+	})
+	if err != nil {
+		t.Fatalf("error JSON-marshaling certification: %v", err)
+	}
+	var certification Certification
+	if err := json.Unmarshal(certificationBytes, &certification); err != nil {
+		t.Fatalf("error JSON-unmarshaling certification: %v", err)
+	}
+	return &certification
+}
+
+func TestVerifyCertification(t *testing.T) {
+	keys := make(map[string]ed25519.PrivateKey)
+	var officials []Official
+	for _, name := range []string{"alice", "bob", "carol"} {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("error generating key of %s: %v", name, err)
+		}
+		keys[name] = privateKey
+		officials = append(officials, Official{Name: name, PublicKey: publicKey})
+	}
+	bundle := newTestBundle(t)
+	signedBy := map[string]ed25519.PrivateKey{"alice": keys["alice"], "bob": keys["bob"]}
+
+	document, signers, err := VerifyCertification(
+		newTestCertification(t, bundle, 2, 1, signedBy), officials, 2, bundle, &testServerKey.PublicKey)
+	if err != nil {
+		t.Fatalf("error verifying certification: %v", err)
+	}
+	if len(signers) != 2 || document.Turnout.Voted != 1 || document.Contests[0].Totals[0].Votes != 1 {
+		t.Errorf("got signers %v and document %+v, want 2 signers, 1 voter who voted and 1 vote for A",
+			signers, document)
+	}
+
+	for name, verify := range map[string]func() error{
+		"quorum not met": func() error {
+			_, _, err := VerifyCertification(
+				newTestCertification(t, bundle, 2, 1, signedBy), officials, 3, bundle, &testServerKey.PublicKey)
+			return err
+		},
+		// alice listed twice would make her signature count twice
+		"duplicate official": func() error {
+			duplicates := append([]Official{officials[0]}, officials...)
+			_, _, err := VerifyCertification(
+				newTestCertification(t, bundle, 2, 1, map[string]ed25519.PrivateKey{"alice": keys["alice"]}),
+				duplicates, 2, bundle, &testServerKey.PublicKey)
+			return err
+		},
+		"more voters who voted than ballots": func() error {
+			_, _, err := VerifyCertification(
+				newTestCertification(t, bundle, 2, 2, signedBy), officials, 2, bundle, &testServerKey.PublicKey)
+			return err
+		},
+		"more registered voters than the voter roll": func() error {
+			_, _, err := VerifyCertification(
+				newTestCertification(t, bundle, 3, 1, signedBy), officials, 2, bundle, &testServerKey.PublicKey)
+			return err
+		},
+	} {
+		if err := verify(); !errors.Is(err, errInvalidCertification) {
+			t.Errorf("verifying certification with %s: got %v, want %v", name, err, errInvalidCertification)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const usage = `immuvoting verifier
+
+Usage:
+  verifier verify [flags]   verify the server state against the locally stored one
+                            and cross-check it with the states of the peers
+  verifier gossip [flags]   run a public gossip endpoint for verifiers
+  verifier verify-bundle [flags]
+                            verify an audit bundle fully offline and reproduce the tally
+  verifier mirror [flags]   mirror the public bulletin board and verify it incrementally
+  verifier sweep [flags]    verify the full history of every ballot against the verified state
+  verifier gen-official-key [flags]
+                            generate the key pair with which an official signs the results
+  verifier sign-certification [flags]
+                            sign the results document of a closed election, as an official
+  verifier verify-certification [flags]
+                            verify the signatures of the results document and its totals
+                            against the audit bundle of the closing tx
+  verifier export-results [flags]
+                            export the results as JSON, CSV or NIST CDF, as the admin
+
+Run "verifier <command> -h" for the flags of each command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	var err error
+	switch os.Args[1] {
+	case "verify":
+		err = verifyCmd(os.Args[2:])
+	case "gossip":
+		err = gossipCmd(os.Args[2:])
+	case "verify-bundle":
+		err = verifyBundleCmd(os.Args[2:])
+	case "mirror":
+		err = mirrorCmd(os.Args[2:])
+	case "sweep":
+		err = sweepCmd(os.Args[2:])
+	case "gen-official-key":
+		err = genOfficialKeyCmd(os.Args[2:])
+	case "sign-certification":
+		err = signCertificationCmd(os.Args[2:])
+	case "verify-certification":
+		err = verifyCertificationCmd(os.Args[2:])
+	case "export-results":
+		err = exportResultsCmd(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, errForkDetected) || errors.Is(err, errTampered) ||
+			errors.Is(err, errInvalidBundle) || errors.Is(err, errInvalidFeed) ||
+			errors.Is(err, errInvalidSweep) || errors.Is(err, errInvalidCertification) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+var (
+	errTampered     = errors.New("TAMPERED: server state is not consistent with the local state")
+	errForkDetected = errors.New("FORK DETECTED: server has shown different histories to different verifiers")
+)
+
+func verifyCmd(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	serverURL := fs.String("server", "http://localhost:8080", "immuvoting server URL")
+	stateFile := fs.String("state", "immuvoting-state.json", "file in which the verified state is stored")
+	id := fs.String("id", "", "ID of this verifier, as seen by its peers (random if empty)")
+	gossipURLs := fs.String("gossip", "", "comma-separated URLs of peers or public gossip endpoints")
+	listen := fs.String("listen", "", "address on which to also serve the gossip endpoint (e.g. :8090)")
+	evidenceDir := fs.String("evidence-dir", ".", "dir in which fork evidence is written")
+	interval := fs.Duration("interval", 0, "repeat the verification at this interval (0 runs it once)")
+	publicKeyFile := fs.String("pubkey", "", "public key (PEM) of the server signing key, as published for the election")
+	fs.Parse(args)
+
+	publicKey, err := readPublicKey(*publicKeyFile)
+	if err != nil {
+		return err
+	}
+
+	if len(*id) == 0 {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("error generating verifier ID: %v", err)
+		}
+		*id = fmt.Sprintf("cli-%x", b)
+	}
+	var peers []string
+	for _, gossipURL := range strings.Split(*gossipURLs, ",") {
+		if gossipURL = strings.TrimSpace(gossipURL); len(gossipURL) > 0 {
+			peers = append(peers, strings.TrimSuffix(gossipURL, "/"))
+		}
+	}
+
+	v := &cliVerifier{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		serverURL:   strings.TrimSuffix(*serverURL, "/"),
+		publicKey:   publicKey,
+		stateFile:   *stateFile,
+		id:          *id,
+		peers:       peers,
+		evidenceDir: *evidenceDir,
+	}
+	if len(*listen) > 0 {
+		v.node = NewGossipNode(publicKey)
+		mux := http.NewServeMux()
+		mux.Handle("/gossip", v.node)
+		go func() {
+			log.Fatal(http.ListenAndServe(*listen, mux))
+		}()
+		log.Printf("verifier %s serving gossip endpoint on %s", v.id, *listen)
+	}
+
+	for {
+		if err := v.run(); err != nil {
+			if *interval == 0 || errors.Is(err, errForkDetected) || errors.Is(err, errTampered) {
+				return err
+			}
+			log.Print(err)
+		}
+		if *interval == 0 {
+			return nil
+		}
+		time.Sleep(*interval)
+	}
+}
+
+type cliVerifier struct {
+	client      *http.Client
+	serverURL   string
+	publicKey   *ecdsa.PublicKey
+	stateFile   string
+	id          string
+	peers       []string
+	node        *GossipNode
+	evidenceDir string
+}
+
+func (v *cliVerifier) run() error {
+	serverState, err := v.verifyServerState()
+	if err != nil {
+		return err
+	}
+	return v.crossCheckPeers(serverState)
+}
+
+// verifyServerState proves that the current server state is consistent with
+// the locally stored one, then stores it in its place
+func (v *cliVerifier) verifyServerState() (*State, error) {
+	localState, err := v.loadState()
+	if err != nil {
+		return nil, err
+	}
+	serverState, err := FetchServerState(v.client, v.serverURL)
+	if err != nil {
+		return nil, err
+	}
+	// only a signed state binds the server, i.e. can be evidence of a fork
+	if err := serverState.VerifySignature(v.publicKey); err != nil {
+		return nil, err
+	}
+
+	if localState != nil && !localState.Equals(serverState) {
+		_, verified, err := ProveConsistency(v.client, v.serverURL, localState, serverState)
+		if err != nil {
+			return nil, fmt.Errorf("error verifying server tx %d against local tx %d: %v",
+				serverState.TXID, localState.TXID, err)
+		}
+		if !verified {
+			return nil, fmt.Errorf("%w: local tx %d, server tx %d",
+				errTampered, localState.TXID, serverState.TXID)
+		}
+	}
+	log.Printf("verified: server tx %d", serverState.TXID)
+	if err := v.saveState(serverState); err != nil {
+		return nil, err
+	}
+	return serverState, nil
+}
+
+func (v *cliVerifier) crossCheckPeers(localState *State) error {
+	ownState := &GossipState{State: *localState, VerifierID: v.id, Observed: time.Now()}
+	var peerStates []*GossipState
+	if v.node != nil {
+		if err := v.node.Record(ownState); err != nil {
+			return fmt.Errorf("error recording own state: %v", err)
+		}
+		peerStates = append(peerStates, v.node.States()...)
+	}
+	for _, peer := range v.peers {
+		states, err := ExchangeStates(v.client, peer, ownState)
+		if err != nil {
+			log.Printf("error exchanging states with %s: %v", peer, err)
+			continue
+		}
+		peerStates = append(peerStates, states...)
+	}
+
+	checked := make(map[string]bool)
+	for _, peerState := range peerStates {
+		if peerState.VerifierID == v.id {
+			continue
+		}
+		checkedKey := fmt.Sprintf("%d:%x", peerState.TXID, peerState.TXHash)
+		if checked[checkedKey] {
+			continue
+		}
+		checked[checkedKey] = true
+		evidence, err := CrossCheck(v.client, v.serverURL, v.publicKey, localState, peerState)
+		if err != nil {
+			if errors.Is(err, ErrPeerAhead) {
+				log.Printf("state of peer %s can not be cross-checked yet: %v", peerState.VerifierID, err)
+			} else {
+				log.Printf("error cross-checking state of peer %s: %v", peerState.VerifierID, err)
+			}
+			continue
+		}
+		if evidence != nil {
+			return v.raiseForkAlarm(evidence)
+		}
+	}
+	if len(checked) > 0 {
+		log.Printf("cross-checked %d distinct peer states: no fork", len(checked))
+	}
+	return nil
+}
+
+func (v *cliVerifier) raiseForkAlarm(evidence *ForkEvidence) error {
+	evidenceBytes, err := json.MarshalIndent(evidence, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %s (error JSON-marshaling evidence: %v)",
+			errForkDetected, evidence.Reason, err)
+	}
+	fmt.Fprintf(os.Stderr, "\n!!! %v !!!\n%s\n\n", errForkDetected, evidenceBytes)
+	evidenceFile := filepath.Join(
+		v.evidenceDir, fmt.Sprintf("fork-evidence-%d.json", evidence.Detected.Unix()))
+	if err := ioutil.WriteFile(evidenceFile, evidenceBytes, 0644); err != nil {
+		return fmt.Errorf("%w: %s (error writing evidence to %s: %v)",
+			errForkDetected, evidence.Reason, evidenceFile, err)
+	}
+	return fmt.Errorf("%w: %s (evidence written to %s)",
+		errForkDetected, evidence.Reason, evidenceFile)
+}
+
+func (v *cliVerifier) loadState() (*State, error) {
+	stateBytes, err := ioutil.ReadFile(v.stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading local state from %s: %v", v.stateFile, err)
+	}
+	var state State
+	if err := json.Unmarshal(stateBytes, &state); err != nil {
+		return nil, fmt.Errorf(
+			"error JSON-unmarshaling local state %s from %s: %v", stateBytes, v.stateFile, err)
+	}
+	return &state, nil
+}
+
+func (v *cliVerifier) saveState(state *State) error {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error JSON-marshaling state %+v: %v", state, err)
+	}
+	tmpFile := v.stateFile + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, stateBytes, 0644); err != nil {
+		return fmt.Errorf("error writing local state to %s: %v", tmpFile, err)
+	}
+	if err := os.Rename(tmpFile, v.stateFile); err != nil {
+		return fmt.Errorf("error moving local state to %s: %v", v.stateFile, err)
+	}
+	return nil
+}
+
+func gossipCmd(args []string) error {
+	fs := flag.NewFlagSet("gossip", flag.ExitOnError)
+	listen := fs.String("listen", ":8090", "address on which to serve the gossip endpoint")
+	publicKeyFile := fs.String("pubkey", "", "public key (PEM) of the server signing key, as published for the election")
+	fs.Parse(args)
+
+	publicKey, err := readPublicKey(*publicKeyFile)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/gossip", NewGossipNode(publicKey))
+	log.Printf("serving public gossip endpoint on %s", *listen)
+	return http.ListenAndServe(*listen, mux)
+}
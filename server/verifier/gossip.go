@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxGossipStates caps the number of verifiers a gossip node keeps track of
+const maxGossipStates = 10000
+
+// GossipState is a state observed by a verifier, as exchanged with its peers
+type GossipState struct {
+	State
+	VerifierID string    `json:"verifier_id"`
+	Observed   time.Time `json:"observed"`
+}
+
+func (gs *GossipState) validate() error {
+	if len(gs.VerifierID) == 0 {
+		return fmt.Errorf("verifier ID is missing")
+	}
+	if gs.TXID == 0 {
+		return fmt.Errorf("tx ID is missing")
+	}
+	if len(gs.TXHash) != sha256.Size {
+		return fmt.Errorf("tx hash must have %d bytes", sha256.Size)
+	}
+	return nil
+}
+
+// ExchangeStates pushes the given state to a gossip endpoint (another verifier
+// or a public gossip node) and returns the states it knows about in exchange
+func ExchangeStates(
+	client *http.Client,
+	gossipURL string,
+	gs *GossipState,
+) ([]*GossipState, error) {
+
+	gsBytes, err := json.Marshal(gs)
+	if err != nil {
+		return nil, fmt.Errorf("error JSON-marshaling gossip state %+v: %v", gs, err)
+	}
+	exchangeURL := gossipURL + "/gossip"
+	req, err := http.NewRequest(http.MethodPost, exchangeURL, bytes.NewReader(gsBytes))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error creating new HTTP POST %s request to exchange states: %v",
+			exchangeURL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var peerStates []*GossipState
+	if _, err := httpDo(client, req, &peerStates, ""); err != nil {
+		return nil, err
+	}
+	return peerStates, nil
+}
+
+// GossipNode is a minimal gossip endpoint: it remembers the latest state
+// reported by each verifier and hands all of them to whoever reports theirs;
+// only states signed by the server are accepted
+type GossipNode struct {
+	publicKey *ecdsa.PublicKey
+	mu        sync.Mutex
+	states    map[string]*GossipState
+}
+
+// NewGossipNode creates a gossip node which accepts the states signed with the
+// private key of the given public key of the server
+func NewGossipNode(publicKey *ecdsa.PublicKey) *GossipNode {
+	return &GossipNode{publicKey: publicKey, states: make(map[string]*GossipState)}
+}
+
+// Record stores the state of a verifier, unless a newer one is already known
+func (n *GossipNode) Record(gs *GossipState) error {
+	if err := gs.validate(); err != nil {
+		return err
+	}
+	if err := gs.VerifySignature(n.publicKey); err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	existing, ok := n.states[gs.VerifierID]
+	if !ok && len(n.states) >= maxGossipStates {
+		return fmt.Errorf("gossip node already tracks %d verifiers", maxGossipStates)
+	}
+	if !ok || existing.TXID <= gs.TXID {
+		n.states[gs.VerifierID] = gs
+	}
+	return nil
+}
+
+// States returns all known states, sorted by verifier ID
+func (n *GossipNode) States() []*GossipState {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	states := make([]*GossipState, 0, len(n.states))
+	for _, gs := range n.states {
+		states = append(states, gs)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].VerifierID < states[j].VerifierID
+	})
+	return states
+}
+
+// ServeHTTP handles GET /gossip (list all known states) and POST /gossip
+// (record the posted state and list all known states)
+func (n *GossipNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length")
+	switch r.Method {
+	case http.MethodOptions:
+		return
+	case http.MethodGet:
+	case http.MethodPost:
+		var gs GossipState
+		if err := json.NewDecoder(r.Body).Decode(&gs); err != nil {
+			http.Error(w, fmt.Sprintf("error parsing request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := n.Record(&gs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, fmt.Sprintf(
+			"%s http method is not supported on %s resource", r.Method, r.URL.Path),
+			http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(n.States())
+}
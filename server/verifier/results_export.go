@@ -0,0 +1,67 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func exportResultsCmd(args []string) error {
+	fs := flag.NewFlagSet("export-results", flag.ExitOnError)
+	serverURL := fs.String("server", "http://localhost:8080", "immuvoting server URL")
+	format := fs.String("format", "json", "json, csv or nist-cdf (NIST SP 1500-100 Election Results Reporting Common Data Format, JSON)")
+	outFile := fs.String("out", "", "file in which the results are written (default: the file name given by the server)")
+	adminUser := fs.String("admin-user", "admin", "immuvoting admin user")
+	adminPassword := fs.String("admin-password", "", "immuvoting admin password (default: the IMMUVOTING_ADMIN_PASSWORD env var)")
+	fs.Parse(args)
+
+	if len(*adminPassword) == 0 {
+		*adminPassword = os.Getenv("IMMUVOTING_ADMIN_PASSWORD")
+	}
+	exportURL := strings.TrimSuffix(*serverURL, "/") +
+		"/api/v1/admin/elections/default/results?format=" + url.QueryEscape(*format)
+	req, err := http.NewRequest(http.MethodGet, exportURL, nil)
+	if err != nil {
+		return fmt.Errorf(
+			"error creating new HTTP GET %s request to export results: %v", exportURL, err)
+	}
+	req.SetBasicAuth(*adminUser, *adminPassword)
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error executing HTTP request %s: %v", req.URL, err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading bytes from %s response: %v", req.URL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with non-200 range code %d: %s",
+			req.URL, resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+
+	if len(*outFile) == 0 {
+		*outFile = "immuvoting-results." + *format
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil &&
+			len(params["filename"]) > 0 {
+			// never write outside the current dir, whatever the server says
+			*outFile = filepath.Base(params["filename"])
+		}
+	}
+	if err := ioutil.WriteFile(*outFile, bodyBytes, 0644); err != nil {
+		return fmt.Errorf("error writing results to %s: %v", *outFile, err)
+	}
+	fmt.Printf("results written to %s\n", *outFile)
+	return nil
+}
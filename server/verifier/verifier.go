@@ -2,28 +2,84 @@ package main
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"syscall/js"
 	"time"
 
 	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/api/schema"
 )
 
-func main() {
-	c := make(chan struct{}, 0)
-	println("Go WebAssembly initialized")
-	js.Global().Set("VerifyConsistency", js.FuncOf(VerifyConsistency))
-	<-c
-}
+var (
+	// ErrTXNotFound is returned when the server does not know one of the txs
+	// it has been asked to prove
+	ErrTXNotFound = errors.New("tx not found on server")
+	// ErrInvalidSignature is returned for a state which has not been signed
+	// with the (pinned) key of the server
+	ErrInvalidSignature = errors.New("state is not signed by the server")
+	// ErrPeerAhead is returned when a peer state is newer than any the server
+	// serves yet, e.g. it comes from a replica of the server which is ahead:
+	// it can not be verified (yet), which is not evidence of a fork
+	ErrPeerAhead = errors.New("peer state is ahead of the server")
+)
 
 // State ...
 type State struct {
+	DB     string `json:"db,omitempty"`
 	TXID   uint64 `json:"tx_id"`
 	TXHash []byte `json:"tx_hash"`
+	// signature of the state by the server
+	Signature *schema.Signature `json:"signature,omitempty"`
+}
+
+// VerifySignature verifies that the state has been signed with the private key
+// of the given (pinned) public key of the server: the public key which comes
+// with the signature proves nothing, as anyone can sign with their own key
+func (s *State) VerifySignature(publicKey *ecdsa.PublicKey) error {
+	if publicKey == nil {
+		return fmt.Errorf("%w: no public key of the server to verify the signature against", ErrInvalidSignature)
+	}
+	if s.Signature == nil {
+		return fmt.Errorf("%w: tx %d is not signed", ErrInvalidSignature, s.TXID)
+	}
+	if pinned := elliptic.Marshal(publicKey.Curve, publicKey.X, publicKey.Y); !bytes.Equal(s.Signature.GetPublicKey(), pinned) {
+		return fmt.Errorf("%w: tx %d is signed with public key %x, not with the one of the server (%x)",
+			ErrInvalidSignature, s.TXID, s.Signature.GetPublicKey(), pinned)
+	}
+	immutableState := schema.ImmutableState{
+		Db:        s.DB,
+		TxId:      s.TXID,
+		TxHash:    s.TXHash,
+		Signature: s.Signature,
+	}
+	if ok, err := immutableState.CheckSignature(publicKey); err != nil || !ok {
+		return fmt.Errorf("%w: signature of tx %d does not verify: %v", ErrInvalidSignature, s.TXID, err)
+	}
+	return nil
+}
+
+// ParsePublicKey parses the public key (PKIX PEM) of the server signing key
+func ParsePublicKey(publicKeyPEM []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return nil, errors.New("public key is not PEM-encoded")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key: %v", err)
+	}
+	ecdsaPublicKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok || ecdsaPublicKey.Curve != elliptic.P256() {
+		return nil, errors.New("public key is not an ECDSA P-256 key")
+	}
+	return ecdsaPublicKey, nil
 }
 
 // Equals ...
@@ -34,97 +90,127 @@ func (s *State) Equals(ss *State) bool {
 	return s.TXID == ss.TXID && bytes.Compare(s.TXHash, ss.TXHash) == 0
 }
 
-// VerifyConsistency ...
-func VerifyConsistency(this js.Value, args []js.Value) interface{} {
-	serverURL := args[0].String()
-
-	go func() {
-		client := http.Client{Timeout: 5 * time.Second}
-
-		// get local state
-		var localState *State
-		localStateJS := js.Global().Get("localStorage").Call("getItem", "immuvotingState")
-		if !js.Null().Equal(localStateJS) {
-			localStateStr := localStateJS.String()
-			println("local state:", localStateStr)
-			localState = &State{}
-			if err := json.Unmarshal([]byte(localStateStr), localState); err != nil {
-				println("error JSON-unmarshaling local state", localStateStr, ": ", err.Error())
-				return
-			}
-		}
+// FetchServerState fetches the current state of the server
+func FetchServerState(client *http.Client, serverURL string) (*State, error) {
+	stateURL := serverURL + "/state"
+	req, err := http.NewRequest(http.MethodGet, stateURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error creating new HTTP GET %s request to fetch server state: %v",
+			stateURL, err)
+	}
+	var serverState State
+	if _, err := httpDo(client, req, &serverState, "server state:"); err != nil {
+		return nil, err
+	}
+	return &serverState, nil
+}
 
-		// get server state
-		stateURL := serverURL + "/state"
-		req, err := http.NewRequest(http.MethodGet, stateURL, nil)
-		if err != nil {
-			println(
-				"error creating new HTTP GET %s request to fetch server state:",
-				stateURL, err.Error())
-			return
-		}
-		var serverState State
-		if _, err := httpDo(&client, req, &serverState, "server state:"); err != nil {
-			println(err.Error())
-			return
+// ProveConsistency fetches from the server the dual proof between the older
+// and the newer state and verifies it locally, i.e. it checks that the newer
+// state is an append-only extension of the older one
+func ProveConsistency(
+	client *http.Client,
+	serverURL string,
+	older *State,
+	newer *State,
+) (*schema.VerifiableTx, bool, error) {
+
+	vTXURL := fmt.Sprintf(
+		"%s/verifiable-tx?server_tx=%d&local_tx=%d",
+		serverURL, newer.TXID, older.TXID)
+	req, err := http.NewRequest(http.MethodGet, vTXURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf(
+			"error creating new HTTP GET %s request to fetch verifiable TX: %v",
+			vTXURL, err)
+	}
+	var vTX schema.VerifiableTx
+	if httpStatus, err := httpDo(client, req, &vTX, ""); err != nil {
+		if httpStatus == http.StatusNotFound {
+			return nil, false, fmt.Errorf("%w: %v", ErrTXNotFound, err)
 		}
+		return nil, false, err
+	}
 
-		var verified bool
-		if localState != nil {
-			// get verifiable transaction
-			vTXURL := fmt.Sprintf(
-				"%s/verifiable-tx?server_tx=%d&local_tx=%d",
-				serverURL, serverState.TXID, localState.TXID)
-			req, err = http.NewRequest(http.MethodGet, vTXURL, nil)
-			if err != nil {
-				println(
-					"error creating new HTTP GET", vTXURL,
-					"request to fetch verifiable TX:", err.Error())
-				return
-			}
-			var vTX schema.VerifiableTx
-			if httpStatus, err := httpDo(&client, req, &vTX, ""); err != nil {
-				errMsg := err.Error()
-				if httpStatus == http.StatusNotFound {
-					errMsg = fmt.Sprintf(
-						"verification error: one of the 2 tx IDs was not found on server: %v", err)
-				}
-				println(errMsg)
-				return
-			}
+	proof := schema.DualProofFrom(vTX.DualProof)
+	olderTXHash := schema.DigestFrom(older.TXHash)
+	newerTXHash := schema.DigestFrom(newer.TXHash)
+	verified := store.VerifyDualProof(
+		proof, older.TXID, newer.TXID, olderTXHash, newerTXHash)
+	return &vTX, verified, nil
+}
 
-			// do the verification
-			proof := schema.DualProofFrom(vTX.DualProof)
-			localTXHash := schema.DigestFrom(localState.TXHash)
-			serverTXHash := schema.DigestFrom(serverState.TXHash)
-			verified = store.VerifyDualProof(
-				proof, localState.TXID, serverState.TXID, localTXHash, serverTXHash)
-			println("verified:", verified)
-
-			now := time.Now().Format(time.RFC3339)
-			resultDOMElem := js.Global().Get("document").Call("getElementById", "tampering-result")
-			if !verified {
-				resultDOMElem.Set("innerHTML", "<span class=\"audit-failed\">Tampered!</span> @ "+now)
-			} else {
-				resultDOMElem.Set("innerHTML", "<span class=\"audit-ok\">OK</span> @ "+now)
-			}
-		}
+// ForkEvidence holds two states served by the same server which can not be
+// reconciled, i.e. the proof that the server has shown different histories to
+// different verifiers
+type ForkEvidence struct {
+	Reason    string               `json:"reason"`
+	Local     *State               `json:"local"`
+	Peer      *GossipState         `json:"peer"`
+	DualProof *schema.VerifiableTx `json:"dual_proof,omitempty"`
+	Detected  time.Time            `json:"detected"`
+}
 
-		// override the local state with the fresh server state
-		if (verified && !localState.Equals(&serverState)) || localState == nil {
-			serverStateBs, err := json.Marshal(&serverState)
-			if err != nil {
-				println(
-					"error JSON-marshaling server state", fmt.Sprintf("%+v", serverState),
-					"before perisiting it to local storage:", err.Error())
-				return
-			}
-			js.Global().Get("localStorage").Call("setItem", "immuvotingState", string(serverStateBs))
+// CrossCheck proves that the local state and the state of a peer, which must
+// have been signed by the server, belong to the same history; if they do not,
+// it returns the conflicting evidence
+func CrossCheck(
+	client *http.Client,
+	serverURL string,
+	publicKey *ecdsa.PublicKey,
+	local *State,
+	peer *GossipState,
+) (*ForkEvidence, error) {
+
+	// an unsigned state is just a claim of the peer, which proves nothing
+	if err := peer.VerifySignature(publicKey); err != nil {
+		return nil, fmt.Errorf("state of peer %s: %w", peer.VerifierID, err)
+	}
+
+	evidence := func(reason string, vTX *schema.VerifiableTx) *ForkEvidence {
+		return &ForkEvidence{
+			Reason:    reason,
+			Local:     local,
+			Peer:      peer,
+			DualProof: vTX,
+			Detected:  time.Now(),
 		}
+	}
 
-	}()
+	if local.TXID == peer.TXID {
+		if !bytes.Equal(local.TXHash, peer.TXHash) {
+			return evidence(fmt.Sprintf(
+				"tx %d has a different hash for peer %s", local.TXID, peer.VerifierID), nil), nil
+		}
+		return nil, nil
+	}
 
-	return nil
+	older, newer := local, &peer.State
+	if older.TXID > newer.TXID {
+		older, newer = newer, older
+	}
+	vTX, verified, err := ProveConsistency(client, serverURL, older, newer)
+	if err != nil {
+		if errors.Is(err, ErrTXNotFound) {
+			if peer.TXID > local.TXID {
+				// the local state has just been verified against the server
+				return nil, fmt.Errorf("%w: tx %d of peer %s can not be proven yet: %v",
+					ErrPeerAhead, peer.TXID, peer.VerifierID, err)
+			}
+			// the server has signed the peer state, but denies knowing it
+			return evidence(fmt.Sprintf(
+				"server denies knowing tx %d, which it has signed for peer %s: %v",
+				peer.TXID, peer.VerifierID, err), nil), nil
+		}
+		return nil, err
+	}
+	if !verified {
+		return evidence(fmt.Sprintf(
+			"tx %d and tx %d (peer %s) are not consistent",
+			local.TXID, peer.TXID, peer.VerifierID), vTX), nil
+	}
+	return nil, nil
 }
 
 func httpDo(client *http.Client, req *http.Request, out interface{}, printRawPayload string) (int, error) {
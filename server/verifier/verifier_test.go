@@ -0,0 +1,192 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/padurean/immuvoting/memstore"
+)
+
+// newTestServer serves the state and the proofs of the in-memory store, as the
+// immuvoting server does
+func newTestServer(t *testing.T, db *memstore.Store) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", func(w http.ResponseWriter, r *http.Request) {
+		state, err := db.CurrentState(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(&State{
+			DB:        db.Database(),
+			TXID:      state.GetTxId(),
+			TXHash:    state.GetTxHash(),
+			Signature: signTestState(testServerKey, db.Database(), state.GetTxId(), state.GetTxHash()),
+		})
+	})
+	mux.HandleFunc("/verifiable-tx", func(w http.ResponseWriter, r *http.Request) {
+		serverTX, _ := strconv.ParseUint(r.URL.Query().Get("server_tx"), 10, 64)
+		localTX, _ := strconv.ParseUint(r.URL.Query().Get("local_tx"), 10, 64)
+		vTX, err := db.VerifiableTXByID(r.Context(), serverTX, localTX)
+		if errors.Is(err, store.ErrTxNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(vTX)
+	})
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+// setTestKeys commits a tx per key, with the key as value, and returns the
+// state signed by the test key of the server
+func setTestKeys(t *testing.T, db *memstore.Store, keys ...string) *State {
+	t.Helper()
+	for _, key := range keys {
+		if err := db.Set(context.Background(), []byte(key), []byte(key)); err != nil {
+			t.Fatalf("error setting key %s: %v", key, err)
+		}
+	}
+	state, err := db.CurrentState(context.Background())
+	if err != nil {
+		t.Fatalf("error fetching current state: %v", err)
+	}
+	return &State{
+		DB:        db.Database(),
+		TXID:      state.GetTxId(),
+		TXHash:    state.GetTxHash(),
+		Signature: signTestState(testServerKey, db.Database(), state.GetTxId(), state.GetTxHash()),
+	}
+}
+
+func TestProveConsistency(t *testing.T) {
+	db := memstore.New("defaultdb")
+	httpServer := newTestServer(t, db)
+
+	older := setTestKeys(t, db, "a", "b", "c")
+	newer := setTestKeys(t, db, "d", "e")
+	fetched, err := FetchServerState(httpServer.Client(), httpServer.URL)
+	if err != nil {
+		t.Fatalf("error fetching server state: %v", err)
+	}
+	if !fetched.Equals(newer) {
+		t.Fatalf("got server state %+v, want %+v", fetched, newer)
+	}
+	if err := fetched.VerifySignature(&testServerKey.PublicKey); err != nil {
+		t.Errorf("verifying signature of server state: %v", err)
+	}
+
+	if _, verified, err := ProveConsistency(httpServer.Client(), httpServer.URL, older, newer); err != nil || !verified {
+		t.Errorf("proving tx %d consistent with tx %d: got %t, %v, want true", older.TXID, newer.TXID, verified, err)
+	}
+	tampered := &State{TXID: older.TXID, TXHash: append([]byte{}, older.TXHash...)}
+	tampered.TXHash[0] ^= 1
+	if _, verified, err := ProveConsistency(httpServer.Client(), httpServer.URL, tampered, newer); err != nil || verified {
+		t.Errorf("proving tampered tx %d consistent with tx %d: got %t, %v, want false",
+			older.TXID, newer.TXID, verified, err)
+	}
+}
+
+func TestCrossCheck(t *testing.T) {
+	db := memstore.New("defaultdb")
+	httpServer := newTestServer(t, db)
+	publicKey := &testServerKey.PublicKey
+	local := setTestKeys(t, db, "a", "b")
+	peer := setTestKeys(t, db, "c", "d", "e")
+
+	if evidence, err := CrossCheck(httpServer.Client(), httpServer.URL, publicKey, local,
+		&GossipState{State: *peer, VerifierID: "peer"}); err != nil || evidence != nil {
+		t.Errorf("cross-checking states of the same history: got evidence %+v, %v, want none", evidence, err)
+	}
+
+	// a fork: the same first tx, then a different one, signed by the server
+	fork := memstore.New("defaultdb")
+	setTestKeys(t, fork, "a")
+	forkedPeer := setTestKeys(t, fork, "x")
+	evidence, err := CrossCheck(httpServer.Client(), httpServer.URL, publicKey, local,
+		&GossipState{State: *forkedPeer, VerifierID: "peer"})
+	if err != nil || evidence == nil {
+		t.Errorf("cross-checking forked states at the same tx: got evidence %+v, %v, want some", evidence, err)
+	}
+	forkedPeer = setTestKeys(t, fork, "y")
+	evidence, err = CrossCheck(httpServer.Client(), httpServer.URL, publicKey, local,
+		&GossipState{State: *forkedPeer, VerifierID: "peer"})
+	if err != nil || evidence == nil || evidence.DualProof == nil {
+		t.Errorf("cross-checking forked states at different txs: got evidence %+v, %v, want some with a proof",
+			evidence, err)
+	}
+}
+
+func TestCrossCheckUnverifiable(t *testing.T) {
+	db := memstore.New("defaultdb")
+	httpServer := newTestServer(t, db)
+	publicKey := &testServerKey.PublicKey
+	local := setTestKeys(t, db, "a", "b")
+
+	// the state of a replica which is ahead of the server
+	replica := memstore.New("defaultdb")
+	ahead := setTestKeys(t, replica, "a", "b", "c")
+	evidence, err := CrossCheck(httpServer.Client(), httpServer.URL, publicKey, local,
+		&GossipState{State: *ahead, VerifierID: "peer"})
+	if !errors.Is(err, ErrPeerAhead) || evidence != nil {
+		t.Errorf("cross-checking a state ahead of the server: got evidence %+v, %v, want %v", evidence, err, ErrPeerAhead)
+	}
+
+	// forged states: not evidence of anything
+	unsigned := *ahead
+	unsigned.Signature = nil
+	forged := *ahead
+	forged.Signature = signTestState(newTestKey(), forged.DB, forged.TXID, forged.TXHash)
+	for name, state := range map[string]State{"unsigned": unsigned, "forged": forged} {
+		evidence, err := CrossCheck(httpServer.Client(), httpServer.URL, publicKey, local,
+			&GossipState{State: state, VerifierID: "peer"})
+		if !errors.Is(err, ErrInvalidSignature) || evidence != nil {
+			t.Errorf("cross-checking %s state: got evidence %+v, %v, want %v", name, evidence, err, ErrInvalidSignature)
+		}
+	}
+}
+
+func TestGossipNode(t *testing.T) {
+	db := memstore.New("defaultdb")
+	node := NewGossipNode(&testServerKey.PublicKey)
+	older := setTestKeys(t, db, "a")
+	newer := setTestKeys(t, db, "b")
+
+	unsigned := *newer
+	unsigned.Signature = nil
+	forged := *newer
+	forged.Signature = signTestState(newTestKey(), forged.DB, forged.TXID, forged.TXHash)
+	tampered := *newer
+	tampered.TXID++
+	for name, state := range map[string]State{"unsigned": unsigned, "forged": forged, "tampered": tampered} {
+		if err := node.Record(&GossipState{State: state, VerifierID: "peer"}); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("recording %s state: got %v, want %v", name, err, ErrInvalidSignature)
+		}
+	}
+	if states := node.States(); len(states) != 0 {
+		t.Fatalf("got %d states, want none", len(states))
+	}
+
+	for _, state := range []*State{newer, older} {
+		if err := node.Record(&GossipState{State: *state, VerifierID: "peer"}); err != nil {
+			t.Fatalf("recording signed state of tx %d: %v", state.TXID, err)
+		}
+	}
+	if states := node.States(); len(states) != 1 || !states[0].Equals(newer) {
+		t.Errorf("got states %+v, want only the newer one", states)
+	}
+}
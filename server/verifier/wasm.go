@@ -0,0 +1,210 @@
+//go:build js && wasm
+// +build js,wasm
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall/js"
+	"time"
+)
+
+func main() {
+	c := make(chan struct{}, 0)
+	println("Go WebAssembly initialized")
+	js.Global().Set("VerifyConsistency", js.FuncOf(VerifyConsistency))
+	<-c
+}
+
+// set once the user has been alerted about a fork, to not alert on every run
+var forkAlarmRaised bool
+
+// VerifyConsistency verifies the server state against the one stored in the
+// browser's local storage; if a gossip URL is passed as 2nd argument and the
+// public key (PEM) of the server signing key as 3rd, the (verified) local
+// state is then also exchanged with the peers and cross-checked against
+// theirs: only the states signed by the server take part in the gossip
+func VerifyConsistency(this js.Value, args []js.Value) interface{} {
+	serverURL := args[0].String()
+	var gossipURL string
+	if len(args) > 1 && args[1].Type() == js.TypeString {
+		gossipURL = args[1].String()
+	}
+	var publicKey *ecdsa.PublicKey
+	if len(args) > 2 && args[2].Type() == js.TypeString && len(args[2].String()) > 0 {
+		var err error
+		if publicKey, err = ParsePublicKey([]byte(args[2].String())); err != nil {
+			println("error parsing public key of the server:", err.Error())
+			return nil
+		}
+	}
+	if len(gossipURL) > 0 && publicKey == nil {
+		println("the public key of the server is required to gossip: gossip disabled")
+		gossipURL = ""
+	}
+
+	go func() {
+		client := http.Client{Timeout: 5 * time.Second}
+
+		// get local state
+		var localState *State
+		localStateJS := js.Global().Get("localStorage").Call("getItem", "immuvotingState")
+		if !js.Null().Equal(localStateJS) {
+			localStateStr := localStateJS.String()
+			println("local state:", localStateStr)
+			localState = &State{}
+			if err := json.Unmarshal([]byte(localStateStr), localState); err != nil {
+				println("error JSON-unmarshaling local state", localStateStr, ": ", err.Error())
+				return
+			}
+		}
+
+		// get server state
+		serverState, err := FetchServerState(&client, serverURL)
+		if err != nil {
+			println(err.Error())
+			return
+		}
+		if publicKey != nil {
+			if err := serverState.VerifySignature(publicKey); err != nil {
+				println(err.Error())
+				return
+			}
+		}
+
+		var verified bool
+		if localState != nil {
+			// get verifiable transaction and do the verification
+			_, verified, err = ProveConsistency(&client, serverURL, localState, serverState)
+			if err != nil {
+				errMsg := err.Error()
+				if errors.Is(err, ErrTXNotFound) {
+					errMsg = fmt.Sprintf(
+						"verification error: one of the 2 tx IDs was not found on server: %v", err)
+				}
+				println(errMsg)
+				return
+			}
+			println("verified:", verified)
+
+			now := time.Now().Format(time.RFC3339)
+			resultDOMElem := js.Global().Get("document").Call("getElementById", "tampering-result")
+			if !verified {
+				resultDOMElem.Set("innerHTML", "<span class=\"audit-failed\">Tampered!</span> @ "+now)
+			} else {
+				resultDOMElem.Set("innerHTML", "<span class=\"audit-ok\">OK</span> @ "+now)
+			}
+		}
+
+		// override the local state with the fresh server state
+		if (verified && !localState.Equals(serverState)) || localState == nil {
+			serverStateBs, err := json.Marshal(serverState)
+			if err != nil {
+				println(
+					"error JSON-marshaling server state", fmt.Sprintf("%+v", serverState),
+					"before perisiting it to local storage:", err.Error())
+				return
+			}
+			js.Global().Get("localStorage").Call("setItem", "immuvotingState", string(serverStateBs))
+			localState = serverState
+			verified = true
+		}
+
+		if verified && len(gossipURL) > 0 {
+			gossip(&client, serverURL, publicKey, gossipURL, localState)
+		}
+	}()
+
+	return nil
+}
+
+// gossip exchanges the local state with the peers and cross-checks it against
+// each of their states, raising an alarm if a fork is detected
+func gossip(
+	client *http.Client,
+	serverURL string,
+	publicKey *ecdsa.PublicKey,
+	gossipURL string,
+	localState *State,
+) {
+	peerStates, err := ExchangeStates(client, gossipURL, &GossipState{
+		State:      *localState,
+		VerifierID: verifierID(),
+		Observed:   time.Now(),
+	})
+	if err != nil {
+		println("error exchanging states with gossip endpoint", gossipURL, ":", err.Error())
+		return
+	}
+
+	checked := make(map[string]bool)
+	var nbChecked int
+	for _, peerState := range peerStates {
+		if peerState.VerifierID == verifierID() {
+			continue
+		}
+		checkedKey := fmt.Sprintf("%d:%x", peerState.TXID, peerState.TXHash)
+		if checked[checkedKey] {
+			continue
+		}
+		checked[checkedKey] = true
+		evidence, err := CrossCheck(client, serverURL, publicKey, localState, peerState)
+		if err != nil {
+			println("error cross-checking state of peer", peerState.VerifierID, ":", err.Error())
+			continue
+		}
+		if evidence != nil {
+			raiseForkAlarm(evidence)
+			return
+		}
+		nbChecked++
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	js.Global().Get("document").Call("getElementById", "fork-result").Set(
+		"innerHTML",
+		fmt.Sprintf("<span class=\"audit-ok\">OK</span> (%d peer states) @ %s", nbChecked, now))
+}
+
+func raiseForkAlarm(evidence *ForkEvidence) {
+	evidenceBs, err := json.Marshal(evidence)
+	if err != nil {
+		println("error JSON-marshaling fork evidence:", err.Error())
+	}
+	println("FORK DETECTED:", string(evidenceBs))
+	js.Global().Get("localStorage").Call("setItem", "immuvotingForkEvidence", string(evidenceBs))
+
+	now := time.Now().Format(time.RFC3339)
+	js.Global().Get("document").Call("getElementById", "fork-result").Set(
+		"innerHTML",
+		"<span class=\"audit-failed\">Fork detected!</span> @ "+now+
+			"<br><code>"+evidence.Reason+"</code>")
+
+	if !forkAlarmRaised {
+		forkAlarmRaised = true
+		js.Global().Call("alert",
+			"The server has shown different election histories to different verifiers!\n\n"+
+				evidence.Reason+"\n\n"+
+				"The evidence has been saved in the local storage (immuvotingForkEvidence).")
+	}
+}
+
+// verifierID returns the random ID of this browser, generating it if needed
+func verifierID() string {
+	idJS := js.Global().Get("localStorage").Call("getItem", "immuvotingVerifierID")
+	if !js.Null().Equal(idJS) {
+		return idJS.String()
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		println("error generating verifier ID:", err.Error())
+	}
+	id := fmt.Sprintf("browser-%x", b)
+	js.Global().Get("localStorage").Call("setItem", "immuvotingVerifierID", id)
+	return id
+}
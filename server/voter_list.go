@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/codenotary/immudb/pkg/database"
+)
+
+const voterListDefaultLimit = 100
+
+// VoterListEntry is a voter, as listed to the admins: its registration status,
+// not its personal data
+type VoterListEntry struct {
+	VoterID string `json:"voter_id"`
+	GetVoterStatusResponse
+}
+
+// VoterListResponse ...
+type VoterListResponse struct {
+	Voters     []VoterListEntry `json:"voters"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// getVoterListHandler lists the voters, page by page, in voter ID order
+func (s *Server) getVoterListHandler(w http.ResponseWriter, r *http.Request) {
+	limit := uint64(voterListDefaultLimit)
+	if limitStr := r.URL.Query().Get("limit"); len(limitStr) > 0 {
+		var err error
+		if limit, err = strconv.ParseUint(limitStr, 10, 32); err != nil ||
+			limit == 0 || limit > database.MaxKeyScanLimit {
+			writeErrorResponse(r, w, http.StatusBadRequest, err, fmt.Sprintf(
+				"limit query param must be between 1 and %d", database.MaxKeyScanLimit))
+			return
+		}
+	}
+
+	voterEntries, nextCursor, err := scanPage(r.Context(), s.store,
+		[]byte(voterPrefix), r.URL.Query().Get("cursor"), limit)
+	if errors.Is(err, errInvalidCursor) {
+		writeErrorResponse(r, w, http.StatusBadRequest, err, "cursor query param is invalid")
+		return
+	} else if err != nil {
+		writeErrorResponse(r, w, http.StatusInternalServerError, err, "error scanning voters")
+		return
+	}
+
+	resPayload := VoterListResponse{
+		Voters:     make([]VoterListEntry, 0, len(voterEntries)),
+		NextCursor: nextCursor,
+	}
+	for _, voterEntry := range voterEntries {
+		voterID := strings.TrimPrefix(string(voterEntry.GetKey()), voterPrefix)
+		var voter Voter
+		if err := json.Unmarshal(voterEntry.GetValue(), &voter); err != nil {
+			writeErrorResponse(r, w, http.StatusInternalServerError, err,
+				fmt.Sprintf("error JSON-unmarshaling voter %s", voterID))
+			return
+		}
+		resPayload.Voters = append(resPayload.Voters, VoterListEntry{
+			VoterID: voterID,
+			GetVoterStatusResponse: GetVoterStatusResponse{
+				RegistrationApproved: voter.RegistrationApproved,
+				Voted:                voter.Voted,
+				Revoked:              voter.Revoked,
+			},
+		})
+	}
+
+	writeJSONResponse(r, w, http.StatusOK, &resPayload)
+}